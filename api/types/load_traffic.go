@@ -6,6 +6,8 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"strconv"
 	"strings"
 
 	apitypes "k8s.io/apimachinery/pkg/types"
@@ -19,12 +21,15 @@ const (
 	ContentTypeJSON ContentType = "json"
 	// ContentTypeProtobuffer means the format is protobuf.
 	ContentTypeProtobuffer = "protobuf"
+	// ContentTypeCBOR means the format is cbor. Request bodies are still
+	// json. kube-apiserver responds in json if cbor isn't enabled.
+	ContentTypeCBOR ContentType = "cbor"
 )
 
 // Validate returns error if ContentType is not supported.
 func (ct ContentType) Validate() error {
 	switch ct {
-	case ContentTypeJSON, ContentTypeProtobuffer:
+	case ContentTypeJSON, ContentTypeProtobuffer, ContentTypeCBOR:
 		return nil
 	default:
 		return fmt.Errorf("unsupported content type %s", ct)
@@ -57,15 +62,163 @@ type LoadProfileSpec struct {
 	ContentType ContentType `json:"contentType" yaml:"contentType"`
 	// DisableHTTP2 means client will use HTTP/1.1 protocol if it's true.
 	DisableHTTP2 bool `json:"disableHTTP2" yaml:"disableHTTP2"`
+	// HTTP2 defines the HTTP/2 settings of clients. It can't be set if
+	// DisableHTTP2 is true. If it's set, clients only speak HTTP/2 and
+	// don't use proxy.
+	HTTP2 *HTTP2Spec `json:"http2,omitempty" yaml:"http2,omitempty"`
 	// MaxRetries makes the request use the given integer as a ceiling of
 	// retrying upon receiving "Retry-After" headers and 429 status-code
 	// in the response (<= 0 means no retry).
 	MaxRetries int `json:"maxRetries" yaml:"maxRetries"`
+	// ControllerEmulation makes every client throttle itself and back off
+	// like a controller built on client-go's defaults.
+	ControllerEmulation *ControllerEmulationSpec `json:"controllerEmulation,omitempty" yaml:"controllerEmulation,omitempty"`
+	// Timeout defines the client-side timeout in seconds for each request.
+	// It's also passed to kube-apiserver as the "timeout" query parameter.
+	// Zero means the default timeout, 60 seconds. It can be overridden by
+	// WeightedRequest.Timeout.
+	Timeout float64 `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Warmup defines the warmup phase before measurement.
+	Warmup *WarmupSpec `json:"warmup,omitempty" yaml:"warmup,omitempty"`
+	// AbortConditions defines when to stop the traffic before it's done.
+	AbortConditions *AbortConditions `json:"abortConditions,omitempty" yaml:"abortConditions,omitempty"`
+	// ConnChurn defines how to tear down connections during the run.
+	ConnChurn *ConnChurn `json:"connChurn,omitempty" yaml:"connChurn,omitempty"`
+	// Endpoints pins connections to the given apiserver endpoints in
+	// round-robin, bypassing the load balancer. The format is IP or
+	// IP:PORT. If PORT is not specified, the port in kubeconfig is used.
+	Endpoints []string `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+	// DiscoverEndpoints pins connections to the apiserver endpoints found
+	// in the default/kubernetes EndpointSlice, like Endpoints.
+	DiscoverEndpoints bool `json:"discoverEndpoints,omitempty" yaml:"discoverEndpoints,omitempty"`
+	// Decode defines how to decode responses. Responses are discarded
+	// without decoding if it's not set.
+	Decode *DecodeSpec `json:"decode,omitempty" yaml:"decode,omitempty"`
 	// Requests defines the different kinds of requests with weights.
 	// The executor should randomly pick by weight.
 	Requests []*WeightedRequest `json:"requests" yaml:"requests"`
 }
 
+// WarmupSpec defines the warmup phase. The traffic during warmup is generated
+// normally before Total or Duration starts counting, but it's excluded from
+// the main measurements. Only one of Total and Duration may be specified.
+type WarmupSpec struct {
+	// Total defines the number of requests sent during warmup.
+	Total int `json:"total,omitempty" yaml:"total,omitempty"`
+	// Duration defines the warmup time in seconds.
+	Duration int `json:"duration,omitempty" yaml:"duration,omitempty"`
+	// Discard means warmup responses are not recorded at all. Otherwise,
+	// they are reported separately.
+	Discard bool `json:"discard,omitempty" yaml:"discard,omitempty"`
+}
+
+// AbortConditions defines guardrails checked continuously during a run. The
+// run stops gracefully once any of the conditions fires. Zero value means
+// that condition is disabled.
+type AbortConditions struct {
+	// Window is the sliding window in seconds used by rate and latency
+	// conditions. Default is 10 seconds.
+	Window int `json:"window,omitempty" yaml:"window,omitempty"`
+	// MinRequests is the minimum number of responses in the window before
+	// rate and latency conditions are evaluated. Default is 10.
+	MinRequests int `json:"minRequests,omitempty" yaml:"minRequests,omitempty"`
+	// MaxErrorRate is the error rate threshold in percentage.
+	MaxErrorRate float64 `json:"maxErrorRate,omitempty" yaml:"maxErrorRate,omitempty"`
+	// MaxTooManyRequestsRate is the threshold of 429 responses in percentage.
+	MaxTooManyRequestsRate float64 `json:"maxTooManyRequestsRate,omitempty" yaml:"maxTooManyRequestsRate,omitempty"`
+	// MaxP99Latency is the p99 latency threshold in seconds.
+	MaxP99Latency float64 `json:"maxP99Latency,omitempty" yaml:"maxP99Latency,omitempty"`
+	// MaxConsecutiveConnectionErrors is the threshold of consecutive
+	// connection errors.
+	MaxConsecutiveConnectionErrors int `json:"maxConsecutiveConnectionErrors,omitempty" yaml:"maxConsecutiveConnectionErrors,omitempty"`
+}
+
+// ConnChurn defines how to tear down connections during the run to emulate
+// clients reconnecting, for instance, after apiserver rollouts. Closed
+// connections are re-dialed by the next requests. Zero value means that
+// churn is disabled.
+type ConnChurn struct {
+	// Interval is the period in seconds to close Fraction of connections.
+	Interval float64 `json:"interval,omitempty" yaml:"interval,omitempty"`
+	// Fraction is the fraction of live connections closed in each period.
+	Fraction float64 `json:"fraction,omitempty" yaml:"fraction,omitempty"`
+	// Jitter is the maximum random delay in seconds added to each period.
+	Jitter float64 `json:"jitter,omitempty" yaml:"jitter,omitempty"`
+	// StormAt closes all the connections at once at the given seconds
+	// after the run starts.
+	StormAt float64 `json:"stormAt,omitempty" yaml:"stormAt,omitempty"`
+}
+
+// HTTP2Spec defines the HTTP/2 settings of clients. Zero value means
+// client-go's default.
+type HTTP2Spec struct {
+	// ReadIdleTimeout is the timeout in seconds after which a health check
+	// using ping frame is carried out if no frame is received on the
+	// connection. Zero means 30 seconds and negative value disables the
+	// health check.
+	ReadIdleTimeout float64 `json:"readIdleTimeout,omitempty" yaml:"readIdleTimeout,omitempty"`
+	// PingTimeout is the timeout in seconds after which the connection is
+	// closed if the ping response isn't received. Zero means 15 seconds.
+	PingTimeout float64 `json:"pingTimeout,omitempty" yaml:"pingTimeout,omitempty"`
+	// StrictMaxConcurrentStreams honors the server's max concurrent streams
+	// setting by queueing requests on the connection. Otherwise, new
+	// connections are dialed once the limit is reached.
+	StrictMaxConcurrentStreams bool `json:"strictMaxConcurrentStreams,omitempty" yaml:"strictMaxConcurrentStreams,omitempty"`
+}
+
+// ControllerEmulationSpec defines the client-side throttling of each client.
+// Zero value means client-go's default.
+type ControllerEmulationSpec struct {
+	// QPS is the token bucket rate limit of each client. Zero means 5.
+	QPS float64 `json:"qps,omitempty" yaml:"qps,omitempty"`
+	// Burst is the token bucket size of each client. Zero means 10.
+	Burst int `json:"burst,omitempty" yaml:"burst,omitempty"`
+	// MaxRetries is the ceiling of retrying upon receiving "Retry-After"
+	// headers with 429 or 5xx status-code. It overrides
+	// LoadProfileSpec.MaxRetries. Zero means 10 and negative value disables
+	// retry.
+	MaxRetries int `json:"maxRetries,omitempty" yaml:"maxRetries,omitempty"`
+	// BackoffBase is the initial delay in seconds of the exponential
+	// backoff after 429 or 5xx responses. Zero means 1 second and negative
+	// value disables backoff.
+	BackoffBase float64 `json:"backoffBase,omitempty" yaml:"backoffBase,omitempty"`
+	// BackoffMax is the maximum delay in seconds of the exponential
+	// backoff. Zero means 10 seconds.
+	BackoffMax float64 `json:"backoffMax,omitempty" yaml:"backoffMax,omitempty"`
+}
+
+// DecodeTarget is the type of objects which responses are decoded into.
+type DecodeTarget string
+
+const (
+	// DecodeTargetUnstructured decodes responses into unstructured
+	// objects. Protobuf responses are decoded into typed objects instead
+	// since protobuf can't be decoded without schema.
+	DecodeTargetUnstructured DecodeTarget = "unstructured"
+	// DecodeTargetMetadata asks kube-apiserver for metadata-only objects,
+	// like client-go's metadata client does, and decodes them.
+	DecodeTargetMetadata DecodeTarget = "metadata"
+)
+
+// DecodeSpec defines how to decode responses to model the cost paid by
+// real clients. It applies to get, list, put, patch and postDel requests.
+type DecodeSpec struct {
+	// Target is the type of decoded objects. Default is unstructured.
+	Target DecodeTarget `json:"target,omitempty" yaml:"target,omitempty"`
+}
+
+// ResponseExpectation defines assertions on responses. The response which
+// doesn't match is counted as assertion error.
+type ResponseExpectation struct {
+	// StatusCode is the expected HTTP status code. Zero means any 2xx.
+	// The non-2xx response with this code is counted as success.
+	StatusCode int `json:"statusCode,omitempty" yaml:"statusCode,omitempty"`
+	// Items is the expected number of items in each list response.
+	Items *int `json:"items,omitempty" yaml:"items,omitempty"`
+	// MinItems is the minimum number of items in each list response.
+	MinItems int `json:"minItems,omitempty" yaml:"minItems,omitempty"`
+}
+
 // KubeGroupVersionResource identifies the resource URI.
 type KubeGroupVersionResource struct {
 	// Group is the name about a collection of related functionality.
@@ -81,6 +234,11 @@ type KubeGroupVersionResource struct {
 type WeightedRequest struct {
 	// Shares defines weight in the same group.
 	Shares int `json:"shares" yaml:"shares"`
+	// Timeout overrides LoadProfileSpec.Timeout for this kind of request.
+	Timeout float64 `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Expect asserts on responses of this kind of request. It requires
+	// LoadProfileSpec.Decode.
+	Expect *ResponseExpectation `json:"expect,omitempty" yaml:"expect,omitempty"`
 	// StaleList means this list request with zero resource version.
 	StaleList *RequestList `json:"staleList,omitempty" yaml:"staleList,omitempty"`
 	// QuorumList means this list request without kube-apiserver cache.
@@ -237,15 +395,196 @@ func (spec LoadProfileSpec) Validate() error {
 		return fmt.Errorf("client requires > 0: %v", spec.Client)
 	}
 
+	if spec.Timeout < 0 {
+		return fmt.Errorf("timeout requires >= 0: %v", spec.Timeout)
+	}
+
 	err := spec.ContentType.Validate()
 	if err != nil {
 		return err
 	}
 
+	if spec.HTTP2 != nil {
+		if spec.DisableHTTP2 {
+			return fmt.Errorf("http2 can't be set if disableHTTP2 is true")
+		}
+		if err := spec.HTTP2.Validate(); err != nil {
+			return fmt.Errorf("http2: %w", err)
+		}
+	}
+
+	if spec.ControllerEmulation != nil {
+		if err := spec.ControllerEmulation.Validate(); err != nil {
+			return fmt.Errorf("controllerEmulation: %w", err)
+		}
+	}
+
+	if spec.Warmup != nil {
+		if err := spec.Warmup.Validate(); err != nil {
+			return fmt.Errorf("warmup: %w", err)
+		}
+	}
+
+	if spec.AbortConditions != nil {
+		if err := spec.AbortConditions.Validate(); err != nil {
+			return fmt.Errorf("abortConditions: %w", err)
+		}
+	}
+
+	if spec.ConnChurn != nil {
+		if err := spec.ConnChurn.Validate(); err != nil {
+			return fmt.Errorf("connChurn: %w", err)
+		}
+	}
+
+	if len(spec.Endpoints) > 0 && spec.DiscoverEndpoints {
+		return fmt.Errorf("only one of endpoints and discoverEndpoints can be set")
+	}
+	for _, ep := range spec.Endpoints {
+		if err := ValidateEndpoint(ep); err != nil {
+			return fmt.Errorf("endpoints: %w", err)
+		}
+	}
+
+	if spec.Decode != nil {
+		if err := spec.Decode.Validate(); err != nil {
+			return fmt.Errorf("decode: %w", err)
+		}
+	}
+
 	for idx, req := range spec.Requests {
 		if err := req.Validate(); err != nil {
 			return fmt.Errorf("idx: %v request: %v", idx, err)
 		}
+		if req.Expect != nil && spec.Decode == nil {
+			return fmt.Errorf("idx: %v request: expect requires decode", idx)
+		}
+	}
+	return nil
+}
+
+// Validate verifies fields of ControllerEmulationSpec.
+func (c ControllerEmulationSpec) Validate() error {
+	if c.QPS < 0 {
+		return fmt.Errorf("qps requires >= 0: %v", c.QPS)
+	}
+	if c.Burst < 0 {
+		return fmt.Errorf("burst requires >= 0: %v", c.Burst)
+	}
+	if c.BackoffMax < 0 {
+		return fmt.Errorf("backoffMax requires >= 0: %v", c.BackoffMax)
+	}
+	return nil
+}
+
+// Validate verifies fields of HTTP2Spec.
+func (h HTTP2Spec) Validate() error {
+	if h.PingTimeout < 0 {
+		return fmt.Errorf("pingTimeout requires >= 0: %v", h.PingTimeout)
+	}
+	return nil
+}
+
+// Validate verifies fields of DecodeSpec.
+func (d DecodeSpec) Validate() error {
+	switch d.Target {
+	case "", DecodeTargetUnstructured, DecodeTargetMetadata:
+		return nil
+	default:
+		return fmt.Errorf("unsupported target %s", d.Target)
+	}
+}
+
+// Validate verifies fields of ResponseExpectation. The item assertions only
+// work for list requests.
+func (e ResponseExpectation) Validate(list bool) error {
+	if e.StatusCode != 0 && (e.StatusCode < 100 || e.StatusCode > 599) {
+		return fmt.Errorf("statusCode requires [100, 599]: %v", e.StatusCode)
+	}
+	if e.Items != nil && *e.Items < 0 {
+		return fmt.Errorf("items requires >= 0: %v", *e.Items)
+	}
+	if e.MinItems < 0 {
+		return fmt.Errorf("minItems requires >= 0: %v", e.MinItems)
+	}
+	if !list && (e.Items != nil || e.MinItems > 0) {
+		return fmt.Errorf("items and minItems only work for list requests")
+	}
+	return nil
+}
+
+// Validate verifies fields of WarmupSpec.
+func (w WarmupSpec) Validate() error {
+	if w.Total < 0 {
+		return fmt.Errorf("total requires >= 0: %v", w.Total)
+	}
+	if w.Duration < 0 {
+		return fmt.Errorf("duration requires >= 0: %v", w.Duration)
+	}
+	if (w.Total > 0) == (w.Duration > 0) {
+		return fmt.Errorf("only one of total(%v) and duration(%v) requires > 0", w.Total, w.Duration)
+	}
+	return nil
+}
+
+// Validate verifies fields of AbortConditions.
+func (c AbortConditions) Validate() error {
+	if c.Window < 0 {
+		return fmt.Errorf("window requires >= 0: %v", c.Window)
+	}
+	if c.MinRequests < 0 {
+		return fmt.Errorf("minRequests requires >= 0: %v", c.MinRequests)
+	}
+	if c.MaxErrorRate < 0 || c.MaxErrorRate > 100 {
+		return fmt.Errorf("maxErrorRate requires [0, 100]: %v", c.MaxErrorRate)
+	}
+	if c.MaxTooManyRequestsRate < 0 || c.MaxTooManyRequestsRate > 100 {
+		return fmt.Errorf("maxTooManyRequestsRate requires [0, 100]: %v", c.MaxTooManyRequestsRate)
+	}
+	if c.MaxP99Latency < 0 {
+		return fmt.Errorf("maxP99Latency requires >= 0: %v", c.MaxP99Latency)
+	}
+	if c.MaxConsecutiveConnectionErrors < 0 {
+		return fmt.Errorf("maxConsecutiveConnectionErrors requires >= 0: %v", c.MaxConsecutiveConnectionErrors)
+	}
+	return nil
+}
+
+// ValidateEndpoint verifies apiserver endpoint in the format of IP or
+// IP:PORT.
+func ValidateEndpoint(ep string) error {
+	host := ep
+	if h, port, err := net.SplitHostPort(ep); err == nil {
+		if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+			return fmt.Errorf("invalid port in endpoint %s", ep)
+		}
+		host = h
+	}
+	if net.ParseIP(host) == nil {
+		return fmt.Errorf("invalid IP in endpoint %s", ep)
+	}
+	return nil
+}
+
+// Validate verifies fields of ConnChurn.
+func (c ConnChurn) Validate() error {
+	if c.Interval < 0 {
+		return fmt.Errorf("interval requires >= 0: %v", c.Interval)
+	}
+	if c.Fraction < 0 || c.Fraction > 1 {
+		return fmt.Errorf("fraction requires [0, 1]: %v", c.Fraction)
+	}
+	if (c.Interval > 0) != (c.Fraction > 0) {
+		return fmt.Errorf("interval(%v) and fraction(%v) require > 0 together", c.Interval, c.Fraction)
+	}
+	if c.Jitter < 0 {
+		return fmt.Errorf("jitter requires >= 0: %v", c.Jitter)
+	}
+	if c.StormAt < 0 {
+		return fmt.Errorf("stormAt requires >= 0: %v", c.StormAt)
+	}
+	if c.Interval == 0 && c.StormAt == 0 {
+		return fmt.Errorf("either interval or stormAt requires > 0")
 	}
 	return nil
 }
@@ -256,6 +595,16 @@ func (r WeightedRequest) Validate() error {
 		return fmt.Errorf("shares(%v) requires >= 0", r.Shares)
 	}
 
+	if r.Timeout < 0 {
+		return fmt.Errorf("timeout(%v) requires >= 0", r.Timeout)
+	}
+
+	if r.Expect != nil {
+		if err := r.Expect.Validate(r.StaleList != nil || r.QuorumList != nil); err != nil {
+			return fmt.Errorf("expect: %w", err)
+		}
+	}
+
 	switch {
 	case r.StaleList != nil:
 		return r.StaleList.Validate(true)
@@ -218,3 +218,45 @@ func TestWeightedRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestWarmupSpec(t *testing.T) {
+	for _, r := range []struct {
+		name   string
+		warmup WarmupSpec
+		hasErr bool
+	}{
+		{
+			name:   "empty",
+			warmup: WarmupSpec{},
+			hasErr: true,
+		},
+		{
+			name:   "both total and duration",
+			warmup: WarmupSpec{Total: 100, Duration: 10},
+			hasErr: true,
+		},
+		{
+			name:   "negative duration",
+			warmup: WarmupSpec{Total: 100, Duration: -1},
+			hasErr: true,
+		},
+		{
+			name:   "total",
+			warmup: WarmupSpec{Total: 100},
+		},
+		{
+			name:   "duration with discard",
+			warmup: WarmupSpec{Duration: 10, Discard: true},
+		},
+	} {
+		r := r
+		t.Run(r.name, func(t *testing.T) {
+			err := r.warmup.Validate()
+			if r.hasErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -18,6 +18,34 @@ const (
 	// ResponseErrorTypeConnection indicates that error is related to connection.
 	// For instance, connection refused caused by server down.
 	ResponseErrorTypeConnection ResponseErrorType = "connection"
+	// ResponseErrorTypeClientTimeout indicates that the request didn't
+	// finish before client-side timeout. It's different from http code 504
+	// which is returned by server.
+	ResponseErrorTypeClientTimeout ResponseErrorType = "client-timeout"
+	// ResponseErrorTypeAssertion indicates that the response didn't match
+	// the expectation defined by WeightedRequest.Expect.
+	ResponseErrorTypeAssertion ResponseErrorType = "assertion"
+)
+
+// RequestPhase is the phase of HTTP request.
+type RequestPhase string
+
+const (
+	// RequestPhaseDNS is the DNS lookup. It only happens for new connection.
+	RequestPhaseDNS RequestPhase = "dns"
+	// RequestPhaseConnect is the TCP connect. It only happens for new
+	// connection.
+	RequestPhaseConnect RequestPhase = "connect"
+	// RequestPhaseTLS is the TLS handshake. It only happens for new
+	// connection.
+	RequestPhaseTLS RequestPhase = "tls"
+	// RequestPhaseTTFB is the time to first byte, from the request being
+	// written to the first byte of response being received. It's mostly
+	// server processing time.
+	RequestPhaseTTFB RequestPhase = "ttfb"
+	// RequestPhaseBodyRead is the time from the first byte of response to
+	// the whole body being read.
+	RequestPhaseBodyRead RequestPhase = "bodyRead"
 )
 
 // ResponseError is the record about that error.
@@ -39,14 +67,71 @@ type ResponseError struct {
 	Message string `json:"message"`
 }
 
+// ResponseErrorClass is the exact count of errors which have the same type,
+// code, message class and request.
+type ResponseErrorClass struct {
+	Method string `json:"method"`
+	// URL indicates target resource.
+	URL string `json:"url"`
+	// Type indicates that category to which the errors belong.
+	Type ResponseErrorType `json:"type"`
+	// Code only works when Type is http.
+	Code int `json:"code,omitempty"`
+	// MessageClass is the error message with variable parts, like
+	// addresses and quoted URLs, masked.
+	MessageClass string `json:"messageClass,omitempty"`
+	// Count is the number of errors.
+	Count int `json:"count"`
+	// FirstSeen is when the first error was received.
+	FirstSeen time.Time `json:"firstSeen"`
+	// LastSeen is when the last error was received.
+	LastSeen time.Time `json:"lastSeen"`
+}
+
 // ResponseStats is the report about benchmark result.
 type ResponseStats struct {
-	// Errors stores all the observed errors.
+	// Errors stores the sample of observed errors. The size of sample is
+	// bounded while ErrorClasses counts all the errors.
 	Errors []ResponseError
-	// LatenciesByURL stores all the observed latencies for each request.
+	// ErrorClasses stores the exact count of errors by class.
+	ErrorClasses []ResponseErrorClass
+	// LatencyHistogramsByURL stores the histogram of observed latencies
+	// for each request.
+	LatencyHistogramsByURL map[string]*LatencyHistogram
+	// LatenciesByURL stores all the observed latencies for each request
+	// if raw samples are kept.
 	LatenciesByURL map[string][]float64
+	// LatencyHistogramsByOutcome stores the histogram of observed
+	// latencies, including failed requests, for each HTTP status code, or
+	// error type if there is no response.
+	LatencyHistogramsByOutcome map[string]*LatencyHistogram
+	// LatencyHistogramsByRetries stores the histogram of observed
+	// latencies of requests which succeeded after client retries for each
+	// number of retries.
+	LatencyHistogramsByRetries map[int]*LatencyHistogram
 	// TotalReceivedBytes is total bytes read from apiserver.
 	TotalReceivedBytes int64
+	// PhaseLatencyHistograms stores the histogram of observed latencies
+	// for each phase.
+	PhaseLatencyHistograms map[RequestPhase]*LatencyHistogram
+	// PhaseLatencies stores all the observed latencies for each phase if
+	// raw samples are kept.
+	PhaseLatencies map[RequestPhase][]float64
+	// DecodeByURL stores the observation of decoding responses for each
+	// request.
+	DecodeByURL map[string]*DecodeReport
+	// ByFlowSchema stores the observation of API Priority and Fairness
+	// for each flow schema UID.
+	ByFlowSchema map[string]*FlowControlReport
+	// ByPriorityLevel stores the observation of API Priority and Fairness
+	// for each priority level UID.
+	ByPriorityLevel map[string]*FlowControlReport
+	// TimeSeries stores the observation per interval if it's enabled.
+	TimeSeries *TimeSeries
+	// CountsByURL stores the number of requests for each request.
+	CountsByURL map[string]*RequestCounts
+	// LimiterWait stores the time spent waiting for rate limiters.
+	LimiterWait *LimiterWaitReport
 }
 
 type RunnerMetricReport struct {
@@ -54,18 +139,360 @@ type RunnerMetricReport struct {
 	Total int `json:"total"`
 	// Duration means the time of benchmark.
 	Duration string `json:"duration"`
-	// Errors stores all the observed errors.
+	// Dispatched is the number of requests sent to apiserver.
+	Dispatched int `json:"dispatched"`
+	// Succeeded is the number of succeeded requests.
+	Succeeded int `json:"succeeded"`
+	// Failed is the number of failed requests.
+	Failed int `json:"failed"`
+	// AchievedQPS is the number of succeeded requests per second.
+	AchievedQPS float64 `json:"achievedQPS"`
+	// CountsByURL stores the number of requests per request.
+	CountsByURL map[string]*RequestCounts `json:"countsByURL,omitempty"`
+	// LimiterWait is the report about time spent waiting for rate
+	// limiters before sending requests.
+	LimiterWait *LimiterWaitReport `json:"limiterWait,omitempty"`
+	// Errors stores the sample of observed errors if raw data is
+	// requested.
 	Errors []ResponseError `json:"errors,omitempty"`
 	// ErrorStats means summary of errors group by type.
 	ErrorStats map[string]int32 `json:"errorStats,omitempty"`
+	// ErrorClasses stores the exact count and the first and last
+	// occurrence of errors by type, code, message class and request.
+	ErrorClasses []ResponseErrorClass `json:"errorClasses,omitempty"`
 	// TotalReceivedBytes is total bytes read from apiserver.
 	TotalReceivedBytes int64 `json:"totalReceivedBytes"`
-	// LatenciesByURL stores all the observed latencies.
+	// LatencyHistogramsByURL stores the histogram of observed latencies
+	// per request. Histograms from different reports can be merged.
+	LatencyHistogramsByURL map[string]*LatencyHistogram `json:"latencyHistogramsByURL,omitempty"`
+	// LatenciesByURL stores all the observed latencies if raw data is
+	// requested.
 	LatenciesByURL map[string][]float64 `json:"latenciesByURL,omitempty"`
 	// PercentileLatencies represents the latency distribution in seconds.
 	PercentileLatencies [][2]float64 `json:"percentileLatencies,omitempty"`
 	// PercentileLatenciesByURL represents the latency distribution in seconds per request.
 	PercentileLatenciesByURL map[string][][2]float64 `json:"percentileLatenciesByURL,omitempty"`
+	// LatencyHistogramsByOutcome stores the histogram of observed
+	// latencies, including failed requests, per HTTP status code, or
+	// error type if there is no response, for instance, connection.
+	LatencyHistogramsByOutcome map[string]*LatencyHistogram `json:"latencyHistogramsByOutcome,omitempty"`
+	// PercentileLatenciesByOutcome represents the latency distribution in
+	// seconds per HTTP status code or error type.
+	PercentileLatenciesByOutcome map[string][][2]float64 `json:"percentileLatenciesByOutcome,omitempty"`
+	// LatencyHistogramsByRetries stores the histogram of observed
+	// latencies of requests which succeeded after client retries. The key
+	// is the number of retries.
+	LatencyHistogramsByRetries map[int]*LatencyHistogram `json:"latencyHistogramsByRetries,omitempty"`
+	// PercentileLatenciesByRetries represents the latency distribution in
+	// seconds of requests which succeeded after client retries per number
+	// of retries.
+	PercentileLatenciesByRetries map[int][][2]float64 `json:"percentileLatenciesByRetries,omitempty"`
+	// PhaseLatencyHistograms stores the histogram of observed latencies
+	// for each HTTP request phase.
+	PhaseLatencyHistograms map[RequestPhase]*LatencyHistogram `json:"phaseLatencyHistograms,omitempty"`
+	// PhaseLatencies stores all the observed latencies for each HTTP
+	// request phase.
+	PhaseLatencies map[RequestPhase][]float64 `json:"phaseLatencies,omitempty"`
+	// PercentilePhaseLatencies represents the latency distribution in
+	// seconds for each HTTP request phase.
+	PercentilePhaseLatencies map[RequestPhase][][2]float64 `json:"percentilePhaseLatencies,omitempty"`
+	// DecodeByURL represents the cost of decoding responses per request
+	// if responses are decoded.
+	DecodeByURL map[string]*DecodeReport `json:"decodeByURL,omitempty"`
+	// ByFlowSchema breaks down the report by the flow schema which
+	// apiserver classified requests into. The key is the flow schema's
+	// name, or UID if the name can't be resolved.
+	ByFlowSchema map[string]*FlowControlReport `json:"byFlowSchema,omitempty"`
+	// ByPriorityLevel breaks down the report by the priority level which
+	// apiserver classified requests into. The key is the priority level's
+	// name, or UID if the name can't be resolved.
+	ByPriorityLevel map[string]*FlowControlReport `json:"byPriorityLevel,omitempty"`
+	// ByVerb aggregates the report by Kubernetes API verb, for instance,
+	// GET, LIST and WATCH, like apiserver's latency SLIs.
+	ByVerb map[string]*AggregateReport `json:"byVerb,omitempty"`
+	// ByResource aggregates the report by resource, including group and
+	// subresource, for instance, pods, deployments.apps and pods/log.
+	ByResource map[string]*AggregateReport `json:"byResource,omitempty"`
+	// ByScope aggregates the report by scope, which is resource (single
+	// object), namespace or cluster.
+	ByScope map[string]*AggregateReport `json:"byScope,omitempty"`
+	// Warmup represents the report of warmup phase, which is excluded
+	// from the other fields.
+	Warmup *RunnerMetricReport `json:"warmup,omitempty"`
+	// Aborted is true if the run was stopped by abort conditions.
+	Aborted bool `json:"aborted,omitempty"`
+	// AbortReason shows which abort condition fired.
+	AbortReason string `json:"abortReason,omitempty"`
+	// Interrupted is true if the run was stopped by signal before it's
+	// done. The report only covers requests finished before that.
+	Interrupted bool `json:"interrupted,omitempty"`
+	// Connections is the report about connections' lifecycle.
+	Connections *ConnectionReport `json:"connections,omitempty"`
+	// Endpoints breaks down the report by apiserver endpoint if
+	// connections are pinned to endpoints.
+	Endpoints map[string]*EndpointReport `json:"endpoints,omitempty"`
+	// TimeSeries breaks down the report by interval so that changes in
+	// the middle of run, for instance, stalls, are visible.
+	TimeSeries *TimeSeries `json:"timeSeries,omitempty"`
+}
+
+// RequestCounts is the number of requests to one URL.
+type RequestCounts struct {
+	// Dispatched is the number of requests sent to apiserver.
+	Dispatched int `json:"dispatched"`
+	// Succeeded is the number of succeeded requests.
+	Succeeded int `json:"succeeded"`
+	// Failed is the number of failed requests.
+	Failed int `json:"failed"`
+	// ReceivedBytes is the bytes read from apiserver.
+	ReceivedBytes int64 `json:"receivedBytes"`
+	// AchievedQPS is the number of succeeded requests per second. It's
+	// only set in report.
+	AchievedQPS float64 `json:"achievedQPS,omitempty"`
+}
+
+// LimiterWaitReport is the report about time spent waiting for rate
+// limiters before sending requests.
+type LimiterWaitReport struct {
+	// Waits is the number of waits, one per dispatched request.
+	Waits int `json:"waits"`
+	// TotalSeconds is the sum of wait time in seconds.
+	TotalSeconds float64 `json:"totalSeconds"`
+	// MeanSeconds is the mean of wait time in seconds.
+	MeanSeconds float64 `json:"meanSeconds"`
+}
+
+// AggregateReport is the report about requests aggregated by one of verb,
+// resource or scope.
+type AggregateReport struct {
+	// Total is the number of requests.
+	Total int `json:"total"`
+	// Failures is the number of failed requests.
+	Failures int `json:"failures,omitempty"`
+	// Histogram is the histogram of succeeded requests' latencies in
+	// seconds.
+	Histogram *LatencyHistogram `json:"histogram,omitempty"`
+	// PercentileLatencies represents the latency distribution in seconds
+	// of succeeded requests.
+	PercentileLatencies [][2]float64 `json:"percentileLatencies,omitempty"`
+}
+
+// FlowControlReport is the report about requests which API Priority and
+// Fairness classified into one flow schema or priority level.
+type FlowControlReport struct {
+	// UID is the UID of the flow schema or priority level.
+	UID string `json:"uid,omitempty"`
+	// Requests is the number of requests.
+	Requests int `json:"requests"`
+	// Failures is the number of failed requests.
+	Failures int `json:"failures,omitempty"`
+	// Throttled is the number of 429 responses, including the ones which
+	// have been retried.
+	Throttled int `json:"throttled,omitempty"`
+	// Retries is the number of retries made by client.
+	Retries int `json:"retries,omitempty"`
+	// MaxRetryAfterSeconds is the maximum value of Retry-After header in
+	// 429 responses.
+	MaxRetryAfterSeconds int `json:"maxRetryAfterSeconds,omitempty"`
+	// Histogram is the histogram of succeeded requests' latencies in
+	// seconds.
+	Histogram *LatencyHistogram `json:"histogram,omitempty"`
+	// Latencies stores the latencies of succeeded requests in seconds if
+	// raw data is requested.
+	Latencies []float64 `json:"latencies,omitempty"`
+	// PercentileLatencies represents the latency distribution of
+	// succeeded requests in seconds.
+	PercentileLatencies [][2]float64 `json:"percentileLatencies,omitempty"`
+}
+
+// DecodeReport is the report about decoding responses of one request.
+type DecodeReport struct {
+	// Responses is the number of decoded responses.
+	Responses int `json:"responses"`
+	// AllocatedBytes is the total bytes allocated by decoding. It's
+	// approximate since it's measured by process-wide heap allocations,
+	// which include allocations made by other goroutines meanwhile.
+	AllocatedBytes uint64 `json:"allocatedBytes"`
+	// Lists is the number of decoded list responses.
+	Lists int `json:"lists,omitempty"`
+	// TotalItems is the total number of items in list responses.
+	TotalItems int64 `json:"totalItems,omitempty"`
+	// MinItems is the minimum number of items in one list response.
+	MinItems int `json:"minItems,omitempty"`
+	// MaxItems is the maximum number of items in one list response.
+	MaxItems int `json:"maxItems,omitempty"`
+	// Histogram is the histogram of decode latencies in seconds.
+	Histogram *LatencyHistogram `json:"histogram,omitempty"`
+	// Latencies stores all the observed decode latencies in seconds if
+	// raw data is requested.
+	Latencies []float64 `json:"latencies,omitempty"`
+	// PercentileLatencies represents the decode latency distribution in
+	// seconds.
+	PercentileLatencies [][2]float64 `json:"percentileLatencies,omitempty"`
+}
+
+// EndpointReport is the report about requests sent to one apiserver
+// endpoint.
+type EndpointReport struct {
+	// Total is the number of finished requests.
+	Total int `json:"total"`
+	// ErrorStats means summary of errors group by type.
+	ErrorStats map[string]int32 `json:"errorStats,omitempty"`
+	// Histogram is the histogram of observed latencies in seconds.
+	Histogram *LatencyHistogram `json:"histogram,omitempty"`
+	// Latencies stores all the observed latencies if raw data is
+	// requested.
+	Latencies []float64 `json:"latencies,omitempty"`
+	// PercentileLatencies represents the latency distribution in seconds.
+	PercentileLatencies [][2]float64 `json:"percentileLatencies,omitempty"`
+}
+
+// TimeSeries is the observation bucketed by interval.
+type TimeSeries struct {
+	// IntervalSeconds is the length of interval in seconds.
+	IntervalSeconds float64 `json:"intervalSeconds"`
+	// Points stores the observation of each interval in time order.
+	// Intervals without any observation are omitted.
+	Points []TimeSeriesPoint `json:"points"`
+}
+
+// TimeSeriesPoint is the observation of one interval.
+type TimeSeriesPoint struct {
+	// Timestamp is the start of interval, aligned to IntervalSeconds since
+	// Unix epoch so that points from different runners can be merged.
+	Timestamp time.Time `json:"timestamp"`
+	// Requests is the number of requests finished in the interval,
+	// including failed ones.
+	Requests int `json:"requests"`
+	// ErrorStats means summary of errors group by type.
+	ErrorStats map[string]int32 `json:"errorStats,omitempty"`
+	// ReceivedBytes is the bytes read from apiserver.
+	ReceivedBytes int64 `json:"receivedBytes"`
+	// Histogram is the histogram of succeeded requests' latencies.
+	Histogram *LatencyHistogram `json:"histogram,omitempty"`
+	// PercentileLatencies represents the latency distribution of
+	// succeeded requests in seconds.
+	PercentileLatencies [][2]float64 `json:"percentileLatencies,omitempty"`
+	// ByURL breaks down the point by request.
+	ByURL map[string]*TimeSeriesURLPoint `json:"byURL,omitempty"`
+}
+
+// TimeSeriesURLPoint is the observation of one request in one interval.
+type TimeSeriesURLPoint struct {
+	// Requests is the number of requests finished in the interval,
+	// including failed ones.
+	Requests int `json:"requests"`
+	// Errors is the number of failed requests.
+	Errors int `json:"errors,omitempty"`
+	// Histogram is the histogram of succeeded requests' latencies.
+	Histogram *LatencyHistogram `json:"histogram,omitempty"`
+	// PercentileLatencies represents the latency distribution of
+	// succeeded requests in seconds.
+	PercentileLatencies [][2]float64 `json:"percentileLatencies,omitempty"`
+}
+
+// LatencyHistogram is the histogram of latencies in seconds, recorded into
+// logarithmic buckets. The bucket at index i covers (γ^(i-1), γ^i], where
+// γ = (1+RelativeAccuracy)/(1-RelativeAccuracy). Histograms with the same
+// RelativeAccuracy can be merged exactly by adding counts of same buckets.
+type LatencyHistogram struct {
+	// RelativeAccuracy is the bound of relative error of quantiles.
+	RelativeAccuracy float64 `json:"relativeAccuracy"`
+	// Count is the number of values.
+	Count uint64 `json:"count"`
+	// Sum is the sum of values.
+	Sum float64 `json:"sum"`
+	// Min is the minimum value.
+	Min float64 `json:"min"`
+	// Max is the maximum value.
+	Max float64 `json:"max"`
+	// ZeroCount is the number of values too small to have bucket.
+	ZeroCount uint64 `json:"zeroCount,omitempty"`
+	// Offset is the bucket index of Counts[0].
+	Offset int `json:"offset"`
+	// Counts stores the number of values in consecutive buckets.
+	Counts []uint64 `json:"counts,omitempty"`
+}
+
+// ConnectionReport is the report about connections' lifecycle.
+type ConnectionReport struct {
+	// Dials is the number of dialed connections.
+	Dials int `json:"dials"`
+	// DialErrors is the number of failed dials.
+	DialErrors int `json:"dialErrors,omitempty"`
+	// DialLatencies stores all the observed dial latencies in seconds. It's
+	// only reported with raw data.
+	DialLatencies []float64 `json:"dialLatencies,omitempty"`
+	// DialLatencyHistogram is the histogram of dial latencies in seconds
+	// so that reports can be merged without raw data.
+	DialLatencyHistogram *LatencyHistogram `json:"dialLatencyHistogram,omitempty"`
+	// PercentileDialLatencies represents the dial latency distribution in
+	// seconds.
+	PercentileDialLatencies [][2]float64 `json:"percentileDialLatencies,omitempty"`
+	// TLSHandshakes is the number of TLS handshakes.
+	TLSHandshakes int `json:"tlsHandshakes"`
+	// TLSHandshakeErrors is the number of failed TLS handshakes.
+	TLSHandshakeErrors int `json:"tlsHandshakeErrors,omitempty"`
+	// Closed is the number of connections closed by churn.
+	Closed int `json:"closed,omitempty"`
+	// ChurnEvents stores all the churn events in time order.
+	ChurnEvents []ConnChurnEvent `json:"churnEvents,omitempty"`
+	// HTTP2 is the report about HTTP/2 frames which affect connections.
+	// It's nil if HTTP/2 is disabled.
+	HTTP2 *HTTP2Report `json:"http2,omitempty"`
+}
+
+// HTTP2Report is the report about HTTP/2 connections.
+type HTTP2Report struct {
+	// GoAways is the number of GOAWAY frames received.
+	GoAways int `json:"goAways"`
+	// RefusedStreams is the number of streams reset by server with
+	// REFUSED_STREAM.
+	RefusedStreams int `json:"refusedStreams"`
+	// Replacements is the number of connections dialed to replace the ones
+	// which went away or were closed.
+	Replacements int `json:"replacements"`
+	// LostPings is the number of connections closed since the ping
+	// response wasn't received in time.
+	LostPings int `json:"lostPings,omitempty"`
+	// PerConnection breaks down the report by connection. The i-th item
+	// is about the i-th connection defined by LoadProfileSpec.Conns,
+	// including the ones dialed to replace it.
+	PerConnection []HTTP2ConnectionReport `json:"perConnection,omitempty"`
+}
+
+// HTTP2ConnectionReport is the report about one HTTP/2 connection.
+type HTTP2ConnectionReport struct {
+	// Dials is the number of dialed connections.
+	Dials int `json:"dials"`
+	// GoAways is the number of GOAWAY frames received.
+	GoAways int `json:"goAways"`
+	// RefusedStreams is the number of streams reset by server with
+	// REFUSED_STREAM.
+	RefusedStreams int `json:"refusedStreams"`
+	// Replacements is the number of connections dialed to replace the ones
+	// which went away or were closed.
+	Replacements int `json:"replacements"`
+	// LostPings is the number of connections closed since the ping
+	// response wasn't received in time.
+	LostPings int `json:"lostPings,omitempty"`
+	// MaxConcurrentStreams is the last max concurrent streams setting
+	// advertised by server. Zero means that server didn't advertise it.
+	MaxConcurrentStreams uint32 `json:"maxConcurrentStreams,omitempty"`
+}
+
+// ConnChurnEvent records the connections closed at once by churn.
+type ConnChurnEvent struct {
+	// Timestamp indicates when connections were closed.
+	Timestamp time.Time `json:"timestamp"`
+	// Storm is true if all the connections were closed.
+	Storm bool `json:"storm,omitempty"`
+	// Closed is the number of closed connections.
+	Closed int `json:"closed"`
+	// RecoverySeconds is how long it took the throughput to get back to
+	// 90% of the throughput before the event. It's nil if the throughput
+	// didn't recover before the run ended or no baseline was available.
+	RecoverySeconds *float64 `json:"recoverySeconds,omitempty"`
 }
 
 // MultiSpecRunnerMetricReport contains results for multiple specs with aggregated summary.
@@ -79,3 +506,40 @@ type MultiSpecRunnerMetricReport struct {
 // TODO(weifu): build brand new struct for RunnerGroupsReport to include more
 // information, like how many runner groups, service account and flow control.
 type RunnerGroupsReport = RunnerMetricReport
+
+// ThroughputSearchTrial is the result of one short trial run by the
+// throughput search.
+type ThroughputSearchTrial struct {
+	// OfferedQPS is the rate limit applied during this trial.
+	OfferedQPS float64 `json:"offeredQPS"`
+	// AchievedQPS is the number of succeeded requests per second.
+	AchievedQPS float64 `json:"achievedQPS"`
+	// Total is the number of requests sent during this trial.
+	Total int `json:"total"`
+	// Duration means the time of this trial.
+	Duration string `json:"duration"`
+	// P50Latency is the median latency in seconds.
+	P50Latency float64 `json:"p50Latency"`
+	// P99Latency is the 99th percentile latency in seconds.
+	P99Latency float64 `json:"p99Latency"`
+	// ErrorRate is the percentage of failed requests.
+	ErrorRate float64 `json:"errorRate"`
+	// Passed is true if this trial stayed within the SLO.
+	Passed bool `json:"passed"`
+}
+
+// ThroughputSearchReport is the report about throughput search.
+type ThroughputSearchReport struct {
+	// Spec is the search setting.
+	Spec ThroughputSearchSpec `json:"spec"`
+	// MaxQPS is the highest offered QPS which stayed within the SLO.
+	// Zero means that no trial passed.
+	MaxQPS float64 `json:"maxQPS"`
+	// MaxAchievedQPS is the achieved QPS of the trial running at MaxQPS.
+	MaxAchievedQPS float64 `json:"maxAchievedQPS"`
+	// Trials stores all the trials in the order they ran.
+	Trials []ThroughputSearchTrial `json:"trials"`
+	// Interrupted is true if the search was stopped before it's done.
+	// The trial cut short isn't included.
+	Interrupted bool `json:"interrupted,omitempty"`
+}
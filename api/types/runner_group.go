@@ -30,6 +30,12 @@ type RunnerGroupSpec struct {
 	//
 	// FORMAT: APIVersion:Kind:Name:UID
 	OwnerReference *string `json:"ownerReference,omitempty" yaml:"ownerReference,omitempty"`
+	// Trace means each runner records every dispatched request and uploads
+	// the trace file alongside the report.
+	Trace bool `json:"trace,omitempty" yaml:"trace,omitempty"`
+	// Percentiles are the quantiles, in [0, 1], reported in percentile
+	// latencies of the summary. 0.5 and 0.99 are always reported.
+	Percentiles []float64 `json:"percentiles,omitempty" yaml:"percentiles,omitempty"`
 }
 
 // RunnerGroupStatus represents current state of RunnerGroup.
@@ -55,3 +61,11 @@ const (
 	// RunnerGroupStatusStateFinished represents all runners finished.
 	RunnerGroupStatusStateFinished = "finished"
 )
+
+// RunnerGroupAbort records the first runner which aborted the runner group.
+type RunnerGroupAbort struct {
+	// Runner is the name of runner which fired abort condition.
+	Runner string `json:"runner"`
+	// Reason shows which abort condition fired.
+	Reason string `json:"reason"`
+}
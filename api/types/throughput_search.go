@@ -0,0 +1,78 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package types
+
+import "fmt"
+
+// ThroughputSearchMode defines how to pick the rate for the next trial.
+type ThroughputSearchMode string
+
+const (
+	// ThroughputSearchModeStep increases the rate by a fixed step until
+	// one trial breaks the SLO.
+	ThroughputSearchModeStep ThroughputSearchMode = "step"
+	// ThroughputSearchModeBinary bisects the rate range until the range
+	// is narrower than the precision.
+	ThroughputSearchModeBinary ThroughputSearchMode = "binary"
+)
+
+// ThroughputSearchSpec defines how to search the maximum throughput under
+// latency and error SLO.
+type ThroughputSearchSpec struct {
+	// Mode is the search strategy.
+	Mode ThroughputSearchMode `json:"mode" yaml:"mode"`
+	// MinRate is the lowest offered QPS to try.
+	MinRate float64 `json:"minRate" yaml:"minRate"`
+	// MaxRate is the highest offered QPS to try.
+	MaxRate float64 `json:"maxRate" yaml:"maxRate"`
+	// Step is the QPS increment between trials. Only valid in step mode.
+	Step float64 `json:"step,omitempty" yaml:"step,omitempty"`
+	// Precision stops the binary search when the range is narrower than
+	// it. Only valid in binary mode.
+	Precision float64 `json:"precision,omitempty" yaml:"precision,omitempty"`
+	// TrialDuration is the running time of each trial in seconds.
+	TrialDuration int `json:"trialDuration" yaml:"trialDuration"`
+	// MaxP99Latency is the p99 latency threshold in seconds (zero is no limit).
+	MaxP99Latency float64 `json:"maxP99Latency" yaml:"maxP99Latency"`
+	// MaxErrorRate is the error rate threshold in percentage.
+	MaxErrorRate float64 `json:"maxErrorRate" yaml:"maxErrorRate"`
+}
+
+// Validate verifies fields of ThroughputSearchSpec.
+func (s ThroughputSearchSpec) Validate() error {
+	if s.MinRate <= 0 {
+		return fmt.Errorf("minRate requires > 0: %v", s.MinRate)
+	}
+
+	if s.MaxRate < s.MinRate {
+		return fmt.Errorf("maxRate(%v) requires >= minRate(%v)", s.MaxRate, s.MinRate)
+	}
+
+	if s.TrialDuration <= 0 {
+		return fmt.Errorf("trialDuration requires > 0s: %v", s.TrialDuration)
+	}
+
+	if s.MaxP99Latency < 0 {
+		return fmt.Errorf("maxP99Latency requires >= 0: %v", s.MaxP99Latency)
+	}
+
+	if s.MaxErrorRate < 0 || s.MaxErrorRate > 100 {
+		return fmt.Errorf("maxErrorRate requires [0, 100]: %v", s.MaxErrorRate)
+	}
+
+	switch s.Mode {
+	case ThroughputSearchModeStep:
+		if s.Step <= 0 {
+			return fmt.Errorf("step requires > 0 in step mode: %v", s.Step)
+		}
+	case ThroughputSearchModeBinary:
+		if s.Precision <= 0 {
+			return fmt.Errorf("precision requires > 0 in binary mode: %v", s.Precision)
+		}
+	default:
+		return fmt.Errorf("unsupported search mode %q (valid modes: %s, %s)",
+			s.Mode, ThroughputSearchModeStep, ThroughputSearchModeBinary)
+	}
+	return nil
+}
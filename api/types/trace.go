@@ -0,0 +1,38 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package types
+
+import "time"
+
+// TraceRecord is one dispatched request in trace file. The trace file is
+// gzip-compressed JSON lines.
+type TraceRecord struct {
+	// Timestamp is when the request was dispatched.
+	Timestamp time.Time `json:"ts"`
+	// Method is the method used in metrics, like LIST and WATCHLIST.
+	Method string `json:"method"`
+	// HTTPMethod is the method sent over the wire.
+	HTTPMethod string `json:"httpMethod"`
+	// URL is the full URL sent over the wire.
+	URL string `json:"url"`
+	// ContentType is the content type of request body.
+	ContentType string `json:"contentType,omitempty"`
+	// BodyHash is the hex-encoded sha256 of request body.
+	BodyHash string `json:"bodyHash,omitempty"`
+	// BodySize is the size of request body.
+	BodySize int `json:"bodySize,omitempty"`
+	// Body is the request body. It's only recorded by the first request
+	// with the same BodyHash.
+	Body []byte `json:"body,omitempty"`
+	// Status is the http code of the last response. Zero means no response.
+	Status int `json:"status,omitempty"`
+	// Latency is the latency in seconds.
+	Latency float64 `json:"latency"`
+	// Bytes is the number of received bytes.
+	Bytes int64 `json:"bytes"`
+	// Error is the error message if the request failed.
+	Error string `json:"error,omitempty"`
+	// Warmup means the request was sent during warmup phase.
+	Warmup bool `json:"warmup,omitempty"`
+}
@@ -0,0 +1,177 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package profile
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/loadprofile"
+
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+	"k8s.io/klog/v2"
+)
+
+var generateCommand = cli.Command{
+	Name:  "generate",
+	Usage: "generate load profile from audit log or apiserver_request_total metrics",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "audit-log",
+			Usage: "Path to the audit log file (audit.k8s.io/v1 events in JSON lines)",
+		},
+		cli.StringFlag{
+			Name:  "metrics-before",
+			Usage: "Path to the first snapshot of kube-apiserver's metrics in text format",
+		},
+		cli.StringFlag{
+			Name:  "metrics-after",
+			Usage: "Path to the second snapshot of kube-apiserver's metrics in text format",
+		},
+		cli.Float64Flag{
+			Name:  "metrics-interval",
+			Usage: "Seconds between two metrics snapshots",
+		},
+		cli.StringFlag{
+			Name:  "namespace",
+			Usage: "Namespace used by namespaced requests generated from metrics",
+			Value: "default",
+		},
+		cli.StringFlag{
+			Name:  "name",
+			Usage: "Object name used by GET requests generated from metrics",
+			Value: "kperf-placeholder",
+		},
+		cli.StringSliceFlag{
+			Name:  "cluster-scoped-resource",
+			Usage: "Cluster-scoped resource besides the built-in ones, like CRDs, for requests generated from metrics (FORMAT: RESOURCE[.GROUP]). It can be repeated.",
+		},
+		cli.IntFlag{
+			Name:  "duration",
+			Usage: "Duration in seconds of generated profile",
+			Value: 60,
+		},
+		cli.IntFlag{
+			Name:  "conns",
+			Usage: "Total number of connections of generated profile",
+			Value: 10,
+		},
+		cli.IntFlag{
+			Name:  "client",
+			Usage: "Total number of HTTP clients of generated profile",
+			Value: 10,
+		},
+		cli.StringFlag{
+			Name:  "content-type",
+			Usage: fmt.Sprintf("Content type (%v, %v or %v)", types.ContentTypeJSON, types.ContentTypeProtobuffer, types.ContentTypeCBOR),
+			Value: string(types.ContentTypeJSON),
+		},
+		cli.StringFlag{
+			Name:  "output",
+			Usage: "Path to the generated load profile (default is stdout)",
+		},
+	},
+	Action: func(cliCtx *cli.Context) error {
+		obs, window, err := observe(cliCtx)
+		if err != nil {
+			return err
+		}
+
+		printUnsupported(obs.Unsupported())
+
+		profile, err := obs.Generate(window, loadprofile.GenerateOptions{
+			Duration:    cliCtx.Int("duration"),
+			Conns:       cliCtx.Int("conns"),
+			Client:      cliCtx.Int("client"),
+			ContentType: types.ContentType(cliCtx.String("content-type")),
+		})
+		if err != nil {
+			return err
+		}
+
+		data, err := yaml.Marshal(profile)
+		if err != nil {
+			return fmt.Errorf("failed to marshal load profile into yaml: %w", err)
+		}
+
+		if outputPath := cliCtx.String("output"); outputPath != "" {
+			return os.WriteFile(outputPath, data, 0600)
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	},
+}
+
+// observe returns observations from audit log or metrics snapshots.
+func observe(cliCtx *cli.Context) (*loadprofile.Observations, time.Duration, error) {
+	auditLogPath := cliCtx.String("audit-log")
+	beforePath, afterPath := cliCtx.String("metrics-before"), cliCtx.String("metrics-after")
+
+	switch {
+	case auditLogPath != "" && (beforePath != "" || afterPath != ""):
+		return nil, 0, fmt.Errorf("only one of --audit-log and --metrics-before/--metrics-after can be specified")
+	case auditLogPath != "":
+		f, err := os.Open(auditLogPath)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to open %s: %w", auditLogPath, err)
+		}
+		defer f.Close()
+
+		obs, window, err := loadprofile.ObserveAuditLog(f)
+		if err != nil {
+			return nil, 0, err
+		}
+		// Requests within one second are considered as one second.
+		if window < time.Second {
+			window = time.Second
+		}
+		return obs, window, nil
+	case beforePath != "" && afterPath != "":
+		interval := cliCtx.Float64("metrics-interval")
+		if interval <= 0 {
+			return nil, 0, fmt.Errorf("--metrics-interval requires > 0: %v", interval)
+		}
+
+		before, err := os.Open(beforePath)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to open %s: %w", beforePath, err)
+		}
+		defer before.Close()
+
+		after, err := os.Open(afterPath)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to open %s: %w", afterPath, err)
+		}
+		defer after.Close()
+
+		obs, err := loadprofile.ObserveRequestTotalDelta(before, after, loadprofile.MetricsObserveOptions{
+			Namespace:              cliCtx.String("namespace"),
+			Name:                   cliCtx.String("name"),
+			ClusterScopedResources: cliCtx.StringSlice("cluster-scoped-resource"),
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		return obs, time.Duration(interval * float64(time.Second)), nil
+	default:
+		return nil, 0, fmt.Errorf("required --audit-log or both --metrics-before and --metrics-after")
+	}
+}
+
+// printUnsupported warns the requests which aren't included in the profile.
+func printUnsupported(unsupported map[string]float64) {
+	keys := make([]string, 0, len(unsupported))
+	for k := range unsupported {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		klog.Warningf("Skipped %.0f unsupported requests: %s", unsupported[k], k)
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package profile
+
+import (
+	"github.com/urfave/cli"
+)
+
+// Command represents profile sub-command.
+var Command = cli.Command{
+	Name:  "profile",
+	Usage: "manage load profiles",
+	Subcommands: []cli.Command{
+		generateCommand,
+	},
+}
@@ -0,0 +1,125 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	kperfreport "github.com/Azure/kperf/report"
+
+	"github.com/urfave/cli"
+)
+
+var diffCommand = cli.Command{
+	Name:      "diff",
+	Usage:     "compare reports with the baseline, which is the first one, and fail if any regression is found",
+	ArgsUsage: "BASELINE TARGET [TARGET...]",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "threshold-percentiles",
+			Usage: "Comma-separated quantiles checked against --max-latency-increase",
+			Value: "0.5,0.9,0.99",
+		},
+		cli.Float64Flag{
+			Name:  "max-latency-increase",
+			Usage: "Maximum increase of latency percentiles in percent of baseline (negative disables)",
+			Value: 10,
+		},
+		cli.Float64Flag{
+			Name:  "min-latency-increase",
+			Usage: "Latency increase in seconds below which it isn't a regression, to tolerate noise of fast requests",
+			Value: 0.005,
+		},
+		cli.Float64Flag{
+			Name:  "max-error-rate-increase",
+			Usage: "Maximum increase of error rate in percentage points (negative disables)",
+			Value: 1,
+		},
+		cli.Float64Flag{
+			Name:  "max-throughput-decrease",
+			Usage: "Maximum decrease of achieved QPS in percent of baseline (negative disables)",
+			Value: 10,
+		},
+		cli.StringFlag{
+			Name:  "output-format",
+			Usage: fmt.Sprintf("Format of comparison, one of %v", []kperfreport.Format{kperfreport.FormatMarkdown, kperfreport.FormatCSV, kperfreport.FormatJSON}),
+			Value: string(kperfreport.FormatMarkdown),
+		},
+	},
+	Action: func(cliCtx *cli.Context) error {
+		if cliCtx.NArg() < 2 {
+			return fmt.Errorf("required at least two reports: baseline and target")
+		}
+
+		format, err := kperfreport.ParseFormat(cliCtx.String("output-format"))
+		if err != nil {
+			return err
+		}
+
+		th, err := thresholdsFromFlags(cliCtx)
+		if err != nil {
+			return err
+		}
+
+		paths := cliCtx.Args()
+		baseline, err := kperfreport.LoadReport(paths[0])
+		if err != nil {
+			return err
+		}
+
+		diffs := make([]kperfreport.Diff, 0, len(paths)-1)
+		regressions := 0
+		for _, path := range paths[1:] {
+			target, err := kperfreport.LoadReport(path)
+			if err != nil {
+				return err
+			}
+
+			d := kperfreport.Diff{
+				Baseline: filepath.Base(paths[0]),
+				Target:   filepath.Base(path),
+				Rows:     kperfreport.DiffReports(baseline, target, th),
+			}
+			regressions += len(d.Regressions())
+			diffs = append(diffs, d)
+		}
+
+		if err := kperfreport.RenderDiffs(os.Stdout, format, diffs); err != nil {
+			return err
+		}
+		if regressions > 0 {
+			return fmt.Errorf("found %d regressions", regressions)
+		}
+		return nil
+	},
+}
+
+// thresholdsFromFlags returns regression thresholds from flags.
+func thresholdsFromFlags(cliCtx *cli.Context) (kperfreport.Thresholds, error) {
+	th := kperfreport.Thresholds{
+		LatencyIncreasePercent:    cliCtx.Float64("max-latency-increase"),
+		MinLatencyIncrease:        cliCtx.Float64("min-latency-increase"),
+		ErrorRateIncrease:         cliCtx.Float64("max-error-rate-increase"),
+		ThroughputDecreasePercent: cliCtx.Float64("max-throughput-decrease"),
+	}
+
+	for _, str := range strings.Split(cliCtx.String("threshold-percentiles"), ",") {
+		if str = strings.TrimSpace(str); str == "" {
+			continue
+		}
+		q, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return th, fmt.Errorf("failed to parse percentile %s: %w", str, err)
+		}
+		if q < 0 || q > 1 {
+			return th, fmt.Errorf("percentile requires [0, 1]: %v", q)
+		}
+		th.Percentiles = append(th.Percentiles, q)
+	}
+	return th, nil
+}
@@ -0,0 +1,17 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package report
+
+import (
+	"github.com/urfave/cli"
+)
+
+// Command represents report sub-command.
+var Command = cli.Command{
+	Name:  "report",
+	Usage: "inspect benchmark reports",
+	Subcommands: []cli.Command{
+		diffCommand,
+	},
+}
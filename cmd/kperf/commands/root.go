@@ -9,6 +9,8 @@ import (
 	"os"
 	"strconv"
 
+	"github.com/Azure/kperf/cmd/kperf/commands/profile"
+	"github.com/Azure/kperf/cmd/kperf/commands/report"
 	"github.com/Azure/kperf/cmd/kperf/commands/runner"
 	"github.com/Azure/kperf/cmd/kperf/commands/runnergroup"
 	"github.com/Azure/kperf/cmd/kperf/commands/virtualcluster"
@@ -25,6 +27,8 @@ func App() *cli.App {
 		Commands: []cli.Command{
 			runner.Command,
 			runnergroup.Command,
+			profile.Command,
+			report.Command,
 			virtualcluster.Command,
 		},
 		Flags: []cli.Flag{
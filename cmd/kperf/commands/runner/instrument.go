@@ -0,0 +1,129 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Azure/kperf/metrics"
+	"github.com/Azure/kperf/request"
+
+	"github.com/urfave/cli"
+	"go.opentelemetry.io/otel"
+	"k8s.io/klog/v2"
+)
+
+// traceFlag records dispatched requests. It isn't part of
+// instrumentationFlags because replay-trace uses --trace as input.
+var traceFlag = cli.StringFlag{
+	Name:  "trace",
+	Usage: "Path to the file which records every dispatched request (gzip-compressed JSON lines)",
+}
+
+// instrumentationFlags are shared by run and replay commands to export
+// metrics while running.
+var instrumentationFlags = []cli.Flag{
+	cli.Float64Flag{
+		Name:  "time-series-interval",
+		Usage: "Break down the result by interval in seconds (0 disables). Each point keeps histograms in total and per URL, so the result grows with duration / interval",
+		Value: 0,
+	},
+	cli.StringFlag{
+		Name:  "metrics-address",
+		Usage: "Serve live Prometheus metrics on /metrics at the address (FORMAT: [HOST]:PORT) while running",
+	},
+	// NOTE: OTEL_EXPORTER_OTLP_ENDPOINT isn't bound since it's often
+	// injected for other protocols and would turn exporting on silently.
+	cli.StringFlag{
+		Name:  "otlp-endpoint",
+		Usage: "Export a span per sampled request and live metrics to the OTLP endpoint (e.g. http://localhost:4318 for HTTP, http://localhost:4317 for gRPC)",
+	},
+	cli.StringFlag{
+		Name:  "otlp-protocol",
+		Usage: "The protocol of --otlp-endpoint: " + request.OTLPProtocolGRPC + " or " + request.OTLPProtocolHTTPProtobuf,
+		Value: request.OTLPProtocolHTTPProtobuf,
+	},
+	cli.Float64Flag{
+		Name:  "otlp-sampling-ratio",
+		Usage: "The ratio of requests exported as spans by --otlp-endpoint, in [0, 1]",
+		Value: 0.01,
+	},
+}
+
+// instrumentationOptsFromFlags returns schedule options from
+// instrumentationFlags. Requests are recorded into tracePath if it isn't
+// empty. The returned function flushes and closes the trace file, the metrics
+// server and the OTLP exporter, and it must be called after the run.
+func instrumentationOptsFromFlags(cliCtx *cli.Context, tracePath string) (_ []request.ScheduleOpt, _ func(), retErr error) {
+	var closers []func()
+	closeAll := func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
+	}
+	defer func() {
+		if retErr != nil {
+			closeAll()
+		}
+	}()
+
+	opts := []request.ScheduleOpt{
+		request.WithScheduleTimeSeriesOpt(time.Duration(cliCtx.Float64("time-series-interval") * float64(time.Second))),
+	}
+
+	if tracePath != "" {
+		traceFile, err := createResultFile(tracePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create trace file: %w", err)
+		}
+
+		tw := request.NewTraceWriter(traceFile)
+		closers = append(closers, func() {
+			if err := tw.Close(); err != nil {
+				klog.ErrorS(err, "failed to close trace file", "path", tracePath)
+			}
+			traceFile.Close()
+		})
+		opts = append(opts, request.WithScheduleTraceWriterOpt(tw))
+	}
+
+	var live *metrics.LiveMetrics
+	if addr := cliCtx.String("metrics-address"); addr != "" {
+		live = metrics.NewLiveMetrics()
+		stop, err := serveLiveMetrics(addr, live)
+		if err != nil {
+			return nil, nil, err
+		}
+		closers = append(closers, stop)
+	}
+	if endpoint := cliCtx.String("otlp-endpoint"); endpoint != "" {
+		if live == nil {
+			live = metrics.NewLiveMetrics()
+		}
+		// The exporters report failures in background.
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+			klog.ErrorS(err, "failed to export to OTLP endpoint", "endpoint", endpoint)
+		}))
+		exp, err := request.NewOTLPExporter(endpoint,
+			request.WithOTLPProtocolOpt(cliCtx.String("otlp-protocol")),
+			request.WithOTLPSamplingRatioOpt(cliCtx.Float64("otlp-sampling-ratio")),
+			request.WithOTLPLiveMetricsOpt(live),
+			request.WithOTLPServiceInstanceOpt(cliCtx.String("user-agent")),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		closers = append(closers, func() {
+			if err := exp.Close(); err != nil {
+				klog.ErrorS(err, "failed to export to OTLP endpoint", "endpoint", endpoint)
+			}
+		})
+		opts = append(opts, request.WithScheduleOTLPExporterOpt(exp))
+	}
+	if live != nil {
+		opts = append(opts, request.WithScheduleLiveMetricsOpt(live))
+	}
+	return opts, closeAll, nil
+}
@@ -0,0 +1,254 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/cmd/kperf/commands/utils"
+	"github.com/Azure/kperf/request"
+
+	"github.com/urfave/cli"
+	"k8s.io/client-go/rest"
+)
+
+// replayFlags are shared by replay commands.
+var replayFlags = append([]cli.Flag{
+	cli.StringFlag{
+		Name:  "kubeconfig",
+		Usage: "Path to the kubeconfig file",
+		Value: utils.DefaultKubeConfigPath,
+	},
+	cli.Float64Flag{
+		Name:  "speed",
+		Usage: "Multiplier applied to the original timing (2 means twice as fast)",
+		Value: 1,
+	},
+	cli.IntFlag{
+		Name:  "conns",
+		Usage: "Total number of connections",
+		Value: 1,
+	},
+	cli.IntFlag{
+		Name:  "client",
+		Usage: "Total number of HTTP clients",
+		Value: 10,
+	},
+	cli.StringFlag{
+		Name:  "content-type",
+		Usage: fmt.Sprintf("Content type (%v, %v or %v)", types.ContentTypeJSON, types.ContentTypeProtobuffer, types.ContentTypeCBOR),
+		Value: string(types.ContentTypeJSON),
+	},
+	cli.BoolFlag{
+		Name:  "disable-http2",
+		Usage: "Disable HTTP2 protocol",
+	},
+	cli.Float64Flag{
+		Name:  "timeout",
+		Usage: "Client-side timeout in seconds for each request (0 means default 60s)",
+		Value: 0,
+	},
+	cli.StringFlag{
+		Name:  "user-agent",
+		Usage: "User Agent",
+	},
+	cli.StringFlag{
+		Name:  "result",
+		Usage: "Path to the file which stores results",
+	},
+	cli.BoolFlag{
+		Name:  "raw-data",
+		Usage: "show raw letencies data in result. It takes memory in proportion to the number of requests",
+	},
+	utils.PercentilesFlag,
+}, instrumentationFlags...)
+
+var replayCommand = cli.Command{
+	Name:  "replay",
+	Usage: "replay kube-apiserver audit log (audit.k8s.io/v1 events in JSON lines) as load",
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:     "audit-log",
+			Usage:    "Path to the audit log file",
+			Required: true,
+		},
+		cli.StringSliceFlag{
+			Name:  "verb",
+			Usage: "Only replay requests with the verb (get, list, create, update, patch, delete or deletecollection)",
+		},
+		cli.StringSliceFlag{
+			Name:  "resource",
+			Usage: "Only replay requests for the resource (FORMAT: RESOURCE[/SUBRESOURCE])",
+		},
+		cli.StringSliceFlag{
+			Name:  "namespace",
+			Usage: "Only replay requests in the namespace",
+		},
+		cli.StringSliceFlag{
+			Name:  "user",
+			Usage: "Only replay requests sent by the user",
+		},
+		cli.BoolFlag{
+			Name:  "impersonate",
+			Usage: "Send requests as the original users via impersonation",
+		},
+		cli.IntFlag{
+			Name:  "max-retries",
+			Usage: "Retry request after receiving 429 http code (<=0 means no retry)",
+			Value: 0,
+		},
+		traceFlag,
+	}, replayFlags...),
+	Action: func(cliCtx *cli.Context) error {
+		replayOpts, err := replayOptionsFromFlags(cliCtx)
+		if err != nil {
+			return err
+		}
+
+		auditLogPath := cliCtx.String("audit-log")
+		auditLog, err := os.Open(auditLogPath)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", auditLogPath, err)
+		}
+		defer auditLog.Close()
+
+		reqs, err := request.LoadAuditRequests(auditLog, request.AuditEventFilter{
+			Verbs:      cliCtx.StringSlice("verb"),
+			Resources:  cliCtx.StringSlice("resource"),
+			Namespaces: cliCtx.StringSlice("namespace"),
+			Users:      cliCtx.StringSlice("user"),
+		})
+		if err != nil {
+			return err
+		}
+		if len(reqs) == 0 {
+			return fmt.Errorf("no requests to replay in %s", auditLogPath)
+		}
+
+		return runReplay(cliCtx, cliCtx.String("trace"), func(ctx context.Context, restClis []rest.Interface, opts ...request.ScheduleOpt) (*request.Result, error) {
+			return request.ReplayAuditRequests(ctx, reqs, request.AuditReplayOptions{
+				ReplayOptions: *replayOpts,
+				MaxRetries:    cliCtx.Int("max-retries"),
+				Impersonate:   cliCtx.Bool("impersonate"),
+			}, restClis, opts...)
+		})
+	},
+}
+
+var replayTraceCommand = cli.Command{
+	Name:  "replay-trace",
+	Usage: "replay trace file recorded by run --trace to reproduce a run",
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:     "trace",
+			Usage:    "Path to the trace file",
+			Required: true,
+		},
+	}, replayFlags...),
+	Action: func(cliCtx *cli.Context) error {
+		replayOpts, err := replayOptionsFromFlags(cliCtx)
+		if err != nil {
+			return err
+		}
+
+		tracePath := cliCtx.String("trace")
+		traceFile, err := os.Open(tracePath)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", tracePath, err)
+		}
+		defer traceFile.Close()
+
+		records, err := request.ReadTrace(traceFile)
+		if err != nil {
+			return fmt.Errorf("failed to read trace %s: %w", tracePath, err)
+		}
+
+		// The trace is the input, so that the replay isn't recorded.
+		return runReplay(cliCtx, "", func(ctx context.Context, restClis []rest.Interface, opts ...request.ScheduleOpt) (*request.Result, error) {
+			return request.ReplayTrace(ctx, records, *replayOpts, restClis, opts...)
+		})
+	},
+}
+
+// replayOptionsFromFlags returns ReplayOptions from replayFlags.
+func replayOptionsFromFlags(cliCtx *cli.Context) (*request.ReplayOptions, error) {
+	speed := cliCtx.Float64("speed")
+	if speed <= 0 {
+		return nil, fmt.Errorf("speed requires > 0: %v", speed)
+	}
+
+	return &request.ReplayOptions{
+		Speed:      speed,
+		Client:     cliCtx.Int("client"),
+		Timeout:    time.Duration(cliCtx.Float64("timeout") * float64(time.Second)),
+		RawSamples: cliCtx.Bool("raw-data"),
+	}, nil
+}
+
+// runReplay creates clients from replayFlags, runs replayFn with
+// instrumentation options and writes the result. Requests are recorded into
+// tracePath if it isn't empty.
+func runReplay(cliCtx *cli.Context, tracePath string, replayFn func(context.Context, []rest.Interface, ...request.ScheduleOpt) (*request.Result, error)) error {
+	if err := utils.SetPercentilesFromFlag(cliCtx); err != nil {
+		return err
+	}
+
+	conns := cliCtx.Int("conns")
+	if conns <= 0 {
+		return fmt.Errorf("conns requires > 0: %v", conns)
+	}
+
+	contentType := types.ContentType(cliCtx.String("content-type"))
+	if err := contentType.Validate(); err != nil {
+		return err
+	}
+
+	restClis, err := request.NewClients(cliCtx.String("kubeconfig"),
+		conns,
+		request.WithClientUserAgentOpt(cliCtx.String("user-agent")),
+		request.WithClientContentTypeOpt(contentType),
+		request.WithClientDisableHTTP2Opt(cliCtx.Bool("disable-http2")),
+	)
+	if err != nil {
+		return err
+	}
+
+	f, err := createResultFile(cliCtx.String("result"))
+	if err != nil {
+		return err
+	}
+	if f != os.Stdout {
+		defer f.Close()
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	go watchInterruptSignals(ctx, cancel)
+
+	instrumentOpts, closeInstruments, err := instrumentationOptsFromFlags(cliCtx, tracePath)
+	if err != nil {
+		return err
+	}
+	defer closeInstruments()
+
+	result, err := replayFn(ctx, restClis, instrumentOpts...)
+	if err != nil {
+		return err
+	}
+	resolveFlowControlNames(cliCtx.String("kubeconfig"), []*request.Result{result})
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(buildRunnerMetricReport(result, cliCtx.Bool("raw-data"))); err != nil {
+		return fmt.Errorf("failed to encode json: %w", err)
+	}
+	return nil
+}
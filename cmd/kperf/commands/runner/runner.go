@@ -6,16 +6,24 @@ package runner
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"syscall"
 
 	"github.com/Azure/kperf/api/types"
 	"github.com/Azure/kperf/cmd/kperf/commands/utils"
 	"github.com/Azure/kperf/metrics"
+	"github.com/Azure/kperf/report"
 	"github.com/Azure/kperf/request"
+	runnergroup "github.com/Azure/kperf/runner"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 
@@ -29,13 +37,15 @@ var Command = cli.Command{
 	Usage: "Setup benchmark to kube-apiserver from one endpoint",
 	Subcommands: []cli.Command{
 		runCommand,
+		replayCommand,
+		replayTraceCommand,
 	},
 }
 
 var runCommand = cli.Command{
 	Name:  "run",
 	Usage: "run a benchmark test to kube-apiserver",
-	Flags: []cli.Flag{
+	Flags: append([]cli.Flag{
 		cli.StringFlag{
 			Name:  "kubeconfig",
 			Usage: "Path to the kubeconfig file",
@@ -58,7 +68,7 @@ var runCommand = cli.Command{
 		},
 		cli.StringFlag{
 			Name:  "content-type",
-			Usage: fmt.Sprintf("Content type (%v or %v)", types.ContentTypeJSON, types.ContentTypeProtobuffer),
+			Usage: fmt.Sprintf("Content type (%v, %v or %v)", types.ContentTypeJSON, types.ContentTypeProtobuffer, types.ContentTypeCBOR),
 			Value: string(types.ContentTypeJSON),
 		},
 		cli.Float64Flag{
@@ -83,23 +93,66 @@ var runCommand = cli.Command{
 			Usage: "Retry request after receiving 429 http code (<=0 means no retry)",
 			Value: 0,
 		},
+		cli.Float64Flag{
+			Name:  "timeout",
+			Usage: "Client-side timeout in seconds for each request (0 means default 60s)",
+			Value: 0,
+		},
 		cli.StringFlag{
 			Name:  "result",
 			Usage: "Path to the file which stores results",
 		},
+		utils.OutputFormatFlag,
 		cli.BoolFlag{
 			Name:  "raw-data",
-			Usage: "show raw letencies data in result",
+			Usage: "show raw letencies data in result. It takes memory in proportion to the number of requests",
 		},
 		cli.IntFlag{
 			Name:  "duration",
 			Usage: "Duration of the benchmark in seconds. It will be ignored if --total is set.",
 			Value: 0,
 		},
-	},
+		cli.StringSliceFlag{
+			Name:  "endpoint",
+			Usage: "Pin connections to the apiserver endpoint in round-robin (FORMAT: IP[:PORT]). It can be repeated.",
+		},
+		cli.BoolFlag{
+			Name:  "discover-endpoints",
+			Usage: "Pin connections to the apiserver endpoints found in the default/kubernetes EndpointSlice",
+		},
+		cli.StringFlag{
+			Name: "decode",
+			Usage: fmt.Sprintf("Decode responses into %v or %v objects instead of discarding them. It can override corresponding value defined by --config",
+				types.DecodeTargetUnstructured, types.DecodeTargetMetadata),
+		},
+		cli.Float64Flag{
+			Name:  "controller-qps",
+			Usage: "Emulate controllers built on client-go's defaults by throttling each client with the given QPS (0 means 5). It can override corresponding value defined by --config",
+		},
+		cli.IntFlag{
+			Name:  "controller-burst",
+			Usage: "Emulate controllers built on client-go's defaults by throttling each client with the given burst (0 means 10). It can override corresponding value defined by --config",
+		},
+		utils.PercentilesFlag,
+		traceFlag,
+		cli.StringFlag{
+			Name:   "abort-url",
+			Usage:  "Runner group server's URL to report and watch abort. Used by runner group only.",
+			Hidden: true,
+		},
+	}, append(instrumentationFlags, utils.ThroughputSearchFlags...)...),
 	Action: func(cliCtx *cli.Context) error {
 		kubeCfgPath := cliCtx.String("kubeconfig")
 
+		if err := utils.SetPercentilesFromFlag(cliCtx); err != nil {
+			return err
+		}
+
+		outputFormat, err := utils.OutputFormatFromFlag(cliCtx, false)
+		if err != nil {
+			return err
+		}
+
 		profileCfg, err := loadConfig(cliCtx)
 		if err != nil {
 			return err
@@ -110,49 +163,118 @@ var runCommand = cli.Command{
 			return fmt.Errorf("CLI flag overrides are not allowed when config has multiple specs")
 		}
 
+		searchSpec, err := utils.ThroughputSearchSpecFromFlags(cliCtx, false)
+		if err != nil {
+			return err
+		}
+		if searchSpec != nil && len(profileCfg.Specs) > 1 {
+			return fmt.Errorf("throughput search is not allowed when config has multiple specs")
+		}
+
 		// Use first spec for client configuration (all specs share same client pool)
 		firstSpec := profileCfg.Specs[0]
+		clientQPS := firstSpec.Rate
+		if searchSpec != nil {
+			clientQPS = searchSpec.MaxRate
+		}
 		clientNum := firstSpec.Conns
+
+		endpoints := firstSpec.Endpoints
+		if firstSpec.DiscoverEndpoints {
+			endpoints, err = request.DiscoverAPIServerEndpoints(context.Background(), kubeCfgPath)
+			if err != nil {
+				return fmt.Errorf("failed to discover apiserver endpoints: %w", err)
+			}
+			klog.V(2).InfoS("Discovered apiserver endpoints", "endpoints", endpoints)
+		}
+		if len(endpoints) > 0 && clientNum%len(endpoints) != 0 {
+			klog.Warningf("conns %d can't be spread evenly across %d endpoints", clientNum, len(endpoints))
+		}
+
+		connTracker := request.NewConnTracker()
 		restClis, err := request.NewClients(kubeCfgPath,
 			clientNum,
 			request.WithClientUserAgentOpt(cliCtx.String("user-agent")),
-			request.WithClientQPSOpt(firstSpec.Rate),
+			request.WithClientQPSOpt(clientQPS),
 			request.WithClientContentTypeOpt(firstSpec.ContentType),
 			request.WithClientDisableHTTP2Opt(firstSpec.DisableHTTP2),
+			request.WithClientHTTP2Opt(firstSpec.HTTP2),
+			request.WithClientConnTrackerOpt(connTracker),
+			request.WithClientEndpointsOpt(endpoints),
 		)
 		if err != nil {
 			return err
 		}
 
-		var f *os.File = os.Stdout
-		outputFilePath := cliCtx.String("result")
-		if outputFilePath != "" {
-			outputFileDir := filepath.Dir(outputFilePath)
+		f, err := createResultFile(cliCtx.String("result"))
+		if err != nil {
+			return err
+		}
+		if f != os.Stdout {
+			defer f.Close()
+		}
+
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
 
-			_, err = os.Stat(outputFileDir)
-			if err != nil && os.IsNotExist(err) {
-				err = os.MkdirAll(outputFileDir, 0750)
-			}
-			if err != nil {
-				return fmt.Errorf("failed to ensure output's dir %s: %w", outputFileDir, err)
-			}
+		if searchSpec != nil {
+			// Stop after the finished trials on SIGINT/SIGTERM.
+			go watchInterruptSignals(ctx, cancel)
 
-			f, err = os.Create(outputFilePath)
+			searchReport, err := request.SearchMaxThroughput(ctx, searchSpec,
+				request.NewLocalThroughputTrialFunc(&firstSpec, restClis, searchSpec.TrialDuration))
 			if err != nil {
 				return err
 			}
-			defer f.Close()
+			return printThroughputSearchReport(f, outputFormat, searchReport)
 		}
 
 		rawDataFlagIncluded := cliCtx.Bool("raw-data")
 
+		abortURL := cliCtx.String("abort-url")
+		if abortURL != "" {
+			go func() {
+				abort := runnergroup.WatchRunnerGroupAbort(ctx, abortURL, 5*time.Second)
+				if abort != nil {
+					cancel(&request.AbortError{
+						Reason: fmt.Sprintf("runner group aborted by %s: %s", abort.Runner, abort.Reason),
+					})
+				}
+			}()
+		}
+
+		// Stop gracefully on SIGINT/SIGTERM so that the requests finished
+		// so far are still reported.
+		go watchInterruptSignals(ctx, cancel)
+
+		instrumentOpts, closeInstruments, err := instrumentationOptsFromFlags(cliCtx, cliCtx.String("trace"))
+		if err != nil {
+			return err
+		}
+		defer closeInstruments()
+
+		scheduleOpts := append([]request.ScheduleOpt{
+			request.WithScheduleConnTrackerOpt(connTracker),
+			request.WithScheduleEndpointsOpt(endpoints),
+			request.WithScheduleRawSamplesOpt(rawDataFlagIncluded),
+		}, instrumentOpts...)
+
 		// Execute all specs (handles both single and multiple specs uniformly)
-		perSpecResults, aggregated, err := executeSpecs(context.TODO(), profileCfg.Specs, restClis)
+		perSpecResults, aggregated, err := executeSpecs(ctx, profileCfg.Specs, restClis, scheduleOpts...)
 		if err != nil {
 			return err
 		}
 
-		err = printMultiSpecResults(f, rawDataFlagIncluded, perSpecResults, aggregated)
+		resolveFlowControlNames(kubeCfgPath, append(perSpecResults, aggregated))
+
+		// Propagate the abort to other runners if it's fired by this runner.
+		if abortURL != "" && aggregated.AbortReason != "" && context.Cause(ctx) == nil {
+			if err := runnergroup.ReportRunnerGroupAbort(context.Background(), abortURL, aggregated.AbortReason); err != nil {
+				klog.ErrorS(err, "failed to report abort to runner group")
+			}
+		}
+
+		err = printMultiSpecResults(f, outputFormat, rawDataFlagIncluded, perSpecResults, aggregated)
 		if err != nil {
 			return fmt.Errorf("error while printing response stats: %w", err)
 		}
@@ -161,6 +283,85 @@ var runCommand = cli.Command{
 	},
 }
 
+// serveLiveMetrics serves live metrics on /metrics at addr in background.
+// The returned function stops the server.
+func serveLiveMetrics(addr string, live *metrics.LiveMetrics) (func(), error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s for metrics: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", live.Handler())
+
+	//nolint:gosec
+	srv := &http.Server{Handler: mux}
+	go func() {
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.ErrorS(err, "failed to serve metrics", "address", addr)
+		}
+	}()
+	klog.V(2).InfoS("Serving live metrics", "address", lis.Addr().String())
+	return func() { _ = srv.Close() }, nil
+}
+
+// resolveFlowControlNames re-keys flow control stats of results by names.
+// UIDs are kept if the names can't be listed.
+func resolveFlowControlNames(kubeCfgPath string, results []*request.Result) {
+	if !slices.ContainsFunc(results, (*request.Result).HasFlowControlStats) {
+		return
+	}
+
+	names, err := request.ListFlowControlNames(context.Background(), kubeCfgPath)
+	if err != nil {
+		klog.Warningf("Failed to resolve flow control names, report UIDs instead: %v", err)
+		return
+	}
+	for _, result := range results {
+		result.ResolveFlowControlNames(names)
+	}
+}
+
+// createResultFile creates the file to store results. It returns os.Stdout if
+// outputFilePath is empty.
+func createResultFile(outputFilePath string) (*os.File, error) {
+	if outputFilePath == "" {
+		return os.Stdout, nil
+	}
+
+	outputFileDir := filepath.Dir(outputFilePath)
+
+	_, err := os.Stat(outputFileDir)
+	if err != nil && os.IsNotExist(err) {
+		err = os.MkdirAll(outputFileDir, 0750)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure output's dir %s: %w", outputFileDir, err)
+	}
+	return os.Create(outputFilePath)
+}
+
+// watchInterruptSignals cancels the run with request.InterruptError after
+// receiving SIGINT or SIGTERM. The process exits immediately if it receives
+// the signal again before the run is drained.
+func watchInterruptSignals(ctx context.Context, cancel context.CancelCauseFunc) {
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	select {
+	case <-ctx.Done():
+		return
+	case sig := <-sigCh:
+		klog.Warningf("Received signal %s, draining in-flight requests before writing partial result", sig)
+		cancel(&request.InterruptError{Signal: sig})
+	}
+
+	sig := <-sigCh
+	klog.Errorf("Received signal %s again, exit immediately", sig)
+	os.Exit(1)
+}
+
 // loadConfig loads and validates the config.
 func loadConfig(cliCtx *cli.Context) (*types.LoadProfile, error) {
 	var profileCfg types.LoadProfile
@@ -209,6 +410,30 @@ func loadConfig(cliCtx *cli.Context) (*types.LoadProfile, error) {
 	if v := "max-retries"; cliCtx.IsSet(v) {
 		profileCfg.Specs[0].MaxRetries = cliCtx.Int(v)
 	}
+	if v := "timeout"; cliCtx.IsSet(v) {
+		profileCfg.Specs[0].Timeout = cliCtx.Float64(v)
+	}
+	if v := "endpoint"; cliCtx.IsSet(v) {
+		profileCfg.Specs[0].Endpoints = cliCtx.StringSlice(v)
+		profileCfg.Specs[0].DiscoverEndpoints = false
+	}
+	if v := "discover-endpoints"; cliCtx.IsSet(v) {
+		profileCfg.Specs[0].DiscoverEndpoints = cliCtx.Bool(v)
+	}
+	if v := "decode"; cliCtx.IsSet(v) {
+		profileCfg.Specs[0].Decode = &types.DecodeSpec{Target: types.DecodeTarget(cliCtx.String(v))}
+	}
+	if cliCtx.IsSet("controller-qps") || cliCtx.IsSet("controller-burst") {
+		if profileCfg.Specs[0].ControllerEmulation == nil {
+			profileCfg.Specs[0].ControllerEmulation = &types.ControllerEmulationSpec{}
+		}
+		if v := "controller-qps"; cliCtx.IsSet(v) {
+			profileCfg.Specs[0].ControllerEmulation.QPS = cliCtx.Float64(v)
+		}
+		if v := "controller-burst"; cliCtx.IsSet(v) {
+			profileCfg.Specs[0].ControllerEmulation.Burst = cliCtx.Int(v)
+		}
+	}
 
 	if err := profileCfg.Validate(); err != nil {
 		return nil, err
@@ -219,7 +444,8 @@ func loadConfig(cliCtx *cli.Context) (*types.LoadProfile, error) {
 // hasCliOverrides checks if any CLI override flags are set.
 func hasCliOverrides(cliCtx *cli.Context) bool {
 	overrideFlags := []string{"rate", "conns", "client", "total", "duration",
-		"content-type", "disable-http2", "max-retries"}
+		"content-type", "disable-http2", "max-retries", "timeout",
+		"endpoint", "discover-endpoints", "decode", "controller-qps", "controller-burst"}
 	for _, flag := range overrideFlags {
 		if cliCtx.IsSet(flag) {
 			return true
@@ -229,7 +455,7 @@ func hasCliOverrides(cliCtx *cli.Context) bool {
 }
 
 // executeSpecs runs all specs sequentially and returns per-spec + aggregated results.
-func executeSpecs(ctx context.Context, specs []types.LoadProfileSpec, restClis []rest.Interface) ([]*request.Result, *request.Result, error) {
+func executeSpecs(ctx context.Context, specs []types.LoadProfileSpec, restClis []rest.Interface, opts ...request.ScheduleOpt) ([]*request.Result, *request.Result, error) {
 	if len(specs) == 0 {
 		return nil, nil, fmt.Errorf("no specs to execute")
 	}
@@ -240,13 +466,22 @@ func executeSpecs(ctx context.Context, specs []types.LoadProfileSpec, restClis [
 	for i, spec := range specs {
 		klog.V(2).Infof("Executing spec %d/%d", i+1, len(specs))
 
-		result, err := request.Schedule(ctx, &spec, restClis)
+		result, err := request.Schedule(ctx, &spec, restClis, opts...)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to execute spec %d: %w", i+1, err)
 		}
 
 		results = append(results, result)
 		totalDuration += result.Duration
+
+		if result.AbortReason != "" {
+			klog.Warningf("Spec %d/%d has been aborted, skip the rest: %s", i+1, len(specs), result.AbortReason)
+			break
+		}
+		if result.Interrupted {
+			klog.Warningf("Spec %d/%d has been interrupted, skip the rest", i+1, len(specs))
+			break
+		}
 	}
 
 	aggregated := aggregateResults(results)
@@ -269,8 +504,10 @@ func aggregateResults(results []*request.Result) *request.Result {
 	for _, result := range results {
 		// Aggregate errors
 		aggregated.Errors = append(aggregated.Errors, result.Errors...)
+		aggregated.ErrorClasses = metrics.MergeErrorClasses(aggregated.ErrorClasses, result.ErrorClasses)
 
 		// Aggregate latencies by URL
+		aggregated.LatencyHistogramsByURL = metrics.MergeLatencyHistogramMaps(aggregated.LatencyHistogramsByURL, result.LatencyHistogramsByURL)
 		for url, latencies := range result.LatenciesByURL {
 			if _, exists := aggregated.LatenciesByURL[url]; !exists {
 				aggregated.LatenciesByURL[url] = make([]float64, 0)
@@ -278,21 +515,61 @@ func aggregateResults(results []*request.Result) *request.Result {
 			aggregated.LatenciesByURL[url] = append(aggregated.LatenciesByURL[url], latencies...)
 		}
 
+		aggregated.PhaseLatencyHistograms = metrics.MergeLatencyHistogramMaps(aggregated.PhaseLatencyHistograms, result.PhaseLatencyHistograms)
+		aggregated.LatencyHistogramsByOutcome = metrics.MergeLatencyHistogramMaps(aggregated.LatencyHistogramsByOutcome, result.LatencyHistogramsByOutcome)
+		aggregated.LatencyHistogramsByRetries = metrics.MergeLatencyHistogramMaps(aggregated.LatencyHistogramsByRetries, result.LatencyHistogramsByRetries)
+		for phase, latencies := range result.PhaseLatencies {
+			if aggregated.PhaseLatencies == nil {
+				aggregated.PhaseLatencies = map[types.RequestPhase][]float64{}
+			}
+			aggregated.PhaseLatencies[phase] = append(aggregated.PhaseLatencies[phase], latencies...)
+		}
+
 		// Sum bytes and requests
 		aggregated.TotalReceivedBytes += result.TotalReceivedBytes
 		aggregated.Total += result.Total
+		aggregated.CountsByURL = metrics.MergeRequestCounts(aggregated.CountsByURL, result.CountsByURL)
+		aggregated.LimiterWait = metrics.MergeLimiterWaitReports(aggregated.LimiterWait, result.LimiterWait)
+
+		if result.AbortReason != "" {
+			aggregated.AbortReason = result.AbortReason
+		}
+		aggregated.Interrupted = aggregated.Interrupted || result.Interrupted
+		aggregated.Connections = metrics.MergeConnectionReports(aggregated.Connections, result.Connections)
+		aggregated.DecodeByURL = metrics.MergeDecodeReports(aggregated.DecodeByURL, result.DecodeByURL)
+		aggregated.ByFlowSchema = metrics.MergeFlowControlReports(aggregated.ByFlowSchema, result.ByFlowSchema)
+		aggregated.ByPriorityLevel = metrics.MergeFlowControlReports(aggregated.ByPriorityLevel, result.ByPriorityLevel)
+		// All the specs share the same interval.
+		aggregated.TimeSeries, _ = metrics.MergeTimeSeries(aggregated.TimeSeries, result.TimeSeries)
+
+		for ep, epStats := range result.ByEndpoint {
+			if aggregated.ByEndpoint == nil {
+				aggregated.ByEndpoint = map[string]types.ResponseStats{}
+			}
+			merged := aggregated.ByEndpoint[ep]
+			merged.Errors = append(merged.Errors, epStats.Errors...)
+			merged.ErrorClasses = metrics.MergeErrorClasses(merged.ErrorClasses, epStats.ErrorClasses)
+			merged.LatencyHistogramsByURL = metrics.MergeLatencyHistogramMaps(merged.LatencyHistogramsByURL, epStats.LatencyHistogramsByURL)
+			for u, l := range epStats.LatenciesByURL {
+				if merged.LatenciesByURL == nil {
+					merged.LatenciesByURL = map[string][]float64{}
+				}
+				merged.LatenciesByURL[u] = append(merged.LatenciesByURL[u], l...)
+			}
+			aggregated.ByEndpoint[ep] = merged
+		}
 	}
 
 	return aggregated
 }
 
 // printMultiSpecResults prints results for multiple specs with aggregated summary.
-func printMultiSpecResults(f *os.File, rawDataFlagIncluded bool, perSpecResults []*request.Result, aggregated *request.Result) error {
+func printMultiSpecResults(f *os.File, format report.Format, rawDataFlagIncluded bool, perSpecResults []*request.Result, aggregated *request.Result) error {
 	// Build per-spec reports
 	perSpecReports := make([]types.RunnerMetricReport, 0, len(perSpecResults))
 	for _, result := range perSpecResults {
-		report := buildRunnerMetricReport(result, rawDataFlagIncluded)
-		perSpecReports = append(perSpecReports, report)
+		specReport := buildRunnerMetricReport(result, rawDataFlagIncluded)
+		perSpecReports = append(perSpecReports, specReport)
 	}
 
 	// Build aggregated report
@@ -304,6 +581,20 @@ func printMultiSpecResults(f *os.File, rawDataFlagIncluded bool, perSpecResults
 		Aggregated:     aggregatedReport,
 	}
 
+	if format != report.FormatJSON {
+		doc := &report.Document{Title: "kperf runner report"}
+		if len(perSpecReports) > 1 {
+			for idx := range perSpecReports {
+				doc.Sections = append(doc.Sections, report.Section{
+					Title:  fmt.Sprintf("Spec #%d", idx),
+					Report: &perSpecReports[idx],
+				})
+			}
+		}
+		doc.Sections = append(doc.Sections, report.Section{Title: "Aggregated", Report: &multiReport.Aggregated})
+		return report.Render(f, format, doc)
+	}
+
 	encoder := json.NewEncoder(f)
 	encoder.SetIndent("", "  ")
 
@@ -314,34 +605,155 @@ func printMultiSpecResults(f *os.File, rawDataFlagIncluded bool, perSpecResults
 	return nil
 }
 
+// printThroughputSearchReport prints throughput search report in format.
+func printThroughputSearchReport(f *os.File, format report.Format, searchReport *types.ThroughputSearchReport) error {
+	if format != report.FormatJSON {
+		return report.Render(f, format, &report.Document{
+			Title:  "kperf throughput search report",
+			Search: searchReport,
+		})
+	}
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+
+	err := encoder.Encode(searchReport)
+	if err != nil {
+		return fmt.Errorf("failed to encode json: %w", err)
+	}
+	return nil
+}
+
 // buildRunnerMetricReport builds a RunnerMetricReport from request.Result.
 func buildRunnerMetricReport(stats *request.Result, includeRawData bool) types.RunnerMetricReport {
 	output := types.RunnerMetricReport{
 		Total:                    stats.Total,
-		ErrorStats:               metrics.BuildErrorStatsGroupByType(stats.Errors),
+		ErrorStats:               metrics.BuildErrorStatsFromClasses(stats.ErrorClasses),
+		ErrorClasses:             stats.ErrorClasses,
 		Duration:                 stats.Duration.String(),
 		TotalReceivedBytes:       stats.TotalReceivedBytes,
 		PercentileLatenciesByURL: map[string][][2]float64{},
+		Aborted:                  stats.AbortReason != "",
+		AbortReason:              stats.AbortReason,
+		Interrupted:              stats.Interrupted,
+		LimiterWait:              stats.LimiterWait,
 	}
 
-	total := 0
-	for _, latencies := range stats.LatenciesByURL {
-		total += len(latencies)
-	}
-	latencies := make([]float64, 0, total)
-	for _, l := range stats.LatenciesByURL {
-		latencies = append(latencies, l...)
-	}
-	output.PercentileLatencies = metrics.BuildPercentileLatencies(latencies)
+	output.Dispatched, output.Succeeded, output.Failed = metrics.SumRequestCounts(stats.CountsByURL)
+	output.AchievedQPS = metrics.AchievedQPS(output.Succeeded, stats.Duration.Seconds())
+	output.CountsByURL = metrics.MergeRequestCounts(nil, stats.CountsByURL)
+	metrics.SetAchievedQPS(output.CountsByURL, stats.Duration.Seconds())
 
-	for u, l := range stats.LatenciesByURL {
-		output.PercentileLatenciesByURL[u] = metrics.BuildPercentileLatencies(l)
+	var all *types.LatencyHistogram
+	for u, h := range stats.LatencyHistogramsByURL {
+		all = metrics.MergeLatencyHistograms(all, h)
+		output.PercentileLatenciesByURL[u] = metrics.BuildPercentileLatenciesFromHistogram(h)
 	}
+	output.PercentileLatencies = metrics.BuildPercentileLatenciesFromHistogram(all)
+	output.LatencyHistogramsByURL = stats.LatencyHistogramsByURL
+	output.LatencyHistogramsByOutcome = stats.LatencyHistogramsByOutcome
+	output.PercentileLatenciesByOutcome = metrics.BuildPercentileLatenciesFromHistogramMap(stats.LatencyHistogramsByOutcome)
+	output.LatencyHistogramsByRetries = stats.LatencyHistogramsByRetries
+	output.PercentileLatenciesByRetries = metrics.BuildPercentileLatenciesFromHistogramMap(stats.LatencyHistogramsByRetries)
+
+	for phase, h := range stats.PhaseLatencyHistograms {
+		if output.PercentilePhaseLatencies == nil {
+			output.PercentilePhaseLatencies = map[types.RequestPhase][][2]float64{}
+		}
+		output.PercentilePhaseLatencies[phase] = metrics.BuildPercentileLatenciesFromHistogram(h)
+	}
+	output.PhaseLatencyHistograms = stats.PhaseLatencyHistograms
 
 	if includeRawData {
 		output.LatenciesByURL = stats.LatenciesByURL
 		output.Errors = stats.Errors
+		output.PhaseLatencies = stats.PhaseLatencies
+	}
+
+	for u, r := range stats.DecodeByURL {
+		if output.DecodeByURL == nil {
+			output.DecodeByURL = map[string]*types.DecodeReport{}
+		}
+		decode := *r
+		decode.PercentileLatencies = metrics.BuildPercentileLatenciesFromHistogram(r.Histogram)
+		if !includeRawData {
+			decode.Latencies = nil
+		}
+		output.DecodeByURL[u] = &decode
+	}
+
+	output.ByVerb, output.ByResource, output.ByScope = metrics.BuildAggregateReports(stats.LatencyHistogramsByURL, stats.ErrorClasses)
+	output.TimeSeries = stats.TimeSeries
+	output.ByFlowSchema = buildFlowControlReports(stats.ByFlowSchema, includeRawData)
+	output.ByPriorityLevel = buildFlowControlReports(stats.ByPriorityLevel, includeRawData)
+
+	for ep, epStats := range stats.ByEndpoint {
+		if output.Endpoints == nil {
+			output.Endpoints = map[string]*types.EndpointReport{}
+		}
+		output.Endpoints[ep] = buildEndpointReport(epStats, includeRawData)
+	}
+
+	if stats.Connections != nil {
+		conns := *stats.Connections
+		if !includeRawData {
+			conns.DialLatencies = nil
+		}
+		output.Connections = &conns
+	}
+
+	if stats.Warmup != nil {
+		warmup := buildRunnerMetricReport(stats.Warmup, includeRawData)
+		output.Warmup = &warmup
 	}
 
 	return output
 }
+
+// buildFlowControlReports copies reports with latency percentiles.
+func buildFlowControlReports(reports map[string]*types.FlowControlReport, includeRawData bool) map[string]*types.FlowControlReport {
+	if len(reports) == 0 {
+		return nil
+	}
+
+	res := make(map[string]*types.FlowControlReport, len(reports))
+	for key, r := range reports {
+		copied := *r
+		copied.PercentileLatencies = metrics.BuildPercentileLatenciesFromHistogram(r.Histogram)
+		if !includeRawData {
+			copied.Latencies = nil
+		}
+		res[key] = &copied
+	}
+	return res
+}
+
+// buildEndpointReport builds an EndpointReport from stats of one endpoint.
+func buildEndpointReport(stats types.ResponseStats, includeRawData bool) *types.EndpointReport {
+	var hist *types.LatencyHistogram
+	for _, h := range stats.LatencyHistogramsByURL {
+		hist = metrics.MergeLatencyHistograms(hist, h)
+	}
+
+	succeeded := 0
+	if hist != nil {
+		succeeded = int(hist.Count)
+	}
+	errStats := metrics.BuildErrorStatsFromClasses(stats.ErrorClasses)
+	failed := 0
+	for _, n := range errStats {
+		failed += int(n)
+	}
+	report := &types.EndpointReport{
+		Total:               succeeded + failed,
+		ErrorStats:          errStats,
+		Histogram:           hist,
+		PercentileLatencies: metrics.BuildPercentileLatenciesFromHistogram(hist),
+	}
+	if includeRawData {
+		for _, l := range stats.LatenciesByURL {
+			report.Latencies = append(report.Latencies, l...)
+		}
+	}
+	return report
+}
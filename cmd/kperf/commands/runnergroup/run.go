@@ -51,6 +51,11 @@ var runCommand = cli.Command{
 			Usage: "The verbosity level of runners",
 			Value: 2,
 		},
+		cli.BoolFlag{
+			Name:  "trace",
+			Usage: "Record every dispatched request in each runner (download by rg trace)",
+		},
+		utils.PercentilesFlag,
 	},
 	Action: func(cliCtx *cli.Context) error {
 		imgRef := cliCtx.String("runner-image")
@@ -77,6 +82,16 @@ var runCommand = cli.Command{
 		}
 
 		specs[0].NodeAffinity = affinityLabels
+		if cliCtx.Bool("trace") {
+			specs[0].Trace = true
+		}
+		percentiles, err := utils.PercentilesFromFlag(cliCtx)
+		if err != nil {
+			return err
+		}
+		if percentiles != nil {
+			specs[0].Percentiles = percentiles
+		}
 
 		kubeCfgPath := cliCtx.GlobalString("kubeconfig")
 		return runner.CreateRunnerGroupServer(context.Background(),
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/Azure/kperf/metrics"
 	"github.com/Azure/kperf/runner"
 	runnergroup "github.com/Azure/kperf/runner/group"
 
@@ -57,6 +58,10 @@ var serverCommand = cli.Command{
 			Usage: "The verbosity level of runners",
 			Value: 2,
 		},
+		cli.IntFlag{
+			Name:  "runner-metrics-port",
+			Usage: "The port on which runners serve live Prometheus metrics (0 disables)",
+		},
 	},
 	Hidden: true,
 	Action: func(cliCtx *cli.Context) error {
@@ -96,6 +101,7 @@ func buildRunnerGroupHandlers(cliCtx *cli.Context, serverName string) ([]*runner
 	imgRef := cliCtx.String("runner-image")
 	namespace := cliCtx.String("namespace")
 	runnerVerbosity := cliCtx.Int("runner-verbosity")
+	runnerMetricsPort := cliCtx.Int("runner-metrics-port")
 
 	ownerRef := ""
 	if cliCtx.IsSet("runner-owner") {
@@ -108,11 +114,13 @@ func buildRunnerGroupHandlers(cliCtx *cli.Context, serverName string) ([]*runner
 	}
 
 	groups := make([]*runnergroup.Handler, 0, len(specURIs))
+	var percentiles []float64
 	for idx, specURI := range specURIs {
 		spec, err := runnergroup.NewRunnerGroupSpecFromURI(clientset, specURI)
 		if err != nil {
 			return nil, err
 		}
+		percentiles = append(percentiles, spec.Percentiles...)
 
 		if ownerRef != "" {
 			spec.OwnerReference = &ownerRef
@@ -123,13 +131,20 @@ func buildRunnerGroupHandlers(cliCtx *cli.Context, serverName string) ([]*runner
 		}
 
 		groupName := fmt.Sprintf("%s-%d", serverName, idx)
-		g, err := runnergroup.NewHandler(clientset, namespace, groupName, spec, imgRef, runnerVerbosity)
+		g, err := runnergroup.NewHandler(clientset, namespace, groupName, spec, imgRef, runnerVerbosity, runnerMetricsPort)
 		if err != nil {
 			return nil, err
 		}
 		groups = append(groups, g)
 	}
 
+	// The summary merges all the runner groups so that it reports the
+	// percentiles required by any of them.
+	if len(percentiles) > 0 {
+		if err := metrics.SetPercentiles(percentiles); err != nil {
+			return nil, err
+		}
+	}
 	return groups, nil
 }
 
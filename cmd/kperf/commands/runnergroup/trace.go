@@ -0,0 +1,48 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runnergroup
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Azure/kperf/runner"
+
+	"github.com/urfave/cli"
+)
+
+var traceCommand = cli.Command{
+	Name:  "trace",
+	Usage: "download the trace file recorded by one runner",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:     "runner",
+			Usage:    "The name of runner (pod)",
+			Required: true,
+		},
+		cli.StringFlag{
+			Name:     "output",
+			Usage:    "Path to the file which stores the trace",
+			Required: true,
+		},
+	},
+	Action: func(cliCtx *cli.Context) error {
+		kubeCfgPath := cliCtx.GlobalString("kubeconfig")
+		outputPath := cliCtx.String("output")
+
+		f, err := os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", outputPath, err)
+		}
+		defer f.Close()
+
+		err = runner.GetRunnerGroupRunnerTrace(context.Background(), kubeCfgPath, cliCtx.String("runner"), f)
+		if err != nil {
+			_ = os.Remove(outputPath)
+			return err
+		}
+		return nil
+	},
+}
@@ -8,8 +8,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/Azure/kperf/metrics"
+	"github.com/Azure/kperf/report"
+
+	"github.com/urfave/cli"
+
 	flowcontrolv1 "k8s.io/api/flowcontrol/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -56,6 +62,57 @@ func KeyValueMap(strs []string) (map[string]string, error) {
 	return res, nil
 }
 
+// PercentilesFlag updates the quantiles reported in percentile latencies.
+var PercentilesFlag = cli.StringFlag{
+	Name:  "percentiles",
+	Usage: "Comma-separated quantiles in [0, 1] reported in percentile latencies, for instance, 0,0.5,0.9,0.99,0.999,0.9999,1. 0.5 and 0.99 are always reported",
+}
+
+// PercentilesFromFlag parses PercentilesFlag. It returns nil if the flag
+// isn't set.
+func PercentilesFromFlag(cliCtx *cli.Context) ([]float64, error) {
+	v := cliCtx.String(PercentilesFlag.Name)
+	if v == "" {
+		return nil, nil
+	}
+
+	strs := strings.Split(v, ",")
+	percentiles := make([]float64, 0, len(strs))
+	for _, str := range strs {
+		p, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse percentile %s: %w", str, err)
+		}
+		percentiles = append(percentiles, p)
+	}
+	return percentiles, nil
+}
+
+// SetPercentilesFromFlag applies PercentilesFlag if it's set.
+func SetPercentilesFromFlag(cliCtx *cli.Context) error {
+	percentiles, err := PercentilesFromFlag(cliCtx)
+	if err != nil || percentiles == nil {
+		return err
+	}
+	return metrics.SetPercentiles(percentiles)
+}
+
+// OutputFormatFlag selects the format of result.
+var OutputFormatFlag = cli.StringFlag{
+	Name:  "output-format",
+	Usage: fmt.Sprintf("Format of result, one of %v. Only json keeps every field", report.Formats),
+	Value: string(report.FormatJSON),
+}
+
+// OutputFormatFromFlag parses OutputFormatFlag. The global is true if the
+// flag is defined by parent command.
+func OutputFormatFromFlag(cliCtx *cli.Context, global bool) (report.Format, error) {
+	if global {
+		return report.ParseFormat(cliCtx.GlobalString(OutputFormatFlag.Name))
+	}
+	return report.ParseFormat(cliCtx.String(OutputFormatFlag.Name))
+}
+
 // inCluster is to check if current process is in pod.
 func inCluster() bool {
 	f, err := os.Stat("/var/run/secrets/kubernetes.io/serviceaccount/token")
@@ -0,0 +1,87 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package utils
+
+import (
+	"fmt"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/urfave/cli"
+)
+
+// ThroughputSearchFlags are options to enable throughput search.
+var ThroughputSearchFlags = []cli.Flag{
+	cli.StringFlag{
+		Name: "search-mode",
+		Usage: fmt.Sprintf("Search the maximum throughput under SLO (%v or %v). Empty means disabled",
+			types.ThroughputSearchModeStep, types.ThroughputSearchModeBinary),
+	},
+	cli.Float64Flag{
+		Name:  "search-min-rate",
+		Usage: "The lowest offered QPS to try in throughput search",
+		Value: 10,
+	},
+	cli.Float64Flag{
+		Name:  "search-max-rate",
+		Usage: "The highest offered QPS to try in throughput search",
+		Value: 1000,
+	},
+	cli.Float64Flag{
+		Name:  "search-step",
+		Usage: "The QPS increment between trials (step mode only)",
+		Value: 50,
+	},
+	cli.Float64Flag{
+		Name:  "search-precision",
+		Usage: "Stop when the QPS range is narrower than this value (binary mode only)",
+		Value: 10,
+	},
+	cli.IntFlag{
+		Name:  "search-trial-duration",
+		Usage: "Duration of each trial in seconds",
+		Value: 30,
+	},
+	cli.Float64Flag{
+		Name:  "slo-p99-latency",
+		Usage: "The p99 latency threshold in seconds for throughput search (zero means no limit)",
+		Value: 1,
+	},
+	cli.Float64Flag{
+		Name:  "slo-error-rate",
+		Usage: "The error rate threshold in percentage for throughput search",
+		Value: 1,
+	},
+}
+
+// ThroughputSearchSpecFromFlags returns ThroughputSearchSpec if --search-mode
+// is set. Otherwise, it returns nil.
+//
+// The global should be true if flags are defined by parent command.
+func ThroughputSearchSpecFromFlags(cliCtx *cli.Context, global bool) (*types.ThroughputSearchSpec, error) {
+	stringFn, float64Fn, intFn := cliCtx.String, cliCtx.Float64, cliCtx.Int
+	if global {
+		stringFn, float64Fn, intFn = cliCtx.GlobalString, cliCtx.GlobalFloat64, cliCtx.GlobalInt
+	}
+
+	mode := stringFn("search-mode")
+	if mode == "" {
+		return nil, nil
+	}
+
+	spec := &types.ThroughputSearchSpec{
+		Mode:          types.ThroughputSearchMode(mode),
+		MinRate:       float64Fn("search-min-rate"),
+		MaxRate:       float64Fn("search-max-rate"),
+		Step:          float64Fn("search-step"),
+		Precision:     float64Fn("search-precision"),
+		TrialDuration: intFn("search-trial-duration"),
+		MaxP99Latency: float64Fn("slo-p99-latency"),
+		MaxErrorRate:  float64Fn("slo-error-rate"),
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid throughput search flags: %w", err)
+	}
+	return spec, nil
+}
@@ -90,21 +90,16 @@ func ciliumCustomResourceListRun(cliCtx *cli.Context) (*internaltypes.BenchmarkR
 		return nil, fmt.Errorf("failed to load Cilium data: %w", err)
 	}
 
-	rgResult, err := utils.DeployRunnerGroup(ctx,
-		cliCtx.GlobalString("kubeconfig"),
-		cliCtx.GlobalString("runner-image"),
-		rgCfgFile,
-		cliCtx.GlobalString("runner-flowcontrol"),
-		cliCtx.GlobalString("rg-affinity"),
-	)
+	rgResult, searchResult, err := deployRunnerGroup(ctx, cliCtx, rgCfgFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to deploy runner group: %w", err)
 	}
 
 	return &internaltypes.BenchmarkReport{
-		Description: fmt.Sprintf(`Deploy %d CiliumIdentities and %d CiliumEndpoints, then run stale list requests against them`, numCID, numCEP),
-		LoadSpec:    *rgSpec,
-		Result:      *rgResult,
+		Description:      fmt.Sprintf(`Deploy %d CiliumIdentities and %d CiliumEndpoints, then run stale list requests against them`, numCID, numCEP),
+		LoadSpec:         *rgSpec,
+		Result:           *rgResult,
+		ThroughputSearch: searchResult,
 		Info: map[string]interface{}{
 			"numCiliumIdentities": numCID,
 			"numCiliumEndpoints":  numCEP,
@@ -106,13 +106,7 @@ func benchListConfigmapsRun(cliCtx *cli.Context) (*internaltypes.BenchmarkReport
 			LogKV("msg", fmt.Sprintf("Running for %v seconds", duration.Seconds()))
 	}
 
-	rgResult, derr := utils.DeployRunnerGroup(ctx,
-		cliCtx.GlobalString("kubeconfig"),
-		cliCtx.GlobalString("runner-image"),
-		rgCfgFile,
-		cliCtx.GlobalString("runner-flowcontrol"),
-		cliCtx.GlobalString("rg-affinity"),
-	)
+	rgResult, searchResult, derr := deployRunnerGroup(ctx, cliCtx, rgCfgFile)
 
 	if derr != nil {
 		return nil, derr
@@ -124,8 +118,9 @@ Environment: Generate %v configmaps with %v bytes each in a namespace.
 Workload: List all configmaps in the namespace and get the percentile latency.`,
 			cmAmount, cmSize),
 
-		LoadSpec: *rgSpec,
-		Result:   *rgResult,
+		LoadSpec:         *rgSpec,
+		Result:           *rgResult,
+		ThroughputSearch: searchResult,
 		Info: map[string]interface{}{
 			"configmapSizeInBytes": cmSize,
 			"runningTime":          duration.String(),
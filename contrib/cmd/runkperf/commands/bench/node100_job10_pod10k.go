@@ -101,13 +101,7 @@ func benchNode100Job10Pod10kCaseRun(cliCtx *cli.Context) (*internaltypes.Benchma
 	defer jobsCleanup()
 
 	// Deploy runner group to measure read-only performance
-	rgResult, err := utils.DeployRunnerGroup(ctx,
-		cliCtx.GlobalString("kubeconfig"),
-		cliCtx.GlobalString("runner-image"),
-		rgCfgFile,
-		cliCtx.GlobalString("runner-flowcontrol"),
-		cliCtx.GlobalString("rg-affinity"),
-	)
+	rgResult, searchResult, err := deployRunnerGroup(ctx, cliCtx, rgCfgFile)
 	if err != nil {
 		return nil, err
 	}
@@ -118,8 +112,9 @@ func benchNode100Job10Pod10kCaseRun(cliCtx *cli.Context) (*internaltypes.Benchma
 		Workload: Deploy %d jobs with %d pods each (total %d pods) with parallelism %d.
 		Measures read-only performance against stable workload.`,
 			nodeCount, jobCount, podsPerJob, totalPods, parallelism),
-		LoadSpec: *rgSpec,
-		Result:   *rgResult,
-		Info:     make(map[string]interface{}),
+		LoadSpec:         *rgSpec,
+		Result:           *rgResult,
+		ThroughputSearch: searchResult,
+		Info:             make(map[string]interface{}),
 	}, nil
 }
@@ -132,13 +132,7 @@ func benchNode100DeploymentNPod10KRun(cliCtx *cli.Context) (*internaltypes.Bench
 		utils.RollingUpdateDeployments(dpCtx, total, deploymentNamePattern, kubeCfgPath, utils.WithRollingUpdateIntervalTimeoutOpt(restartInterval))
 	}()
 
-	rgResult, derr := utils.DeployRunnerGroup(ctx,
-		cliCtx.GlobalString("kubeconfig"),
-		cliCtx.GlobalString("runner-image"),
-		rgCfgFile,
-		cliCtx.GlobalString("runner-flowcontrol"),
-		cliCtx.GlobalString("rg-affinity"),
-	)
+	rgResult, searchResult, derr := deployRunnerGroup(ctx, cliCtx, rgCfgFile)
 	dpCancel()
 	wg.Wait()
 
@@ -152,8 +146,9 @@ Environment: 100 virtual nodes managed by kwok-controller,
 Workload: Deploy %d deployments with %d pods. Rolling-update deployments one by one and the interval is %v`,
 			total, total*replica, restartInterval),
 
-		LoadSpec: *rgSpec,
-		Result:   *rgResult,
+		LoadSpec:         *rgSpec,
+		Result:           *rgResult,
+		ThroughputSearch: searchResult,
 		Info: map[string]interface{}{
 			"podSizeInBytes": podSize,
 			"interval":       restartInterval.String(),
@@ -75,13 +75,7 @@ func benchNode10Job1Pod100CaseRun(cliCtx *cli.Context) (*internaltypes.Benchmark
 			utils.WithJobIntervalOpt(jobInterval))
 	}()
 
-	rgResult, derr := utils.DeployRunnerGroup(ctx,
-		cliCtx.GlobalString("kubeconfig"),
-		cliCtx.GlobalString("runner-image"),
-		rgCfgFile,
-		cliCtx.GlobalString("runner-flowcontrol"),
-		cliCtx.GlobalString("rg-affinity"),
-	)
+	rgResult, searchResult, derr := deployRunnerGroup(ctx, cliCtx, rgCfgFile)
 	jobCancel()
 	wg.Wait()
 
@@ -93,8 +87,9 @@ func benchNode10Job1Pod100CaseRun(cliCtx *cli.Context) (*internaltypes.Benchmark
 		Description: fmt.Sprintf(`
 Environment: 100 virtual nodes managed by kwok-controller,
 Workload: Deploy 1 job with 3,000 pods repeatedly. The parallelism is 100. The interval is %v`, jobInterval),
-		LoadSpec: *rgSpec,
-		Result:   *rgResult,
-		Info:     make(map[string]interface{}),
+		LoadSpec:         *rgSpec,
+		Result:           *rgResult,
+		ThroughputSearch: searchResult,
+		Info:             make(map[string]interface{}),
 	}, nil
 }
@@ -77,13 +77,7 @@ func benchNode10Job1Pod1kCaseRun(cliCtx *cli.Context) (*internaltypes.BenchmarkR
 
 	}()
 
-	rgResult, derr := utils.DeployRunnerGroup(ctx,
-		cliCtx.GlobalString("kubeconfig"),
-		cliCtx.GlobalString("runner-image"),
-		rgCfgFile,
-		cliCtx.GlobalString("runner-flowcontrol"),
-		cliCtx.GlobalString("rg-affinity"),
-	)
+	rgResult, searchResult, derr := deployRunnerGroup(ctx, cliCtx, rgCfgFile)
 	jobCancel()
 	wg.Wait()
 
@@ -95,8 +89,9 @@ func benchNode10Job1Pod1kCaseRun(cliCtx *cli.Context) (*internaltypes.BenchmarkR
 		Description: fmt.Sprintf(`
 		Environment: 10 virtual nodes managed by kwok-controller,
 		Workload: Deploy 1 job with 1,000 pods repeatedly. The parallelism is 100. The interval is %v`, jobInterval),
-		LoadSpec: *rgSpec,
-		Result:   *rgResult,
-		Info:     make(map[string]interface{}),
+		LoadSpec:         *rgSpec,
+		Result:           *rgResult,
+		ThroughputSearch: searchResult,
+		Info:             make(map[string]interface{}),
 	}, nil
 }
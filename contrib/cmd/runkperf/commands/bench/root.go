@@ -13,7 +13,7 @@ import (
 var Command = cli.Command{
 	Name:  "bench",
 	Usage: "Run benchmark test cases",
-	Flags: []cli.Flag{
+	Flags: append([]cli.Flag{
 		cli.StringFlag{
 			Name:  "kubeconfig",
 			Usage: "Path to the kubeconfig file",
@@ -53,7 +53,8 @@ var Command = cli.Command{
 			Name:  "result",
 			Usage: "Path to the file which stores results",
 		},
-	},
+		kperfcmdutils.OutputFormatFlag,
+	}, kperfcmdutils.ThroughputSearchFlags...),
 	Subcommands: []cli.Command{
 		benchNode10Job1Pod100Case,
 		benchNode100Job1Pod3KCase,
@@ -91,7 +92,7 @@ var commonFlags = []cli.Flag{
 	},
 	cli.StringFlag{
 		Name:  "content-type",
-		Usage: "Content type (json or protobuf)",
+		Usage: "Content type (json, protobuf or cbor)",
 		Value: "json",
 	},
 }
@@ -7,7 +7,6 @@ import (
 	"context"
 
 	internaltypes "github.com/Azure/kperf/contrib/internal/types"
-	"github.com/Azure/kperf/contrib/utils"
 
 	"github.com/urfave/cli"
 )
@@ -50,22 +49,17 @@ func benchTimeseriesReplayRun(cliCtx *cli.Context) (*internaltypes.BenchmarkRepo
 	defer func() { _ = rgCfgFileDone() }()
 
 	// Deploy the runner group
-	rgResult, derr := utils.DeployRunnerGroup(ctx,
-		cliCtx.GlobalString("kubeconfig"),
-		cliCtx.GlobalString("runner-image"),
-		rgCfgFile,
-		cliCtx.GlobalString("runner-flowcontrol"),
-		cliCtx.GlobalString("rg-affinity"),
-	)
+	rgResult, searchResult, derr := deployRunnerGroup(ctx, cliCtx, rgCfgFile)
 
 	if derr != nil {
 		return nil, derr
 	}
 
 	return &internaltypes.BenchmarkReport{
-		Description: "Time-series replay: Baseline (10 QPS, 30s) → Spike (100 QPS, 20s) → Recovery (25 QPS, 30s)",
-		LoadSpec:    *rgSpec,
-		Result:      *rgResult,
-		Info:        map[string]interface{}{},
+		Description:      "Time-series replay: Baseline (10 QPS, 30s) → Spike (100 QPS, 20s) → Recovery (25 QPS, 30s)",
+		LoadSpec:         *rgSpec,
+		Result:           *rgResult,
+		ThroughputSearch: searchResult,
+		Info:             map[string]interface{}{},
 	}, nil
 }
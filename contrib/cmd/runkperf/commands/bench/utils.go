@@ -9,12 +9,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/Azure/kperf/api/types"
 	kperfcmdutils "github.com/Azure/kperf/cmd/kperf/commands/utils"
 	internaltypes "github.com/Azure/kperf/contrib/internal/types"
 	"github.com/Azure/kperf/contrib/log"
 	"github.com/Azure/kperf/contrib/utils"
+	"github.com/Azure/kperf/report"
+	"github.com/Azure/kperf/request"
 
 	"github.com/urfave/cli"
 	"gopkg.in/yaml.v2"
@@ -62,7 +65,12 @@ func addAPIServerCoresInfoInterceptor(handler subcmdActionFunc) subcmdActionFunc
 // renderBenchmarkReportInterceptor renders benchmark report into file or stdout.
 func renderBenchmarkReportInterceptor(handler subcmdActionFunc) subcmdActionFunc {
 	return func(cliCtx *cli.Context) (*internaltypes.BenchmarkReport, error) {
-		report, err := handler(cliCtx)
+		format, err := kperfcmdutils.OutputFormatFromFlag(cliCtx, true)
+		if err != nil {
+			return nil, err
+		}
+
+		benchReport, err := handler(cliCtx)
 		if err != nil {
 			return nil, err
 		}
@@ -86,13 +94,23 @@ func renderBenchmarkReportInterceptor(handler subcmdActionFunc) subcmdActionFunc
 			defer outF.Close()
 		}
 
+		if format != report.FormatJSON {
+			err = report.Render(outF, format, &report.Document{
+				Title:    benchReport.Description,
+				Info:     benchReport.Info,
+				Sections: []report.Section{{Title: "Result", Report: &benchReport.Result}},
+				Search:   benchReport.ThroughputSearch,
+			})
+			return benchReport, err
+		}
+
 		encoder := json.NewEncoder(outF)
 		encoder.SetIndent("", "  ")
 
-		if err := encoder.Encode(report); err != nil {
+		if err := encoder.Encode(benchReport); err != nil {
 			return nil, fmt.Errorf("failed to encode json: %w", err)
 		}
-		return report, nil
+		return benchReport, nil
 	}
 }
 
@@ -139,6 +157,100 @@ func deployVirtualNodepool(ctx context.Context, cliCtx *cli.Context, target stri
 
 func NewRunnerGroupSpecFromYamlFile() {}
 
+// deployRunnerGroup deploys runner group and waits for its report.
+//
+// If throughput search is enabled by --search-mode, it deploys runner group
+// for each trial. The offered QPS is split evenly across runners. It returns
+// the report of the fastest passed trial, or the last trial if none passed,
+// together with the search report.
+func deployRunnerGroup(ctx context.Context, cliCtx *cli.Context, rgCfgFile string) (*types.RunnerGroupsReport, *types.ThroughputSearchReport, error) {
+	kubeCfgPath := cliCtx.GlobalString("kubeconfig")
+	runnerImage := cliCtx.GlobalString("runner-image")
+	runnerFlowControl := cliCtx.GlobalString("runner-flowcontrol")
+	rgAffinity := cliCtx.GlobalString("rg-affinity")
+
+	searchSpec, err := kperfcmdutils.ThroughputSearchSpecFromFlags(cliCtx, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if searchSpec == nil {
+		rgResult, err := utils.DeployRunnerGroup(ctx, kubeCfgPath, runnerImage, rgCfgFile, runnerFlowControl, rgAffinity)
+		return rgResult, nil, err
+	}
+
+	rgCfgInRaw, err := os.ReadFile(rgCfgFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", rgCfgFile, err)
+	}
+
+	// rgResults stores runner group's report for each trial in order.
+	rgResults := []*types.RunnerGroupsReport{}
+	trialFn := func(ctx context.Context, rate float64) (*types.ThroughputSearchTrial, error) {
+		trialCfgFile, trialCfgFileDone, err := newThroughputTrialRunnerGroupSpecFile(rgCfgInRaw, rate, searchSpec.TrialDuration)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = trialCfgFileDone() }()
+
+		rgResult, err := utils.DeployRunnerGroup(ctx, kubeCfgPath, runnerImage, trialCfgFile, runnerFlowControl, rgAffinity)
+		if err != nil {
+			return nil, err
+		}
+		rgResults = append(rgResults, rgResult)
+
+		dur, err := time.ParseDuration(rgResult.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse duration %s: %w", rgResult.Duration, err)
+		}
+		return request.NewThroughputSearchTrial(rgResult.Succeeded, rgResult.Failed, dur, rgResult.PercentileLatencies), nil
+	}
+
+	searchReport, err := request.SearchMaxThroughput(ctx, searchSpec, trialFn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rgResults) == 0 {
+		return nil, nil, fmt.Errorf("throughput search stopped before any trial")
+	}
+
+	rgResult := rgResults[len(rgResults)-1]
+	for idx, trial := range searchReport.Trials {
+		if trial.Passed && trial.OfferedQPS == searchReport.MaxQPS {
+			rgResult = rgResults[idx]
+		}
+	}
+	return rgResult, searchReport, nil
+}
+
+// newThroughputTrialRunnerGroupSpecFile creates a temporary runner group spec
+// file whose first load profile spec runs rate QPS in total for duration seconds.
+func newThroughputTrialRunnerGroupSpecFile(rgCfgInRaw []byte, rate float64, duration int) (_name string, _cleanup func() error, _ error) {
+	spec, err := utils.NewRunnerGroupSpecFromYAML(rgCfgInRaw, func(spec *types.RunnerGroupSpec) error {
+		if spec.Profile == nil || len(spec.Profile.Specs) != 1 {
+			return fmt.Errorf("throughput search requires exactly one load profile spec")
+		}
+
+		count := spec.Count
+		if count <= 0 {
+			count = 1
+		}
+		spec.Profile.Specs[0].Rate = rate / float64(count)
+		spec.Profile.Specs[0].Total = 0
+		spec.Profile.Specs[0].Duration = duration
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	data, err := yaml.Marshal(spec)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal RunnerGroupSpec for trial: %w", err)
+	}
+	return utils.CreateTempFileWithContent(data)
+}
+
 // newLoadProfileFromEmbed loads load profile from embed and tweaks that load
 // profile.
 func newLoadProfileFromEmbed(cliCtx *cli.Context, name string) (_name string, _spec *types.RunnerGroupSpec, _cleanup func() error, _err error) {
@@ -13,6 +13,8 @@ type BenchmarkReport struct {
 	LoadSpec apitypes.RunnerGroupSpec `json:"loadSpec" yaml:"loadSpec"`
 	// Result represents runner group's report.
 	Result apitypes.RunnerGroupsReport `json:"result" yaml:"result"`
+	// ThroughputSearch represents the throughput search's report if enabled.
+	ThroughputSearch *apitypes.ThroughputSearchReport `json:"throughputSearch,omitempty" yaml:"throughputSearch,omitempty"`
 	// Info is additional information.
 	//
 	// FIXME(weifu): Use struct after finialized.
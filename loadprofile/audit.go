@@ -0,0 +1,103 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package loadprofile
+
+import (
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/request"
+
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+)
+
+// ObserveAuditLog reads audit events (audit.k8s.io/v1 Event in JSON lines)
+// and returns the observations with the window between the first and the
+// last request.
+func ObserveAuditLog(r io.Reader) (*Observations, time.Duration, error) {
+	o := NewObservations()
+
+	var first, last time.Time
+	err := request.ReadAuditEvents(r, func(ev *auditv1.Event) {
+		at := ev.RequestReceivedTimestamp.Time
+		if first.IsZero() || at.Before(first) {
+			first = at
+		}
+		if at.After(last) {
+			last = at
+		}
+
+		o.observeAuditEvent(ev)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return o, last.Sub(first), nil
+}
+
+// observeAuditEvent maps audit event to the request kind.
+func (o *Observations) observeAuditEvent(ev *auditv1.Event) {
+	ref := ev.ObjectRef
+	if ref == nil {
+		o.addUnsupported(ev.Verb, ev.RequestURI, 1)
+		return
+	}
+
+	resource := ref.Resource
+	if ref.Subresource != "" {
+		resource = resource + "/" + ref.Subresource
+	}
+
+	u, err := url.ParseRequestURI(ev.RequestURI)
+	if err != nil {
+		o.addUnsupported(ev.Verb, resource, 1)
+		return
+	}
+	query := u.Query()
+
+	key := observationKey{
+		gvr: types.KubeGroupVersionResource{
+			Group:    ref.APIGroup,
+			Version:  ref.APIVersion,
+			Resource: ref.Resource,
+		},
+		namespace: ref.Namespace,
+	}
+
+	switch {
+	case ev.Verb == "list" && ref.Subresource == "":
+		key.kind = requestKindQuorumList
+		if query.Get("resourceVersion") == "0" {
+			// stale list doesn't support pagination.
+			key.kind = requestKindStaleList
+		} else if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 {
+			key.limit = limit
+		}
+		o.add(key, "", 1)
+	case ev.Verb == "watch" && ref.Subresource == "" && query.Get("sendInitialEvents") == "true":
+		key.kind = requestKindWatchList
+		o.add(key, "", 1)
+	case ev.Verb == "get" && ref.Subresource == "" && ref.Name != "":
+		key.kind = requestKindQuorumGet
+		if query.Get("resourceVersion") == "0" {
+			key.kind = requestKindStaleGet
+		}
+		o.add(key, ref.Name, 1)
+	case ev.Verb == "get" && resource == "pods/log" && ref.Name != "":
+		key.kind = requestKindGetPodLog
+		key.gvr.Resource = resource
+		o.add(key, ref.Name, 1)
+	case (ev.Verb == "create" || ev.Verb == "delete") && resource == "pods":
+		key.kind = requestKindPostDel
+		ob := o.add(key, "", 1)
+		if ev.Verb == "delete" {
+			ob.deletes++
+		}
+	default:
+		o.addUnsupported(ev.Verb, resource, 1)
+	}
+}
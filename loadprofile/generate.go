@@ -0,0 +1,231 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+// Package loadprofile synthesizes LoadProfile from observed traffic.
+package loadprofile
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+// requestKind is the kind of WeightedRequest.
+type requestKind string
+
+const (
+	requestKindStaleList  requestKind = "staleList"
+	requestKindQuorumList requestKind = "quorumList"
+	requestKindWatchList  requestKind = "watchList"
+	requestKindStaleGet   requestKind = "staleGet"
+	requestKindQuorumGet  requestKind = "quorumGet"
+	requestKindGetPodLog  requestKind = "getPodLog"
+	requestKindPostDel    requestKind = "postDel"
+)
+
+// totalShares is the sum of shares in generated profile.
+const totalShares = 1000
+
+// observationKey identifies one WeightedRequest in generated profile.
+type observationKey struct {
+	kind      requestKind
+	gvr       types.KubeGroupVersionResource
+	namespace string
+	limit     int
+}
+
+// observation is the traffic of one WeightedRequest.
+type observation struct {
+	observationKey
+
+	count float64
+	// deletes is the number of DELETE requests for postDel kind.
+	deletes float64
+	// names records how many times each object name was requested.
+	names map[string]float64
+}
+
+// Observations accumulates observed requests.
+type Observations struct {
+	obs map[observationKey]*observation
+	// unsupported records requests which can't be mapped to any
+	// WeightedRequest, in the format of "verb resource".
+	unsupported map[string]float64
+}
+
+// NewObservations returns new instance of Observations.
+func NewObservations() *Observations {
+	return &Observations{
+		obs:         map[observationKey]*observation{},
+		unsupported: map[string]float64{},
+	}
+}
+
+// add records count requests of given kind.
+func (o *Observations) add(key observationKey, name string, count float64) *observation {
+	ob, ok := o.obs[key]
+	if !ok {
+		ob = &observation{observationKey: key, names: map[string]float64{}}
+		o.obs[key] = ob
+	}
+	ob.count += count
+	if name != "" {
+		ob.names[name] += count
+	}
+	return ob
+}
+
+// addUnsupported records count requests which can't be generated.
+func (o *Observations) addUnsupported(verb, resource string, count float64) {
+	o.unsupported[strings.TrimSpace(verb+" "+resource)] += count
+}
+
+// Unsupported returns the number of requests which can't be generated,
+// group by "verb resource".
+func (o *Observations) Unsupported() map[string]float64 {
+	return o.unsupported
+}
+
+// GenerateOptions defines the settings of generated profile which can't be
+// inferred from traffic.
+type GenerateOptions struct {
+	// Duration is the running time in seconds of generated profile.
+	Duration int
+	// Conns is the total number of connections.
+	Conns int
+	// Client is the total number of HTTP clients.
+	Client int
+	// ContentType is the response's content type.
+	ContentType types.ContentType
+}
+
+// Generate returns LoadProfile whose request mix matches observations and
+// whose rate is the observed QPS over the given window.
+func (o *Observations) Generate(window time.Duration, opts GenerateOptions) (*types.LoadProfile, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("observation window requires > 0: %v", window)
+	}
+
+	obs := make([]*observation, 0, len(o.obs))
+	total := float64(0)
+	for _, ob := range o.obs {
+		if ob.count <= 0 {
+			continue
+		}
+		obs = append(obs, ob)
+		total += ob.count
+	}
+	if len(obs) == 0 {
+		return nil, fmt.Errorf("no supported requests observed")
+	}
+
+	// Sort by count so that the heaviest request goes first.
+	sort.Slice(obs, func(i, j int) bool {
+		if obs[i].count != obs[j].count {
+			return obs[i].count > obs[j].count
+		}
+		return obs[i].sortKey() < obs[j].sortKey()
+	})
+
+	reqs := make([]*types.WeightedRequest, 0, len(obs))
+	for _, ob := range obs {
+		req := ob.weightedRequest()
+		req.Shares = int(math.Round(ob.count * totalShares / total))
+		if req.Shares == 0 {
+			req.Shares = 1
+		}
+		reqs = append(reqs, req)
+	}
+
+	qps := total / window.Seconds()
+	profile := &types.LoadProfile{
+		Version: 1,
+		Description: fmt.Sprintf("generated from %.0f requests over %s (%.2f QPS)",
+			total, window.Round(time.Second), qps),
+		Specs: []types.LoadProfileSpec{
+			{
+				// Keep two decimals.
+				Rate:        math.Round(qps*100) / 100,
+				Duration:    opts.Duration,
+				Conns:       opts.Conns,
+				Client:      opts.Client,
+				ContentType: opts.ContentType,
+				Requests:    reqs,
+			},
+		},
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, fmt.Errorf("generated invalid profile: %w", err)
+	}
+	return profile, nil
+}
+
+// sortKey is used to sort observations with the same count.
+func (ob *observation) sortKey() string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s", ob.kind, ob.gvr.Group, ob.gvr.Version, ob.gvr.Resource, ob.namespace)
+}
+
+// mostRequestedName returns the most requested object name.
+func (ob *observation) mostRequestedName() string {
+	name, count := "", float64(0)
+	for n, c := range ob.names {
+		if c > count || (c == count && n < name) {
+			name, count = n, c
+		}
+	}
+	return name
+}
+
+// weightedRequest converts observation into WeightedRequest without shares.
+func (ob *observation) weightedRequest() *types.WeightedRequest {
+	req := &types.WeightedRequest{}
+
+	switch ob.kind {
+	case requestKindStaleList, requestKindQuorumList:
+		list := &types.RequestList{
+			KubeGroupVersionResource: ob.gvr,
+			Namespace:                ob.namespace,
+			Limit:                    ob.limit,
+		}
+		if ob.kind == requestKindStaleList {
+			req.StaleList = list
+		} else {
+			req.QuorumList = list
+		}
+	case requestKindWatchList:
+		req.WatchList = &types.RequestWatchList{
+			KubeGroupVersionResource: ob.gvr,
+			Namespace:                ob.namespace,
+		}
+	case requestKindStaleGet, requestKindQuorumGet:
+		get := &types.RequestGet{
+			KubeGroupVersionResource: ob.gvr,
+			Namespace:                ob.namespace,
+			Name:                     ob.mostRequestedName(),
+		}
+		if ob.kind == requestKindStaleGet {
+			req.StaleGet = get
+		} else {
+			req.QuorumGet = get
+		}
+	case requestKindGetPodLog:
+		req.GetPodLog = &types.RequestGetPodLog{
+			Namespace: ob.namespace,
+			Name:      ob.mostRequestedName(),
+		}
+	case requestKindPostDel:
+		req.PostDel = &types.RequestPostDel{
+			KubeGroupVersionResource: ob.gvr,
+			Namespace:                ob.namespace,
+			// PostDel requires create proportion greater than delete.
+			DeleteRatio: math.Min(math.Round(ob.deletes/ob.count*100)/100, 0.5),
+		}
+	default:
+		panic(fmt.Sprintf("unknown request kind %s", ob.kind))
+	}
+	return req
+}
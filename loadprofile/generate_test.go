@@ -0,0 +1,108 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package loadprofile
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testGenerateOpts = GenerateOptions{
+	Duration:    60,
+	Conns:       1,
+	Client:      1,
+	ContentType: types.ContentTypeJSON,
+}
+
+const testAuditLog = `
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"1","stage":"ResponseComplete","requestURI":"/api/v1/namespaces/default/pods?resourceVersion=0","verb":"list","user":{"username":"a"},"objectRef":{"resource":"pods","namespace":"default","apiVersion":"v1"},"requestReceivedTimestamp":"2024-01-01T00:00:00.000000Z","stageTimestamp":"2024-01-01T00:00:00.100000Z"}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"2","stage":"ResponseComplete","requestURI":"/api/v1/namespaces/default/pods?resourceVersion=0","verb":"list","user":{"username":"a"},"objectRef":{"resource":"pods","namespace":"default","apiVersion":"v1"},"requestReceivedTimestamp":"2024-01-01T00:00:01.000000Z","stageTimestamp":"2024-01-01T00:00:01.100000Z"}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"3","stage":"ResponseComplete","requestURI":"/apis/apps/v1/deployments?limit=500","verb":"list","user":{"username":"a"},"objectRef":{"resource":"deployments","apiGroup":"apps","apiVersion":"v1"},"requestReceivedTimestamp":"2024-01-01T00:00:02.000000Z","stageTimestamp":"2024-01-01T00:00:02.100000Z"}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"4","stage":"ResponseComplete","requestURI":"/api/v1/namespaces/kube-system/configmaps/a","verb":"get","user":{"username":"a"},"objectRef":{"resource":"configmaps","namespace":"kube-system","name":"a","apiVersion":"v1"},"requestReceivedTimestamp":"2024-01-01T00:00:03.000000Z","stageTimestamp":"2024-01-01T00:00:03.100000Z"}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"4","stage":"RequestReceived","requestURI":"/api/v1/namespaces/kube-system/configmaps/a","verb":"get","user":{"username":"a"},"objectRef":{"resource":"configmaps","namespace":"kube-system","name":"a","apiVersion":"v1"},"requestReceivedTimestamp":"2024-01-01T00:00:03.000000Z","stageTimestamp":"2024-01-01T00:00:03.000000Z"}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"5","stage":"ResponseComplete","requestURI":"/api/v1/namespaces/default/pods/x","verb":"update","user":{"username":"a"},"objectRef":{"resource":"pods","namespace":"default","name":"x","apiVersion":"v1"},"requestReceivedTimestamp":"2024-01-01T00:00:04.000000Z","stageTimestamp":"2024-01-01T00:00:04.100000Z"}
+`
+
+func TestGenerateFromAuditLog(t *testing.T) {
+	obs, window, err := ObserveAuditLog(strings.NewReader(testAuditLog))
+	require.NoError(t, err)
+	assert.Equal(t, 4*time.Second, window)
+	assert.Equal(t, map[string]float64{"update pods": 1}, obs.Unsupported())
+
+	profile, err := obs.Generate(window, testGenerateOpts)
+	require.NoError(t, err)
+	require.Len(t, profile.Specs, 1)
+
+	spec := profile.Specs[0]
+	assert.Equal(t, 1.0, spec.Rate)
+	require.Len(t, spec.Requests, 3)
+
+	assert.Equal(t, 500, spec.Requests[0].Shares)
+	require.NotNil(t, spec.Requests[0].StaleList)
+	assert.Equal(t, "pods", spec.Requests[0].StaleList.Resource)
+	assert.Equal(t, "default", spec.Requests[0].StaleList.Namespace)
+
+	assert.Equal(t, 250, spec.Requests[1].Shares)
+	require.NotNil(t, spec.Requests[1].QuorumGet)
+	assert.Equal(t, "kube-system", spec.Requests[1].QuorumGet.Namespace)
+	assert.Equal(t, "a", spec.Requests[1].QuorumGet.Name)
+
+	assert.Equal(t, 250, spec.Requests[2].Shares)
+	require.NotNil(t, spec.Requests[2].QuorumList)
+	assert.Equal(t, "apps", spec.Requests[2].QuorumList.Group)
+	assert.Equal(t, 500, spec.Requests[2].QuorumList.Limit)
+}
+
+const testMetricsBefore = `
+# HELP apiserver_request_total [STABLE] Counter of apiserver requests.
+# TYPE apiserver_request_total counter
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="",resource="pods",scope="namespace",subresource="",verb="LIST",version="v1"} 100
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="",resource="nodes",scope="resource",subresource="",verb="GET",version="v1"} 50
+apiserver_request_total{code="201",component="apiserver",dry_run="",group="",resource="pods",scope="namespace",subresource="",verb="POST",version="v1"} 10
+`
+
+const testMetricsAfter = `
+# HELP apiserver_request_total [STABLE] Counter of apiserver requests.
+# TYPE apiserver_request_total counter
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="",resource="pods",scope="namespace",subresource="",verb="LIST",version="v1"} 700
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="",resource="nodes",scope="resource",subresource="",verb="GET",version="v1"} 350
+apiserver_request_total{code="201",component="apiserver",dry_run="",group="",resource="pods",scope="namespace",subresource="",verb="POST",version="v1"} 85
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="",resource="pods",scope="resource",subresource="",verb="DELETE",version="v1"} 25
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="",resource="pods",scope="namespace",subresource="",verb="WATCH",version="v1"} 3
+`
+
+func TestGenerateFromRequestTotal(t *testing.T) {
+	obs, err := ObserveRequestTotalDelta(strings.NewReader(testMetricsBefore), strings.NewReader(testMetricsAfter),
+		MetricsObserveOptions{Namespace: "default", Name: "x"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"WATCH pods": 3}, obs.Unsupported())
+
+	profile, err := obs.Generate(10*time.Second, testGenerateOpts)
+	require.NoError(t, err)
+
+	spec := profile.Specs[0]
+	assert.Equal(t, 100.0, spec.Rate)
+	require.Len(t, spec.Requests, 3)
+
+	assert.Equal(t, 600, spec.Requests[0].Shares)
+	require.NotNil(t, spec.Requests[0].StaleList)
+	assert.Equal(t, "default", spec.Requests[0].StaleList.Namespace)
+
+	assert.Equal(t, 300, spec.Requests[1].Shares)
+	require.NotNil(t, spec.Requests[1].QuorumGet)
+	assert.Equal(t, "x", spec.Requests[1].QuorumGet.Name)
+	assert.Equal(t, "nodes", spec.Requests[1].QuorumGet.Resource)
+	// nodes are cluster-scoped.
+	assert.Empty(t, spec.Requests[1].QuorumGet.Namespace)
+
+	assert.Equal(t, 100, spec.Requests[2].Shares)
+	require.NotNil(t, spec.Requests[2].PostDel)
+	assert.Equal(t, 0.25, spec.Requests[2].PostDel.DeleteRatio)
+}
@@ -0,0 +1,204 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package loadprofile
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Azure/kperf/api/types"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// requestTotalMetricName is the name of kube-apiserver's request counter.
+const requestTotalMetricName = "apiserver_request_total"
+
+// MetricsObserveOptions fills the information which isn't recorded in
+// apiserver_request_total metrics.
+type MetricsObserveOptions struct {
+	// Namespace is used by namespace and resource scope requests.
+	Namespace string
+	// Name is used by resource scope requests.
+	Name string
+	// ClusterScopedResources are the cluster-scoped resources besides the
+	// built-in ones, like CRDs, in the format of RESOURCE[.GROUP].
+	ClusterScopedResources []string
+}
+
+// builtinClusterScopedResources are the built-in cluster-scoped resources in
+// the format of RESOURCE[.GROUP].
+var builtinClusterScopedResources = []string{
+	"componentstatuses",
+	"namespaces",
+	"nodes",
+	"persistentvolumes",
+	"mutatingwebhookconfigurations.admissionregistration.k8s.io",
+	"validatingadmissionpolicies.admissionregistration.k8s.io",
+	"validatingadmissionpolicybindings.admissionregistration.k8s.io",
+	"validatingwebhookconfigurations.admissionregistration.k8s.io",
+	"customresourcedefinitions.apiextensions.k8s.io",
+	"apiservices.apiregistration.k8s.io",
+	"selfsubjectreviews.authentication.k8s.io",
+	"tokenreviews.authentication.k8s.io",
+	"selfsubjectaccessreviews.authorization.k8s.io",
+	"selfsubjectrulesreviews.authorization.k8s.io",
+	"subjectaccessreviews.authorization.k8s.io",
+	"certificatesigningrequests.certificates.k8s.io",
+	"flowschemas.flowcontrol.apiserver.k8s.io",
+	"prioritylevelconfigurations.flowcontrol.apiserver.k8s.io",
+	"ingressclasses.networking.k8s.io",
+	"ipaddresses.networking.k8s.io",
+	"servicecidrs.networking.k8s.io",
+	"runtimeclasses.node.k8s.io",
+	"clusterrolebindings.rbac.authorization.k8s.io",
+	"clusterroles.rbac.authorization.k8s.io",
+	"deviceclasses.resource.k8s.io",
+	"resourceslices.resource.k8s.io",
+	"priorityclasses.scheduling.k8s.io",
+	"csidrivers.storage.k8s.io",
+	"csinodes.storage.k8s.io",
+	"storageclasses.storage.k8s.io",
+	"volumeattachments.storage.k8s.io",
+}
+
+// isClusterScoped returns true if the resource of group is cluster-scoped.
+func (opts *MetricsObserveOptions) isClusterScoped(group, resource string) bool {
+	name := resource
+	if group != "" {
+		name = resource + "." + group
+	}
+	return slices.Contains(builtinClusterScopedResources, name) ||
+		slices.Contains(opts.ClusterScopedResources, name)
+}
+
+// ObserveRequestTotalDelta returns the observations from the difference of
+// two snapshots of apiserver_request_total metrics in text format.
+//
+// The metrics don't record resource version, namespace or object name. LIST
+// is treated as stale list because most of lists come from informers with
+// zero resource version. GET is treated as quorum get. The namespace and
+// name come from opts. WATCH isn't supported because it can't tell whether
+// it's watch list.
+func ObserveRequestTotalDelta(before, after io.Reader, opts MetricsObserveOptions) (*Observations, error) {
+	beforeCounters, err := parseRequestTotal(before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the first snapshot: %w", err)
+	}
+
+	afterCounters, err := parseRequestTotal(after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the second snapshot: %w", err)
+	}
+
+	o := NewObservations()
+	for id, c := range afterCounters {
+		delta := c.value
+		if prev, ok := beforeCounters[id]; ok && prev.value <= c.value {
+			delta -= prev.value
+		}
+		// Otherwise, the counter has been reset between snapshots.
+
+		if delta <= 0 {
+			continue
+		}
+		o.observeRequestTotal(c.labels, delta, opts)
+	}
+	return o, nil
+}
+
+// requestTotalCounter is one series of apiserver_request_total.
+type requestTotalCounter struct {
+	labels map[string]string
+	value  float64
+}
+
+// parseRequestTotal returns apiserver_request_total series keyed by labels.
+func parseRequestTotal(r io.Reader) (map[string]requestTotalCounter, error) {
+	var parser expfmt.TextParser
+
+	families, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return nil, err
+	}
+
+	family, ok := families[requestTotalMetricName]
+	if !ok {
+		return nil, fmt.Errorf("%s not found", requestTotalMetricName)
+	}
+	if family.GetType() != dto.MetricType_COUNTER {
+		return nil, fmt.Errorf("%s is %s, not counter", requestTotalMetricName, family.GetType())
+	}
+
+	res := make(map[string]requestTotalCounter, len(family.GetMetric()))
+	for _, m := range family.GetMetric() {
+		labels := make(map[string]string, len(m.GetLabel()))
+		pairs := make([]string, 0, len(m.GetLabel()))
+		for _, l := range m.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+			pairs = append(pairs, l.GetName()+"="+l.GetValue())
+		}
+		sort.Strings(pairs)
+
+		res[strings.Join(pairs, ",")] = requestTotalCounter{
+			labels: labels,
+			value:  m.GetCounter().GetValue(),
+		}
+	}
+	return res, nil
+}
+
+// observeRequestTotal maps one series of apiserver_request_total to the
+// request kind.
+func (o *Observations) observeRequestTotal(labels map[string]string, count float64, opts MetricsObserveOptions) {
+	verb, subresource, scope := labels["verb"], labels["subresource"], labels["scope"]
+
+	resource := labels["resource"]
+	if subresource != "" {
+		resource = resource + "/" + subresource
+	}
+
+	key := observationKey{
+		gvr: types.KubeGroupVersionResource{
+			Group:    labels["group"],
+			Version:  labels["version"],
+			Resource: labels["resource"],
+		},
+	}
+	// The scope is resource for single object requests no matter whether
+	// the object is namespaced or not.
+	if scope == "namespace" || (scope == "resource" && !opts.isClusterScoped(labels["group"], labels["resource"])) {
+		key.namespace = opts.Namespace
+	}
+
+	switch {
+	case labels["resource"] == "":
+		// non-resource requests, like /healthz.
+		o.addUnsupported(verb, resource, count)
+	case verb == "LIST" && subresource == "":
+		key.kind = requestKindStaleList
+		o.add(key, "", count)
+	case verb == "GET" && subresource == "" && scope == "resource":
+		key.kind = requestKindQuorumGet
+		o.add(key, opts.Name, count)
+	case verb == "GET" && resource == "pods/log":
+		key.kind = requestKindGetPodLog
+		key.gvr.Resource = resource
+		o.add(key, opts.Name, count)
+	case (verb == "POST" || verb == "DELETE") && resource == "pods":
+		key.kind = requestKindPostDel
+		// POST is namespace scope while DELETE is resource scope.
+		key.namespace = opts.Namespace
+		ob := o.add(key, "", count)
+		if verb == "DELETE" {
+			ob.deletes += count
+		}
+	default:
+		o.addUnsupported(verb, resource, count)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+const (
+	// defaultAbortWindow is the default sliding window in seconds.
+	defaultAbortWindow = 10
+	// defaultAbortMinRequests is the default minimum number of responses
+	// in the window before evaluation.
+	defaultAbortMinRequests = 10
+)
+
+// AbortMonitor evaluates abort conditions over a sliding window of observed
+// responses.
+type AbortMonitor struct {
+	mu sync.Mutex
+
+	cond types.AbortConditions
+	// buckets is a ring of per-second buckets.
+	buckets []abortBucket
+
+	consecutiveConnErrs int
+	// reason is set once any condition fires.
+	reason string
+}
+
+// abortBucket stores responses observed in one second.
+type abortBucket struct {
+	sec       int64
+	total     int
+	failed    int
+	tooMany   int
+	latencies *Histogram
+}
+
+// NewAbortMonitor returns new instance of AbortMonitor.
+func NewAbortMonitor(cond types.AbortConditions) *AbortMonitor {
+	if cond.Window == 0 {
+		cond.Window = defaultAbortWindow
+	}
+	if cond.MinRequests == 0 {
+		cond.MinRequests = defaultAbortMinRequests
+	}
+	return &AbortMonitor{
+		cond:    cond,
+		buckets: make([]abortBucket, cond.Window),
+	}
+}
+
+// ObserveLatency observes successful response.
+func (m *AbortMonitor) ObserveLatency(now time.Time, seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b := m.bucket(now)
+	b.total++
+	if b.latencies == nil {
+		b.latencies = NewHistogram()
+	}
+	b.latencies.Observe(seconds)
+	m.consecutiveConnErrs = 0
+}
+
+// ObserveFailure observes failure response.
+func (m *AbortMonitor) ObserveFailure(now time.Time, seconds float64, err error) {
+	if err == nil {
+		return
+	}
+	oerr := newResponseError("", "", now, seconds, err)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b := m.bucket(now)
+	b.total++
+	b.failed++
+	if oerr.Type == types.ResponseErrorTypeHTTP && oerr.Code == http.StatusTooManyRequests {
+		b.tooMany++
+	}
+
+	if oerr.Type != types.ResponseErrorTypeConnection {
+		m.consecutiveConnErrs = 0
+		return
+	}
+
+	m.consecutiveConnErrs++
+	if limit := m.cond.MaxConsecutiveConnectionErrors; limit > 0 && m.consecutiveConnErrs >= limit && m.reason == "" {
+		m.reason = fmt.Sprintf("%d consecutive connection errors (threshold %d), last: %s",
+			m.consecutiveConnErrs, limit, oerr.Message)
+	}
+}
+
+// Check evaluates abort conditions over the window ending at now. It returns
+// the reason if any condition has fired.
+func (m *AbortMonitor) Check(now time.Time) (reason string, aborted bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.reason != "" {
+		return m.reason, true
+	}
+
+	total, failed, tooMany := 0, 0, 0
+	latencies := NewHistogram()
+	oldest := now.Unix() - int64(m.cond.Window) + 1
+	for i := range m.buckets {
+		b := &m.buckets[i]
+		if b.sec < oldest || b.sec > now.Unix() {
+			continue
+		}
+		total += b.total
+		failed += b.failed
+		tooMany += b.tooMany
+		if b.latencies != nil {
+			latencies.Merge(&b.latencies.data)
+		}
+	}
+
+	if total == 0 || total < m.cond.MinRequests {
+		return "", false
+	}
+
+	window := fmt.Sprintf("%ds", m.cond.Window)
+	if limit := m.cond.MaxErrorRate; limit > 0 {
+		if rate := float64(failed) * 100 / float64(total); rate > limit {
+			m.reason = fmt.Sprintf("error rate %.2f%% over last %s exceeds %.2f%%", rate, window, limit)
+			return m.reason, true
+		}
+	}
+
+	if limit := m.cond.MaxTooManyRequestsRate; limit > 0 {
+		if rate := float64(tooMany) * 100 / float64(total); rate > limit {
+			m.reason = fmt.Sprintf("429 ratio %.2f%% over last %s exceeds %.2f%%", rate, window, limit)
+			return m.reason, true
+		}
+	}
+
+	if limit := m.cond.MaxP99Latency; limit > 0 && latencies.Count() > 0 {
+		if p99 := latencies.Quantile(0.99); p99 > limit {
+			m.reason = fmt.Sprintf("p99 latency %.3fs over last %s exceeds %.3fs", p99, window, limit)
+			return m.reason, true
+		}
+	}
+	return "", false
+}
+
+// bucket returns the bucket for now and resets it if it's stale.
+func (m *AbortMonitor) bucket(now time.Time) *abortBucket {
+	sec := now.Unix()
+	b := &m.buckets[sec%int64(len(m.buckets))]
+	if b.sec != sec {
+		*b = abortBucket{sec: sec}
+	}
+	return b
+}
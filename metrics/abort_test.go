@@ -0,0 +1,85 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestAbortMonitor(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	t.Run("error rate", func(t *testing.T) {
+		m := NewAbortMonitor(types.AbortConditions{MaxErrorRate: 10})
+		for i := 0; i < 9; i++ {
+			m.ObserveLatency(now, 0.1)
+		}
+		m.ObserveFailure(now, 0.1, apierrors.NewInternalError(errors.New("oops")))
+
+		_, aborted := m.Check(now)
+		assert.False(t, aborted)
+
+		m.ObserveFailure(now, 0.1, apierrors.NewInternalError(errors.New("oops")))
+		reason, aborted := m.Check(now)
+		assert.True(t, aborted)
+		assert.Contains(t, reason, "error rate")
+
+		// The reason sticks even if the window moves.
+		_, aborted = m.Check(now.Add(time.Hour))
+		assert.True(t, aborted)
+	})
+
+	t.Run("429 ratio outside window", func(t *testing.T) {
+		m := NewAbortMonitor(types.AbortConditions{Window: 5, MinRequests: 1, MaxTooManyRequestsRate: 50})
+		m.ObserveFailure(now, 0.1, apierrors.NewTooManyRequestsError("retry"))
+
+		_, aborted := m.Check(now.Add(5 * time.Second))
+		assert.False(t, aborted)
+
+		reason, aborted := m.Check(now.Add(4 * time.Second))
+		assert.True(t, aborted)
+		assert.Contains(t, reason, "429 ratio")
+	})
+
+	t.Run("p99 latency", func(t *testing.T) {
+		m := NewAbortMonitor(types.AbortConditions{MinRequests: 1, MaxP99Latency: 1})
+		m.ObserveLatency(now, 0.5)
+
+		_, aborted := m.Check(now)
+		assert.False(t, aborted)
+
+		m.ObserveLatency(now, 2)
+		reason, aborted := m.Check(now)
+		assert.True(t, aborted)
+		assert.Contains(t, reason, "p99 latency")
+	})
+
+	t.Run("consecutive connection errors", func(t *testing.T) {
+		m := NewAbortMonitor(types.AbortConditions{MaxConsecutiveConnectionErrors: 3})
+		connErr := fmt.Errorf("oops: %w", syscall.ECONNREFUSED)
+
+		m.ObserveFailure(now, 0.1, connErr)
+		m.ObserveFailure(now, 0.1, connErr)
+		m.ObserveLatency(now, 0.1)
+		m.ObserveFailure(now, 0.1, connErr)
+		m.ObserveFailure(now, 0.1, connErr)
+
+		_, aborted := m.Check(now)
+		assert.False(t, aborted)
+
+		m.ObserveFailure(now, 0.1, connErr)
+		reason, aborted := m.Check(now)
+		assert.True(t, aborted)
+		assert.Contains(t, reason, "consecutive connection errors")
+	})
+}
@@ -0,0 +1,174 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+const (
+	// ScopeResource is the scope of requests to single object.
+	ScopeResource = "resource"
+	// ScopeNamespace is the scope of requests to objects in one namespace.
+	ScopeNamespace = "namespace"
+	// ScopeCluster is the scope of requests to objects across namespaces
+	// or cluster-scoped objects.
+	ScopeCluster = "cluster"
+)
+
+// RequestClass is how apiserver's latency SLIs [1] classify a request.
+//
+// [1]: https://github.com/kubernetes/community/blob/master/sig-scalability/slos/api_call_latency.md
+type RequestClass struct {
+	// Verb is the Kubernetes API verb, for instance, LIST or WATCH.
+	Verb string
+	// Resource includes group and subresource, for instance,
+	// deployments.apps and pods/log. It's empty for non-resource
+	// requests, like /healthz.
+	Resource string
+	// Scope is one of ScopeResource, ScopeNamespace and ScopeCluster. It's
+	// empty for non-resource requests.
+	Scope string
+}
+
+// ClassifyRequest classifies request by method and URL, which can be masked.
+func ClassifyRequest(method, rawURL string) RequestClass {
+	cls := RequestClass{Verb: strings.ToUpper(method)}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return cls
+	}
+
+	segs := strings.Split(strings.Trim(u.Path, "/"), "/")
+	var group string
+	switch {
+	case len(segs) >= 2 && segs[0] == "api":
+		segs = segs[2:]
+	case len(segs) >= 3 && segs[0] == "apis":
+		group, segs = segs[1], segs[3:]
+	default:
+		return cls
+	}
+	if len(segs) == 0 {
+		return cls
+	}
+
+	// /namespaces/{namespace} is the namespace object itself.
+	var namespace string
+	if segs[0] == "namespaces" && len(segs) >= 3 {
+		namespace, segs = segs[1], segs[2:]
+	}
+
+	var name, subresource string
+	cls.Resource = segs[0]
+	if len(segs) >= 2 {
+		name = segs[1]
+	}
+	if len(segs) >= 3 {
+		subresource = segs[2]
+	}
+	if group != "" {
+		cls.Resource += "." + group
+	}
+	if subresource != "" {
+		cls.Resource += "/" + subresource
+	}
+
+	switch {
+	case name != "":
+		cls.Scope = ScopeResource
+	case namespace != "":
+		cls.Scope = ScopeNamespace
+	default:
+		cls.Scope = ScopeCluster
+	}
+
+	watch := u.Query().Get("watch")
+	switch cls.Verb {
+	case http.MethodGet, http.MethodHead:
+		switch {
+		case watch == "true" || watch == "1":
+			cls.Verb = "WATCH"
+		case name == "":
+			cls.Verb = "LIST"
+		default:
+			cls.Verb = "GET"
+		}
+	case http.MethodDelete:
+		if name == "" {
+			cls.Verb = "DELETECOLLECTION"
+		}
+	}
+	return cls
+}
+
+// BuildAggregateReports aggregates latency histograms, keyed by "METHOD URL"
+// like ResponseStats.LatencyHistogramsByURL, and error classes by verb,
+// resource and scope. The non-resource requests are only aggregated by verb.
+func BuildAggregateReports(hists map[string]*types.LatencyHistogram, errs []types.ResponseErrorClass) (byVerb, byResource, byScope map[string]*types.AggregateReport) {
+	observe := func(cls RequestClass, h *types.LatencyHistogram, total, failures int) {
+		add := func(reports *map[string]*types.AggregateReport, key string) {
+			if key == "" {
+				return
+			}
+			if *reports == nil {
+				*reports = map[string]*types.AggregateReport{}
+			}
+
+			r, ok := (*reports)[key]
+			if !ok {
+				r = &types.AggregateReport{}
+				(*reports)[key] = r
+			}
+			r.Total += total
+			r.Failures += failures
+			r.Histogram = MergeLatencyHistograms(r.Histogram, h)
+		}
+		add(&byVerb, cls.Verb)
+		add(&byResource, cls.Resource)
+		add(&byScope, cls.Scope)
+	}
+
+	for key, h := range hists {
+		method, u, _ := strings.Cut(key, " ")
+		observe(ClassifyRequest(method, u), h, int(h.Count), 0)
+	}
+	for _, err := range errs {
+		observe(ClassifyRequest(err.Method, err.URL), nil, err.Count, err.Count)
+	}
+
+	for _, reports := range []map[string]*types.AggregateReport{byVerb, byResource, byScope} {
+		for _, r := range reports {
+			r.PercentileLatencies = BuildPercentileLatenciesFromHistogram(r.Histogram)
+		}
+	}
+	return byVerb, byResource, byScope
+}
+
+// MergeAggregateReports merges aggregate reports from src into dst and
+// returns dst. The latency percentiles should be rebuilt from Histogram
+// after merge.
+func MergeAggregateReports(dst, src map[string]*types.AggregateReport) map[string]*types.AggregateReport {
+	for key, r := range src {
+		if dst == nil {
+			dst = map[string]*types.AggregateReport{}
+		}
+
+		merged, ok := dst[key]
+		if !ok {
+			merged = &types.AggregateReport{}
+			dst[key] = merged
+		}
+
+		merged.Total += r.Total
+		merged.Failures += r.Failures
+		merged.Histogram = MergeLatencyHistograms(merged.Histogram, r.Histogram)
+	}
+	return dst
+}
@@ -0,0 +1,69 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyRequest(t *testing.T) {
+	for _, tc := range []struct {
+		method   string
+		url      string
+		expected RequestClass
+	}{
+		{"GET", "https://10.0.0.1/api/v1/pods?limit=500", RequestClass{"LIST", "pods", ScopeCluster}},
+		{"GET", "https://10.0.0.1/api/v1/namespaces/default/pods", RequestClass{"LIST", "pods", ScopeNamespace}},
+		{"GET", "https://10.0.0.1/api/v1/namespaces/default/pods/x1", RequestClass{"GET", "pods", ScopeResource}},
+		{"GET", "https://10.0.0.1/api/v1/namespaces/default/pods/x1/log", RequestClass{"GET", "pods/log", ScopeResource}},
+		{"GET", "https://10.0.0.1/api/v1/namespaces/default", RequestClass{"GET", "namespaces", ScopeResource}},
+		{"GET", "https://10.0.0.1/apis/apps/v1/deployments?watch=true", RequestClass{"WATCH", "deployments.apps", ScopeCluster}},
+		{"POST", "https://10.0.0.1/api/v1/namespaces/default/configmaps", RequestClass{"POST", "configmaps", ScopeNamespace}},
+		{"PUT", "https://10.0.0.1/api/v1/namespaces/default/configmaps/:name", RequestClass{"PUT", "configmaps", ScopeResource}},
+		{"DELETE", "https://10.0.0.1/api/v1/namespaces/default/configmaps", RequestClass{"DELETECOLLECTION", "configmaps", ScopeNamespace}},
+		{"GET", "https://10.0.0.1/healthz", RequestClass{Verb: "GET"}},
+		{"GET", "https://10.0.0.1/apis/apps/v1", RequestClass{Verb: "GET"}},
+	} {
+		assert.Equal(t, tc.expected, ClassifyRequest(tc.method, tc.url), "%s %s", tc.method, tc.url)
+	}
+}
+
+func TestBuildAggregateReports(t *testing.T) {
+	hists := map[string]*types.LatencyHistogram{
+		"GET https://10.0.0.1/api/v1/pods":                          HistogramFromLatencies([]float64{1, 2}),
+		"GET https://10.0.0.1/api/v1/namespaces/default/pods":       HistogramFromLatencies([]float64{3}),
+		"GET https://10.0.0.1/api/v1/namespaces/default/pods/x1":    HistogramFromLatencies([]float64{0.1}),
+		"PUT https://10.0.0.1/api/v1/namespaces/default/pods/:name": HistogramFromLatencies([]float64{0.2}),
+	}
+	errs := []types.ResponseErrorClass{
+		{Method: "GET", URL: "https://10.0.0.1/api/v1/pods", Type: types.ResponseErrorTypeHTTP, Code: 429, Count: 1},
+	}
+
+	byVerb, byResource, byScope := BuildAggregateReports(hists, errs)
+
+	assert.Equal(t, 4, byVerb["LIST"].Total)
+	assert.Equal(t, 1, byVerb["LIST"].Failures)
+	assert.Equal(t, uint64(3), byVerb["LIST"].Histogram.Count)
+	assert.Equal(t, 1, byVerb["GET"].Total)
+	assert.Equal(t, 1, byVerb["PUT"].Total)
+
+	assert.Len(t, byResource, 1)
+	assert.Equal(t, 6, byResource["pods"].Total)
+
+	assert.Equal(t, 3, byScope[ScopeCluster].Total)
+	assert.Equal(t, 1, byScope[ScopeNamespace].Total)
+	assert.Equal(t, 2, byScope[ScopeResource].Total)
+	assert.Equal(t, [2]float64{1, 0.2}, byScope[ScopeResource].PercentileLatencies[len(byScope[ScopeResource].PercentileLatencies)-1])
+
+	merged := MergeAggregateReports(nil, byVerb)
+	merged = MergeAggregateReports(merged, byVerb)
+	assert.Equal(t, 8, merged["LIST"].Total)
+	assert.Equal(t, 2, merged["LIST"].Failures)
+	assert.Equal(t, uint64(6), merged["LIST"].Histogram.Count)
+	assert.Equal(t, uint64(3), byVerb["LIST"].Histogram.Count)
+}
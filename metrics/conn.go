@@ -0,0 +1,77 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"sort"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+// MergeConnectionReports merges connection reports into one. The dial
+// latency percentiles are rebuilt from DialLatencyHistogram, or from
+// DialLatencies for the reports without histogram. It returns nil if all the
+// reports are nil.
+func MergeConnectionReports(reports ...*types.ConnectionReport) *types.ConnectionReport {
+	var res *types.ConnectionReport
+	for _, r := range reports {
+		if r == nil {
+			continue
+		}
+		if res == nil {
+			res = &types.ConnectionReport{}
+		}
+
+		res.Dials += r.Dials
+		res.DialErrors += r.DialErrors
+		res.DialLatencies = append(res.DialLatencies, r.DialLatencies...)
+		dialHistogram := r.DialLatencyHistogram
+		if dialHistogram == nil {
+			dialHistogram = HistogramFromLatencies(r.DialLatencies)
+		}
+		res.DialLatencyHistogram = MergeLatencyHistograms(res.DialLatencyHistogram, dialHistogram)
+		res.TLSHandshakes += r.TLSHandshakes
+		res.TLSHandshakeErrors += r.TLSHandshakeErrors
+		res.Closed += r.Closed
+		res.ChurnEvents = append(res.ChurnEvents, r.ChurnEvents...)
+		res.HTTP2 = mergeHTTP2Reports(res.HTTP2, r.HTTP2)
+	}
+
+	if res != nil {
+		res.PercentileDialLatencies = BuildPercentileLatenciesFromHistogram(res.DialLatencyHistogram)
+		sort.SliceStable(res.ChurnEvents, func(i, j int) bool {
+			return res.ChurnEvents[i].Timestamp.Before(res.ChurnEvents[j].Timestamp)
+		})
+	}
+	return res
+}
+
+// mergeHTTP2Reports merges src into dst and returns dst. The per-connection
+// reports are merged by index.
+func mergeHTTP2Reports(dst, src *types.HTTP2Report) *types.HTTP2Report {
+	if src == nil {
+		return dst
+	}
+	if dst == nil {
+		dst = &types.HTTP2Report{}
+	}
+
+	dst.GoAways += src.GoAways
+	dst.RefusedStreams += src.RefusedStreams
+	dst.Replacements += src.Replacements
+	dst.LostPings += src.LostPings
+	for idx, r := range src.PerConnection {
+		if idx >= len(dst.PerConnection) {
+			dst.PerConnection = append(dst.PerConnection, types.HTTP2ConnectionReport{})
+		}
+		merged := &dst.PerConnection[idx]
+		merged.Dials += r.Dials
+		merged.GoAways += r.GoAways
+		merged.RefusedStreams += r.RefusedStreams
+		merged.Replacements += r.Replacements
+		merged.LostPings += r.LostPings
+		merged.MaxConcurrentStreams = max(merged.MaxConcurrentStreams, r.MaxConcurrentStreams)
+	}
+	return dst
+}
@@ -0,0 +1,32 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeConnectionReports(t *testing.T) {
+	assert.Nil(t, MergeConnectionReports(nil, nil))
+
+	res := MergeConnectionReports(
+		// The report without raw data.
+		&types.ConnectionReport{Dials: 2, DialLatencyHistogram: HistogramFromLatencies([]float64{0.01, 0.02})},
+		// The report written before histogram is available.
+		&types.ConnectionReport{Dials: 1, DialLatencies: []float64{0.5}},
+		nil,
+	)
+	require.NotNil(t, res)
+	assert.Equal(t, 3, res.Dials)
+	assert.Equal(t, uint64(3), res.DialLatencyHistogram.Count)
+	require.NotEmpty(t, res.PercentileDialLatencies)
+	p100 := res.PercentileDialLatencies[len(res.PercentileDialLatencies)-1]
+	assert.Equal(t, 1.0, p100[0])
+	assert.InDelta(t, 0.5, p100[1], 0.01)
+}
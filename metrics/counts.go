@@ -0,0 +1,76 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"github.com/Azure/kperf/api/types"
+)
+
+// SumRequestCounts returns the number of dispatched, succeeded and failed
+// requests in counts.
+func SumRequestCounts(counts map[string]*types.RequestCounts) (dispatched, succeeded, failed int) {
+	for _, c := range counts {
+		dispatched += c.Dispatched
+		succeeded += c.Succeeded
+		failed += c.Failed
+	}
+	return dispatched, succeeded, failed
+}
+
+// MergeRequestCounts merges request counts from src into dst and returns
+// dst. The AchievedQPS should be rebuilt after merge.
+func MergeRequestCounts(dst, src map[string]*types.RequestCounts) map[string]*types.RequestCounts {
+	for key, c := range src {
+		if dst == nil {
+			dst = map[string]*types.RequestCounts{}
+		}
+
+		merged, ok := dst[key]
+		if !ok {
+			merged = &types.RequestCounts{}
+			dst[key] = merged
+		}
+
+		merged.Dispatched += c.Dispatched
+		merged.Succeeded += c.Succeeded
+		merged.Failed += c.Failed
+		merged.ReceivedBytes += c.ReceivedBytes
+	}
+	return dst
+}
+
+// SetAchievedQPS sets AchievedQPS of counts by the duration of run.
+func SetAchievedQPS(counts map[string]*types.RequestCounts, seconds float64) {
+	for _, c := range counts {
+		c.AchievedQPS = AchievedQPS(c.Succeeded, seconds)
+	}
+}
+
+// AchievedQPS returns the number of succeeded requests per second. It's
+// zero if seconds isn't positive.
+func AchievedQPS(succeeded int, seconds float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+	return float64(succeeded) / seconds
+}
+
+// MergeLimiterWaitReports merges src into dst, which is allocated if it's
+// nil, and returns dst with the mean rebuilt.
+func MergeLimiterWaitReports(dst, src *types.LimiterWaitReport) *types.LimiterWaitReport {
+	if src == nil {
+		return dst
+	}
+	if dst == nil {
+		dst = &types.LimiterWaitReport{}
+	}
+
+	dst.Waits += src.Waits
+	dst.TotalSeconds += src.TotalSeconds
+	dst.MeanSeconds = 0
+	if dst.Waits > 0 {
+		dst.MeanSeconds = dst.TotalSeconds / float64(dst.Waits)
+	}
+	return dst
+}
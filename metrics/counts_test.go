@@ -0,0 +1,52 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestResponseMetricRequestCounts(t *testing.T) {
+	m := NewResponseMetric()
+	for i := 0; i < 3; i++ {
+		m.ObserveLimiterWait(float64(i))
+		m.ObserveDispatch("GET", "/api/v1/pods")
+		m.ObserveReceivedBytes("GET", "/api/v1/pods", 100)
+	}
+	m.ObserveLatency("GET", "/api/v1/pods", 0.1)
+	m.ObserveLatency("GET", "/api/v1/pods", 0.1)
+	m.ObserveFailure("GET", "/api/v1/pods", time.Now(), 1, apierrors.NewTooManyRequests("slow down", 1))
+
+	stats := m.Gather()
+	assert.Equal(t, int64(300), stats.TotalReceivedBytes)
+	assert.Equal(t, map[string]*types.RequestCounts{
+		"GET /api/v1/pods": {Dispatched: 3, Succeeded: 2, Failed: 1, ReceivedBytes: 300},
+	}, stats.CountsByURL)
+	assert.Equal(t, &types.LimiterWaitReport{Waits: 3, TotalSeconds: 3, MeanSeconds: 1}, stats.LimiterWait)
+
+	dispatched, succeeded, failed := SumRequestCounts(stats.CountsByURL)
+	assert.Equal(t, []int{3, 2, 1}, []int{dispatched, succeeded, failed})
+
+	merged := MergeRequestCounts(nil, stats.CountsByURL)
+	merged = MergeRequestCounts(merged, stats.CountsByURL)
+	SetAchievedQPS(merged, 2)
+	require.Contains(t, merged, "GET /api/v1/pods")
+	assert.Equal(t, 6, merged["GET /api/v1/pods"].Dispatched)
+	assert.InDelta(t, 2, merged["GET /api/v1/pods"].AchievedQPS, 0.0001)
+	assert.Zero(t, stats.CountsByURL["GET /api/v1/pods"].AchievedQPS)
+
+	wait := MergeLimiterWaitReports(nil, stats.LimiterWait)
+	wait = MergeLimiterWaitReports(wait, &types.LimiterWaitReport{Waits: 1, TotalSeconds: 5})
+	assert.InDelta(t, 2, wait.MeanSeconds, 0.0001)
+
+	assert.Nil(t, NewResponseMetric().Gather().LimiterWait)
+	assert.Zero(t, AchievedQPS(10, 0))
+}
@@ -0,0 +1,40 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"github.com/Azure/kperf/api/types"
+)
+
+// MergeDecodeReports merges decode reports from src into dst and returns
+// dst. The latency percentiles should be rebuilt from Histogram after merge.
+func MergeDecodeReports(dst, src map[string]*types.DecodeReport) map[string]*types.DecodeReport {
+	for u, r := range src {
+		if dst == nil {
+			dst = map[string]*types.DecodeReport{}
+		}
+
+		merged, ok := dst[u]
+		if !ok {
+			merged = &types.DecodeReport{}
+			dst[u] = merged
+		}
+
+		if r.Lists > 0 {
+			if merged.Lists == 0 || r.MinItems < merged.MinItems {
+				merged.MinItems = r.MinItems
+			}
+			if r.MaxItems > merged.MaxItems {
+				merged.MaxItems = r.MaxItems
+			}
+		}
+		merged.Responses += r.Responses
+		merged.AllocatedBytes += r.AllocatedBytes
+		merged.Lists += r.Lists
+		merged.TotalItems += r.TotalItems
+		merged.Histogram = MergeLatencyHistograms(merged.Histogram, r.Histogram)
+		merged.Latencies = append(merged.Latencies, r.Latencies...)
+	}
+	return dst
+}
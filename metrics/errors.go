@@ -0,0 +1,225 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"math/rand"
+	"regexp"
+	"sort"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+// DefaultErrorSampleSize is the default number of errors kept in sample.
+const DefaultErrorSampleSize = 1000
+
+var (
+	// quotedPattern matches quoted parts in error message, which are
+	// usually URLs.
+	quotedPattern = regexp.MustCompile(`"[^"]*"`)
+	// numberPattern matches standalone numbers in error message, like
+	// IP addresses, ports and durations.
+	numberPattern = regexp.MustCompile(`\b\d+\b`)
+)
+
+// errorMessageClass masks variable parts of error message so that errors
+// with the same cause fall into one class.
+func errorMessageClass(msg string) string {
+	msg = quotedPattern.ReplaceAllString(msg, `"*"`)
+	return numberPattern.ReplaceAllString(msg, "N")
+}
+
+// errorClassKey identifies one class of errors.
+type errorClassKey struct {
+	method       string
+	url          string
+	typ          types.ResponseErrorType
+	code         int
+	messageClass string
+}
+
+func errorClassKeyOf(c *types.ResponseErrorClass) errorClassKey {
+	return errorClassKey{
+		method:       c.Method,
+		url:          c.URL,
+		typ:          c.Type,
+		code:         c.Code,
+		messageClass: c.MessageClass,
+	}
+}
+
+// errorRecorder counts every error by class and keeps a uniform sample of
+// error records by reservoir sampling so that memory is bounded even if
+// all the requests fail.
+type errorRecorder struct {
+	sampleSize int
+	seen       int
+	sample     []types.ResponseError
+	classes    map[errorClassKey]*types.ResponseErrorClass
+}
+
+func newErrorRecorder(sampleSize int) *errorRecorder {
+	return &errorRecorder{
+		sampleSize: sampleSize,
+		classes:    map[errorClassKey]*types.ResponseErrorClass{},
+	}
+}
+
+func (r *errorRecorder) observe(err types.ResponseError) {
+	cls := &types.ResponseErrorClass{
+		Method:       err.Method,
+		URL:          err.URL,
+		Type:         err.Type,
+		Code:         err.Code,
+		MessageClass: errorMessageClass(err.Message),
+	}
+	key := errorClassKeyOf(cls)
+	if c, ok := r.classes[key]; ok {
+		cls = c
+	} else {
+		cls.FirstSeen = err.Timestamp
+		r.classes[key] = cls
+	}
+	cls.Count++
+	if err.Timestamp.Before(cls.FirstSeen) {
+		cls.FirstSeen = err.Timestamp
+	}
+	if err.Timestamp.After(cls.LastSeen) {
+		cls.LastSeen = err.Timestamp
+	}
+
+	r.seen++
+	switch {
+	case len(r.sample) < r.sampleSize:
+		r.sample = append(r.sample, err)
+	default:
+		if idx := rand.Intn(r.seen); idx < r.sampleSize { //nolint:gosec
+			r.sample[idx] = err
+		}
+	}
+}
+
+// dumpSample returns the copy of sample in time order.
+func (r *errorRecorder) dumpSample() []types.ResponseError {
+	res := append(make([]types.ResponseError, 0, len(r.sample)), r.sample...)
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Timestamp.Before(res[j].Timestamp)
+	})
+	return res
+}
+
+// dumpClasses returns the copy of error classes.
+func (r *errorRecorder) dumpClasses() []types.ResponseErrorClass {
+	return sortedErrorClasses(r.classes)
+}
+
+func sortedErrorClasses(classes map[errorClassKey]*types.ResponseErrorClass) []types.ResponseErrorClass {
+	if len(classes) == 0 {
+		return nil
+	}
+
+	res := make([]types.ResponseErrorClass, 0, len(classes))
+	for _, c := range classes {
+		res = append(res, *c)
+	}
+	// The most frequent error comes first.
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].FirstSeen.Before(res[j].FirstSeen)
+	})
+	return res
+}
+
+// MergeErrorClasses merges error classes from src into dst and returns the
+// merged classes, the most frequent first.
+func MergeErrorClasses(dst, src []types.ResponseErrorClass) []types.ResponseErrorClass {
+	classes := make(map[errorClassKey]*types.ResponseErrorClass, len(dst)+len(src))
+	for _, list := range [][]types.ResponseErrorClass{dst, src} {
+		for idx := range list {
+			c := list[idx]
+			key := errorClassKeyOf(&c)
+
+			merged, ok := classes[key]
+			if !ok {
+				classes[key] = &c
+				continue
+			}
+			merged.Count += c.Count
+			if c.FirstSeen.Before(merged.FirstSeen) {
+				merged.FirstSeen = c.FirstSeen
+			}
+			if c.LastSeen.After(merged.LastSeen) {
+				merged.LastSeen = c.LastSeen
+			}
+		}
+	}
+	return sortedErrorClasses(classes)
+}
+
+// BuildErrorStatsFromClasses summaries total count for each type of errors
+// like BuildErrorStatsGroupByType.
+func BuildErrorStatsFromClasses(classes []types.ResponseErrorClass) map[string]int32 {
+	res := map[string]int32{}
+
+	for _, c := range classes {
+		res[errorStatKey(types.ResponseError{Type: c.Type, Code: c.Code, Message: c.MessageClass})] += int32(c.Count)
+	}
+	return res
+}
+
+// ErrorClassesFromErrors counts errors by class. It's used for errors which
+// have been recorded without classes.
+func ErrorClassesFromErrors(errs []types.ResponseError) []types.ResponseErrorClass {
+	r := newErrorRecorder(0)
+	for _, err := range errs {
+		r.observe(err)
+	}
+	return r.dumpClasses()
+}
+
+// MergeErrorSamples merges error sample src, drawn from srcTotal errors, into
+// dst, drawn from dstTotal errors. It returns a uniform sample of the union
+// with size errors at most in time order, and the number of errors which the
+// sample is drawn from. The sample doesn't grow with the number of merged
+// samples.
+func MergeErrorSamples(dst []types.ResponseError, dstTotal int, src []types.ResponseError, srcTotal int, size int) ([]types.ResponseError, int) {
+	dstTotal = max(dstTotal, len(dst))
+	srcTotal = max(srcTotal, len(src))
+	total := dstTotal + srcTotal
+
+	// Draw without replacement from the union. Each pick comes from dst
+	// by the ratio of the errors left in dst, which keeps the sample
+	// uniform even if src and dst are drawn from different totals.
+	dst = append(make([]types.ResponseError, 0, len(dst)), dst...)
+	src = append(make([]types.ResponseError, 0, len(src)), src...)
+	res := make([]types.ResponseError, 0, min(size, len(dst)+len(src)))
+	for len(res) < size && len(dst)+len(src) > 0 {
+		if len(src) == 0 || (len(dst) > 0 && rand.Intn(dstTotal+srcTotal) < dstTotal) { //nolint:gosec
+			var err types.ResponseError
+			err, dst = popRandomError(dst)
+			res = append(res, err)
+			dstTotal--
+			continue
+		}
+
+		var err types.ResponseError
+		err, src = popRandomError(src)
+		res = append(res, err)
+		srcTotal--
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Timestamp.Before(res[j].Timestamp)
+	})
+	return res, total
+}
+
+// popRandomError removes a random error from errs.
+func popRandomError(errs []types.ResponseError) (types.ResponseError, []types.ResponseError) {
+	idx, last := rand.Intn(len(errs)), len(errs)-1 //nolint:gosec
+	errs[idx], errs[last] = errs[last], errs[idx]
+	return errs[last], errs[:last]
+}
@@ -0,0 +1,102 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestResponseMetricErrorSample(t *testing.T) {
+	start := time.Now()
+	m := NewResponseMetric(WithErrorSampleSizeOpt(10))
+	for i := 0; i < 1000; i++ {
+		m.ObserveFailure("GET", "/api/v1/pods", start.Add(time.Duration(i)*time.Second), 1, apierrors.NewTooManyRequests("slow down", 1))
+	}
+	for i := 0; i < 5; i++ {
+		m.ObserveFailure("GET", "/api/v1/nodes", start.Add(time.Duration(i)*time.Second), 1,
+			fmt.Errorf("Get \"https://10.0.0.%d:443/api/v1/nodes\": %w", i, errors.New("oops")))
+	}
+
+	stats := m.Gather()
+	assert.Len(t, stats.Errors, 10)
+	for i := 1; i < len(stats.Errors); i++ {
+		assert.False(t, stats.Errors[i].Timestamp.Before(stats.Errors[i-1].Timestamp))
+	}
+
+	require.Len(t, stats.ErrorClasses, 2)
+	assert.Equal(t, types.ResponseErrorClass{
+		Method:    "GET",
+		URL:       "/api/v1/pods",
+		Type:      types.ResponseErrorTypeHTTP,
+		Code:      429,
+		Count:     1000,
+		FirstSeen: start,
+		LastSeen:  start.Add(999 * time.Second),
+	}, stats.ErrorClasses[0])
+	assert.Equal(t, `Get "*": oops`, stats.ErrorClasses[1].MessageClass)
+	assert.Equal(t, 5, stats.ErrorClasses[1].Count)
+
+	assert.Equal(t, map[string]int32{
+		"http/429":              1000,
+		`unknown/Get "*": oops`: 5,
+	}, BuildErrorStatsFromClasses(stats.ErrorClasses))
+
+	merged := MergeErrorClasses(stats.ErrorClasses, []types.ResponseErrorClass{
+		{Method: "GET", URL: "/api/v1/pods", Type: types.ResponseErrorTypeHTTP, Code: 429, Count: 1,
+			FirstSeen: start.Add(-time.Second), LastSeen: start.Add(-time.Second)},
+	})
+	require.Len(t, merged, 2)
+	assert.Equal(t, 1001, merged[0].Count)
+	assert.Equal(t, start.Add(-time.Second), merged[0].FirstSeen)
+	assert.Equal(t, start.Add(999*time.Second), merged[0].LastSeen)
+	assert.Equal(t, 1000, stats.ErrorClasses[0].Count)
+}
+
+func TestMergeErrorSamples(t *testing.T) {
+	start := time.Now()
+	sample := func(url string, n int) []types.ResponseError {
+		res := make([]types.ResponseError, 0, n)
+		for i := 0; i < n; i++ {
+			res = append(res, types.ResponseError{URL: url, Timestamp: start.Add(time.Duration(i) * time.Second)})
+		}
+		return res
+	}
+
+	// Small samples are kept as they are.
+	merged, total := MergeErrorSamples(sample("/a", 2), 2, sample("/b", 3), 3, 10)
+	assert.Equal(t, 5, total)
+	require.Len(t, merged, 5)
+	for i := 1; i < len(merged); i++ {
+		assert.False(t, merged[i].Timestamp.Before(merged[i-1].Timestamp))
+	}
+
+	// The merged sample is bounded and weighted by the number of errors
+	// each sample is drawn from.
+	var errs []types.ResponseError
+	total = 0
+	for i := 0; i < 9; i++ {
+		errs, total = MergeErrorSamples(errs, total, sample("/b", DefaultErrorSampleSize), 10*DefaultErrorSampleSize, DefaultErrorSampleSize)
+	}
+	errs, total = MergeErrorSamples(errs, total, sample("/a", DefaultErrorSampleSize), DefaultErrorSampleSize, DefaultErrorSampleSize)
+	assert.Equal(t, 91*DefaultErrorSampleSize, total)
+	require.Len(t, errs, DefaultErrorSampleSize)
+
+	fromA := 0
+	for _, err := range errs {
+		if err.URL == "/a" {
+			fromA++
+		}
+	}
+	// The expected number is 1000 / 91 ≈ 11.
+	assert.Less(t, fromA, 40)
+}
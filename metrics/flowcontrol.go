@@ -0,0 +1,34 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"github.com/Azure/kperf/api/types"
+)
+
+// MergeFlowControlReports merges flow control reports from src into dst and
+// returns dst. The latency percentiles should be rebuilt from Histogram
+// after merge.
+func MergeFlowControlReports(dst, src map[string]*types.FlowControlReport) map[string]*types.FlowControlReport {
+	for key, r := range src {
+		if dst == nil {
+			dst = map[string]*types.FlowControlReport{}
+		}
+
+		merged, ok := dst[key]
+		if !ok {
+			merged = &types.FlowControlReport{UID: r.UID}
+			dst[key] = merged
+		}
+
+		merged.Requests += r.Requests
+		merged.Failures += r.Failures
+		merged.Throttled += r.Throttled
+		merged.Retries += r.Retries
+		merged.MaxRetryAfterSeconds = max(merged.MaxRetryAfterSeconds, r.MaxRetryAfterSeconds)
+		merged.Histogram = MergeLatencyHistograms(merged.Histogram, r.Histogram)
+		merged.Latencies = append(merged.Latencies, r.Latencies...)
+	}
+	return dst
+}
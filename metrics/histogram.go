@@ -0,0 +1,299 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+const (
+	// DefaultRelativeAccuracy is the relative accuracy of quantiles
+	// returned by Histogram.
+	DefaultRelativeAccuracy = 0.01
+
+	// minIndexableValue is the smallest value which has its own bucket.
+	// The smaller values, for instance, zero, are counted by ZeroCount.
+	minIndexableValue = 1e-9
+)
+
+// DefaultPercentiles are the quantiles reported in percentile latencies by
+// default.
+var DefaultPercentiles = []float64{0, 0.5, 0.90, 0.95, 0.99, 1}
+
+// requiredPercentiles are always reported since abort conditions and
+// throughput search rely on them.
+var requiredPercentiles = []float64{0.5, 0.99}
+
+// percentiles are the quantiles reported in percentile latencies.
+var percentiles = DefaultPercentiles
+
+// SetPercentiles updates the quantiles, in [0, 1], reported in percentile
+// latencies. 0.5 and 0.99 are always included. It should be called before
+// building any report since it isn't safe for concurrent use.
+func SetPercentiles(ps []float64) error {
+	res := make([]float64, 0, len(ps)+len(requiredPercentiles))
+	for _, p := range ps {
+		if math.IsNaN(p) || p < 0 || p > 1 {
+			return fmt.Errorf("percentile %v should be in [0, 1]", p)
+		}
+		res = append(res, p)
+	}
+	res = append(res, requiredPercentiles...)
+
+	sort.Float64s(res)
+	percentiles = slices.Compact(res)
+	return nil
+}
+
+// Percentiles returns the quantiles reported in percentile latencies.
+func Percentiles() []float64 {
+	return slices.Clone(percentiles)
+}
+
+// Histogram records values into logarithmic buckets, like DDSketch [1]. The
+// quantiles have bounded relative error and it takes constant memory since
+// the number of buckets only depends on the range of values. Histograms
+// with the same relative accuracy can be merged without losing accuracy.
+//
+// It isn't safe for concurrent use.
+//
+// [1]: https://arxiv.org/abs/1908.10693
+type Histogram struct {
+	data     types.LatencyHistogram
+	logGamma float64
+}
+
+// NewHistogram returns new instance of Histogram with DefaultRelativeAccuracy.
+func NewHistogram() *Histogram {
+	return newHistogram(DefaultRelativeAccuracy)
+}
+
+func newHistogram(accuracy float64) *Histogram {
+	return &Histogram{
+		data:     types.LatencyHistogram{RelativeAccuracy: accuracy},
+		logGamma: logGamma(accuracy),
+	}
+}
+
+// logGamma returns the logarithm of bucket's growth factor.
+func logGamma(accuracy float64) float64 {
+	return math.Log((1 + accuracy) / (1 - accuracy))
+}
+
+// Observe records one value.
+func (h *Histogram) Observe(v float64) {
+	d := &h.data
+	if d.Count == 0 || v < d.Min {
+		d.Min = v
+	}
+	if d.Count == 0 || v > d.Max {
+		d.Max = v
+	}
+	d.Count++
+	d.Sum += v
+
+	h.add(v, 1)
+}
+
+// add puts n values into the bucket of v.
+func (h *Histogram) add(v float64, n uint64) {
+	d := &h.data
+	if v < minIndexableValue {
+		d.ZeroCount += n
+		return
+	}
+
+	idx := int(math.Ceil(math.Log(v) / h.logGamma))
+	h.grow(idx, idx)
+	d.Counts[idx-d.Offset] += n
+}
+
+// grow makes sure buckets from lo to hi exist.
+func (h *Histogram) grow(lo, hi int) {
+	d := &h.data
+	if len(d.Counts) == 0 {
+		d.Offset = lo
+		d.Counts = make([]uint64, hi-lo+1)
+		return
+	}
+
+	if lo < d.Offset {
+		d.Counts = append(make([]uint64, d.Offset-lo), d.Counts...)
+		d.Offset = lo
+	}
+	if end := d.Offset + len(d.Counts) - 1; hi > end {
+		d.Counts = append(d.Counts, make([]uint64, hi-end)...)
+	}
+}
+
+// Count returns the number of recorded values.
+func (h *Histogram) Count() uint64 {
+	return h.data.Count
+}
+
+// Merge adds the values recorded by o. It's exact if both have the same
+// relative accuracy. Otherwise, o's buckets are re-recorded by their
+// representative values.
+func (h *Histogram) Merge(o *types.LatencyHistogram) {
+	if o == nil || o.Count == 0 {
+		return
+	}
+
+	d := &h.data
+	if d.Count == 0 || o.Min < d.Min {
+		d.Min = o.Min
+	}
+	if d.Count == 0 || o.Max > d.Max {
+		d.Max = o.Max
+	}
+	d.Count += o.Count
+	d.Sum += o.Sum
+	d.ZeroCount += o.ZeroCount
+
+	if len(o.Counts) == 0 {
+		return
+	}
+
+	if o.RelativeAccuracy != d.RelativeAccuracy {
+		oGamma := math.Exp(logGamma(o.RelativeAccuracy))
+		for i, c := range o.Counts {
+			if c > 0 {
+				h.add(bucketValue(oGamma, o.Offset+i), c)
+			}
+		}
+		return
+	}
+
+	h.grow(o.Offset, o.Offset+len(o.Counts)-1)
+	for i, c := range o.Counts {
+		d.Counts[o.Offset+i-d.Offset] += c
+	}
+}
+
+// bucketValue returns the value in the middle of idx-th bucket, which has
+// the bounded relative error to any value in the bucket.
+func bucketValue(gamma float64, idx int) float64 {
+	return 2 * math.Pow(gamma, float64(idx)) / (gamma + 1)
+}
+
+// Snapshot returns the copy of recorded data.
+func (h *Histogram) Snapshot() *types.LatencyHistogram {
+	copied := h.data
+	copied.Counts = append([]uint64(nil), h.data.Counts...)
+	return &copied
+}
+
+// Quantile returns the value at quantile q, in [0, 1].
+func (h *Histogram) Quantile(q float64) float64 {
+	d := &h.data
+	if d.Count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return d.Min
+	}
+	if q >= 1 {
+		return d.Max
+	}
+
+	// Align with BuildPercentileLatencies, which picks the ceil(n*q)-th
+	// value.
+	rank := uint64(math.Ceil(float64(d.Count) * q))
+	seen := d.ZeroCount
+	if seen >= rank {
+		return d.Min
+	}
+
+	gamma := math.Exp(h.logGamma)
+	for i, c := range d.Counts {
+		seen += c
+		if seen >= rank {
+			v := bucketValue(gamma, d.Offset+i)
+			return math.Min(math.Max(v, d.Min), d.Max)
+		}
+	}
+	return d.Max
+}
+
+// MergeLatencyHistograms merges src into dst and returns dst. If dst is nil,
+// it returns the copy of src.
+func MergeLatencyHistograms(dst, src *types.LatencyHistogram) *types.LatencyHistogram {
+	if src == nil {
+		return dst
+	}
+
+	var h *Histogram
+	if dst == nil {
+		h = newHistogram(src.RelativeAccuracy)
+	} else {
+		h = &Histogram{data: *dst, logGamma: logGamma(dst.RelativeAccuracy)}
+	}
+	h.Merge(src)
+	return &h.data
+}
+
+// BuildPercentileLatenciesFromHistogram builds percentile latencies like
+// BuildPercentileLatencies, from histogram.
+func BuildPercentileLatenciesFromHistogram(data *types.LatencyHistogram) [][2]float64 {
+	return BuildQuantilesFromHistogram(data, percentiles)
+}
+
+// BuildQuantilesFromHistogram builds latencies at the given quantiles, in
+// [0, 1], from histogram.
+func BuildQuantilesFromHistogram(data *types.LatencyHistogram, qs []float64) [][2]float64 {
+	if data == nil || data.Count == 0 {
+		return nil
+	}
+
+	h := &Histogram{data: *data, logGamma: logGamma(data.RelativeAccuracy)}
+	res := make([][2]float64, len(qs))
+	for pi, pv := range qs {
+		res[pi] = [2]float64{pv, h.Quantile(pv)}
+	}
+	return res
+}
+
+// HistogramFromLatencies builds histogram from raw latencies.
+func HistogramFromLatencies(latencies []float64) *types.LatencyHistogram {
+	if len(latencies) == 0 {
+		return nil
+	}
+
+	h := NewHistogram()
+	for _, l := range latencies {
+		h.Observe(l)
+	}
+	return &h.data
+}
+
+// MergeLatencyHistogramMaps merges histograms from src into dst by key and
+// returns dst.
+func MergeLatencyHistogramMaps[K comparable](dst, src map[K]*types.LatencyHistogram) map[K]*types.LatencyHistogram {
+	for k, h := range src {
+		if dst == nil {
+			dst = make(map[K]*types.LatencyHistogram, len(src))
+		}
+		dst[k] = MergeLatencyHistograms(dst[k], h)
+	}
+	return dst
+}
+
+// BuildPercentileLatenciesFromHistogramMap builds percentile latencies for
+// each histogram in hists. It returns nil if hists is empty.
+func BuildPercentileLatenciesFromHistogramMap[K comparable](hists map[K]*types.LatencyHistogram) map[K][][2]float64 {
+	if len(hists) == 0 {
+		return nil
+	}
+
+	res := make(map[K][][2]float64, len(hists))
+	for k, h := range hists {
+		res[k] = BuildPercentileLatenciesFromHistogram(h)
+	}
+	return res
+}
@@ -0,0 +1,72 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	latencies := make([]float64, 0, 10000)
+	whole, left, right := NewHistogram(), NewHistogram(), NewHistogram()
+	for i := 0; i < 10000; i++ {
+		v := rnd.ExpFloat64() / 10
+		if i%100 == 0 {
+			v = 0
+		}
+		latencies = append(latencies, v)
+
+		whole.Observe(v)
+		if i%2 == 0 {
+			left.Observe(v)
+		} else {
+			right.Observe(v)
+		}
+	}
+
+	// Merge is exact.
+	merged := MergeLatencyHistograms(nil, left.Snapshot())
+	merged = MergeLatencyHistograms(merged, right.Snapshot())
+	assert.Equal(t, whole.Snapshot().Counts, merged.Counts)
+	assert.Equal(t, whole.Snapshot().ZeroCount, merged.ZeroCount)
+	assert.Equal(t, uint64(10000), merged.Count)
+
+	expected := BuildPercentileLatencies(latencies)
+	got := BuildPercentileLatenciesFromHistogram(merged)
+	assert.Len(t, got, len(expected))
+	for i := range expected {
+		assert.Equal(t, expected[i][0], got[i][0])
+		assert.InDelta(t, expected[i][1], got[i][1], expected[i][1]*DefaultRelativeAccuracy, "percentile %v", expected[i][0])
+	}
+
+	// Histogram with different accuracy is merged approximately.
+	coarse := newHistogram(0.05)
+	coarse.Merge(merged)
+	assert.Equal(t, merged.Count, coarse.Count())
+	assert.InEpsilon(t, whole.Quantile(0.99), coarse.Quantile(0.99), 0.1)
+}
+
+func TestSetPercentiles(t *testing.T) {
+	defer func() { percentiles = DefaultPercentiles }()
+
+	assert.Error(t, SetPercentiles([]float64{1.5}))
+
+	assert.NoError(t, SetPercentiles([]float64{0.9999, 0.999, 0.99}))
+	assert.Equal(t, []float64{0.5, 0.99, 0.999, 0.9999}, Percentiles())
+
+	latencies := make([]float64, 0, 10000)
+	for i := 1; i <= 10000; i++ {
+		latencies = append(latencies, float64(i))
+	}
+	got := BuildPercentileLatenciesFromHistogram(HistogramFromLatencies(latencies))
+	assert.Len(t, got, 4)
+	assert.Equal(t, 0.999, got[2][0])
+	assert.InEpsilon(t, 9990, got[2][1], DefaultRelativeAccuracy)
+	assert.Equal(t, BuildPercentileLatencies(latencies)[3], [2]float64{0.9999, 9999})
+}
@@ -0,0 +1,112 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// LiveMetrics exports observations as Prometheus metrics while running so
+// that long runs can be watched before the result is ready. Requests are
+// labeled by verb and resource instead of URL to keep the cardinality low.
+type LiveMetrics struct {
+	registry *prometheus.Registry
+
+	requests      *prometheus.CounterVec
+	errors        *prometheus.CounterVec
+	latencies     *prometheus.HistogramVec
+	receivedBytes prometheus.Counter
+	inflight      prometheus.Gauge
+	limiterWait   prometheus.Histogram
+}
+
+// NewLiveMetrics returns new instance of LiveMetrics.
+func NewLiveMetrics() *LiveMetrics {
+	l := &LiveMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "kperf",
+			Name:      "requests_total",
+			Help:      "Number of finished requests, including failed ones.",
+		}, []string{"verb", "resource"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "kperf",
+			Name:      "request_errors_total",
+			Help:      "Number of failed requests by error type and HTTP code.",
+		}, []string{"verb", "resource", "type", "code"}),
+		latencies: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "kperf",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of succeeded requests.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 15),
+		}, []string{"verb", "resource"}),
+		receivedBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "kperf",
+			Name:      "received_bytes_total",
+			Help:      "Number of bytes read from apiserver.",
+		}),
+		inflight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "kperf",
+			Name:      "inflight_requests",
+			Help:      "Number of requests waiting for response.",
+		}),
+		limiterWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "kperf",
+			Name:      "rate_limiter_wait_seconds",
+			Help:      "Time spent waiting for rate limiters before sending request.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+		}),
+	}
+	l.registry.MustRegister(l.requests, l.errors, l.latencies,
+		l.receivedBytes, l.inflight, l.limiterWait)
+	return l
+}
+
+// Handler returns the handler serving metrics in Prometheus text format.
+func (l *LiveMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(l.registry, promhttp.HandlerOpts{})
+}
+
+// Gather returns the current value of metrics.
+func (l *LiveMetrics) Gather() ([]*dto.MetricFamily, error) {
+	return l.registry.Gather()
+}
+
+// ObserveInflight adds delta to the number of in-flight requests.
+func (l *LiveMetrics) ObserveInflight(delta int) {
+	l.inflight.Add(float64(delta))
+}
+
+// ObserveLimiterWait observes the time spent waiting for rate limiters.
+func (l *LiveMetrics) ObserveLimiterWait(seconds float64) {
+	l.limiterWait.Observe(seconds)
+}
+
+func (l *LiveMetrics) observeLatency(method, url string, seconds float64) {
+	cls := ClassifyRequest(method, url)
+	l.requests.WithLabelValues(cls.Verb, cls.Resource).Inc()
+	l.latencies.WithLabelValues(cls.Verb, cls.Resource).Observe(seconds)
+}
+
+func (l *LiveMetrics) observeFailure(err types.ResponseError) {
+	cls := ClassifyRequest(err.Method, err.URL)
+	l.requests.WithLabelValues(cls.Verb, cls.Resource).Inc()
+
+	code := ""
+	if err.Code != 0 {
+		code = strconv.Itoa(err.Code)
+	}
+	l.errors.WithLabelValues(cls.Verb, cls.Resource, string(err.Type), code).Inc()
+}
+
+func (l *LiveMetrics) observeReceivedBytes(bytes int64) {
+	l.receivedBytes.Add(float64(bytes))
+}
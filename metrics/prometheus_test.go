@@ -0,0 +1,47 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestLiveMetrics(t *testing.T) {
+	live := NewLiveMetrics()
+	m := NewResponseMetric(WithLiveMetricsOpt(live))
+
+	m.ObserveLatency("GET", "/api/v1/pods", 0.1)
+	m.ObserveFailure("GET", "/api/v1/pods", time.Now(), 1, apierrors.NewTooManyRequests("slow down", 1))
+	m.ObserveReceivedBytes("GET", "/api/v1/pods", 100)
+	live.ObserveInflight(1)
+	live.ObserveLimiterWait(0.5)
+
+	srv := httptest.NewServer(live.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	for _, line := range []string{
+		`kperf_requests_total{resource="pods",verb="LIST"} 2`,
+		`kperf_request_errors_total{code="429",resource="pods",type="http",verb="LIST"} 1`,
+		`kperf_request_duration_seconds_count{resource="pods",verb="LIST"} 1`,
+		`kperf_received_bytes_total 100`,
+		`kperf_inflight_requests 1`,
+		`kperf_rate_limiter_wait_seconds_count 1`,
+	} {
+		assert.Contains(t, string(data), line)
+	}
+}
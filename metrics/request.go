@@ -4,10 +4,9 @@
 package metrics
 
 import (
-	"container/list"
 	"fmt"
+	"strconv"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/Azure/kperf/api/types"
@@ -15,42 +14,299 @@ import (
 
 // ResponseMetric is a measurement related to http response.
 type ResponseMetric interface {
+	// ObserveDispatch observes request sent to apiserver.
+	ObserveDispatch(method string, url string)
 	// ObserveLatency observes latency.
 	ObserveLatency(method string, url string, seconds float64)
 	// ObserveFailure observes failure response.
 	ObserveFailure(method string, url string, now time.Time, seconds float64, err error)
 	// ObserveReceivedBytes observes the bytes read from apiserver.
-	ObserveReceivedBytes(bytes int64)
+	ObserveReceivedBytes(method string, url string, bytes int64)
+	// ObserveLimiterWait observes the time spent waiting for rate limiters
+	// before sending request.
+	ObserveLimiterWait(seconds float64)
+	// ObserveResponse observes the status code of the last response and
+	// the number of client-go retries of succeeded request.
+	ObserveResponse(statusCode int, retries int, seconds float64)
+	// ObservePhaseLatency observes latency of HTTP request phase.
+	ObservePhaseLatency(phase types.RequestPhase, seconds float64)
+	// ObserveDecode observes decoding response. The items is the number of
+	// items if the response is a list, or negative.
+	ObserveDecode(method string, url string, seconds float64, allocatedBytes uint64, items int)
+	// ObserveFlowControl observes how API Priority and Fairness handled the
+	// request. The seconds is only observed if the request succeeded.
+	ObserveFlowControl(obs FlowControlObservation, seconds float64, failed bool)
 	// Gather returns the summary.
 	Gather() types.ResponseStats
 }
 
+// ResponseMetricOpt is used to update default response metric setting.
+type ResponseMetricOpt func(*responseMetricImpl)
+
+// WithRawSamplesOpt keeps every observed latency besides histograms. It
+// takes memory in proportion to the number of requests.
+func WithRawSamplesOpt(keep bool) ResponseMetricOpt {
+	return func(m *responseMetricImpl) {
+		m.keepRaw = keep
+	}
+}
+
+// WithTimeSeriesOpt buckets observations by the given interval besides the
+// whole run. Zero disables it.
+func WithTimeSeriesOpt(interval time.Duration) ResponseMetricOpt {
+	return func(m *responseMetricImpl) {
+		m.series = nil
+		if interval > 0 {
+			m.series = newTimeSeriesRecorder(interval)
+		}
+	}
+}
+
+// WithErrorSampleSizeOpt sets the maximum number of error records kept in
+// sample. The errors are still counted by class beyond it.
+func WithErrorSampleSizeOpt(size int) ResponseMetricOpt {
+	return func(m *responseMetricImpl) {
+		m.errors = newErrorRecorder(max(size, 0))
+	}
+}
+
+// WithLiveMetricsOpt exports observations to live as well.
+func WithLiveMetricsOpt(live *LiveMetrics) ResponseMetricOpt {
+	return func(m *responseMetricImpl) {
+		m.live = live
+	}
+}
+
 type responseMetricImpl struct {
 	mu              sync.Mutex
-	errors          *list.List
+	keepRaw         bool
+	series          *timeSeriesRecorder
+	live            *LiveMetrics
+	errors          *errorRecorder
 	receivedBytes   int64
-	latenciesByURLs map[string]*list.List
+	counts          map[string]*types.RequestCounts
+	limiterWait     types.LimiterWaitReport
+	latenciesByURLs map[string]*latencyRecorder
+	phaseLatencies  map[types.RequestPhase]*latencyRecorder
+	// outcomeLatencies is keyed by HTTP status code, or error type if
+	// there is no response.
+	outcomeLatencies map[string]*Histogram
+	// retriedLatencies is keyed by the number of retries.
+	retriedLatencies map[int]*Histogram
+	decodeByURLs     map[string]*types.DecodeReport
+	decodeLatencies  map[string]*latencyRecorder
+	flowSchemas      map[string]*types.FlowControlReport
+	priorityLevels   map[string]*types.FlowControlReport
+	// flowControlLatencies is keyed by the pointer of report in
+	// flowSchemas or priorityLevels.
+	flowControlLatencies map[*types.FlowControlReport]*latencyRecorder
 }
 
-func NewResponseMetric() ResponseMetric {
-	return &responseMetricImpl{
-		errors:          list.New(),
-		latenciesByURLs: map[string]*list.List{},
+// latencyRecorder records latencies into histogram, and raw samples if
+// they're kept.
+type latencyRecorder struct {
+	hist    *Histogram
+	keepRaw bool
+	raw     []float64
+}
+
+func newLatencyRecorder(keepRaw bool) *latencyRecorder {
+	return &latencyRecorder{hist: NewHistogram(), keepRaw: keepRaw}
+}
+
+func (r *latencyRecorder) observe(seconds float64) {
+	r.hist.Observe(seconds)
+	if r.keepRaw {
+		r.raw = append(r.raw, seconds)
 	}
 }
 
+// rawSamples returns the copy of raw samples, or nil if they aren't kept.
+func (r *latencyRecorder) rawSamples() []float64 {
+	if !r.keepRaw {
+		return nil
+	}
+	return append(make([]float64, 0, len(r.raw)), r.raw...)
+}
+
+// FlowControlObservation is what one request observed from API Priority
+// and Fairness, including retries.
+type FlowControlObservation struct {
+	// FlowSchema is the UID of the flow schema in the last response.
+	FlowSchema string
+	// PriorityLevel is the UID of the priority level in the last response.
+	PriorityLevel string
+	// Throttled is the number of 429 responses.
+	Throttled int
+	// Retries is the number of retries.
+	Retries int
+	// RetryAfterSeconds is the maximum Retry-After in 429 responses.
+	RetryAfterSeconds int
+}
+
+// NewResponseMetric returns new instance of ResponseMetric. The latencies
+// are recorded into histograms, which take constant memory.
+func NewResponseMetric(opts ...ResponseMetricOpt) ResponseMetric {
+	m := &responseMetricImpl{
+		errors:               newErrorRecorder(DefaultErrorSampleSize),
+		counts:               map[string]*types.RequestCounts{},
+		latenciesByURLs:      map[string]*latencyRecorder{},
+		phaseLatencies:       map[types.RequestPhase]*latencyRecorder{},
+		outcomeLatencies:     map[string]*Histogram{},
+		retriedLatencies:     map[int]*Histogram{},
+		decodeByURLs:         map[string]*types.DecodeReport{},
+		decodeLatencies:      map[string]*latencyRecorder{},
+		flowSchemas:          map[string]*types.FlowControlReport{},
+		priorityLevels:       map[string]*types.FlowControlReport{},
+		flowControlLatencies: map[*types.FlowControlReport]*latencyRecorder{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// ObserveDispatch implements ResponseMetric.
+func (m *responseMetricImpl) ObserveDispatch(method string, url string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.countsLocked(fmt.Sprintf("%s %s", method, url)).Dispatched++
+}
+
+// countsLocked returns the counts of key. The caller must hold m.mu.
+func (m *responseMetricImpl) countsLocked(key string) *types.RequestCounts {
+	c, ok := m.counts[key]
+	if !ok {
+		c = &types.RequestCounts{}
+		m.counts[key] = c
+	}
+	return c
+}
+
 // ObserveLatency implements ResponseMetric.
 func (m *responseMetricImpl) ObserveLatency(method string, url string, seconds float64) {
+	if m.live != nil {
+		m.live.observeLatency(method, url, seconds)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	key := fmt.Sprintf("%s %s", method, url)
+	m.countsLocked(key).Succeeded++
+
 	l, ok := m.latenciesByURLs[key]
 	if !ok {
-		m.latenciesByURLs[key] = list.New()
-		l = m.latenciesByURLs[key]
+		l = newLatencyRecorder(m.keepRaw)
+		m.latenciesByURLs[key] = l
+	}
+	l.observe(seconds)
+
+	if m.series != nil {
+		m.series.observeLatency(time.Now(), key, seconds)
+	}
+}
+
+// ObserveResponse implements ResponseMetric.
+func (m *responseMetricImpl) ObserveResponse(statusCode int, retries int, seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if statusCode > 0 {
+		observeHistogram(m.outcomeLatencies, strconv.Itoa(statusCode), seconds)
+	}
+	if retries > 0 {
+		observeHistogram(m.retriedLatencies, retries, seconds)
+	}
+}
+
+// observeHistogram observes v into the histogram of key in hists.
+func observeHistogram[K comparable](hists map[K]*Histogram, key K, v float64) {
+	h, ok := hists[key]
+	if !ok {
+		h = NewHistogram()
+		hists[key] = h
+	}
+	h.Observe(v)
+}
+
+// outcomeOf returns the key of failed request in outcomeLatencies.
+func outcomeOf(err types.ResponseError) string {
+	if err.Type == types.ResponseErrorTypeHTTP {
+		return strconv.Itoa(err.Code)
+	}
+	return string(err.Type)
+}
+
+// ObservePhaseLatency implements ResponseMetric.
+func (m *responseMetricImpl) ObservePhaseLatency(phase types.RequestPhase, seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.phaseLatencies[phase]
+	if !ok {
+		l = newLatencyRecorder(m.keepRaw)
+		m.phaseLatencies[phase] = l
+	}
+	l.observe(seconds)
+}
+
+// ObserveDecode implements ResponseMetric.
+func (m *responseMetricImpl) ObserveDecode(method string, url string, seconds float64, allocatedBytes uint64, items int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := fmt.Sprintf("%s %s", method, url)
+	r, ok := m.decodeByURLs[key]
+	if !ok {
+		r = &types.DecodeReport{}
+		m.decodeByURLs[key] = r
+		m.decodeLatencies[key] = newLatencyRecorder(m.keepRaw)
+	}
+	r.Responses++
+	r.AllocatedBytes += allocatedBytes
+	m.decodeLatencies[key].observe(seconds)
+	if items >= 0 {
+		if r.Lists == 0 || items < r.MinItems {
+			r.MinItems = items
+		}
+		if items > r.MaxItems {
+			r.MaxItems = items
+		}
+		r.Lists++
+		r.TotalItems += int64(items)
+	}
+}
+
+// ObserveFlowControl implements ResponseMetric.
+func (m *responseMetricImpl) ObserveFlowControl(obs FlowControlObservation, seconds float64, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	observe := func(reports map[string]*types.FlowControlReport, uid string) {
+		if uid == "" {
+			return
+		}
+
+		r, ok := reports[uid]
+		if !ok {
+			r = &types.FlowControlReport{UID: uid}
+			reports[uid] = r
+			m.flowControlLatencies[r] = newLatencyRecorder(m.keepRaw)
+		}
+		r.Requests++
+		r.Throttled += obs.Throttled
+		r.Retries += obs.Retries
+		r.MaxRetryAfterSeconds = max(r.MaxRetryAfterSeconds, obs.RetryAfterSeconds)
+		if failed {
+			r.Failures++
+			return
+		}
+		m.flowControlLatencies[r].observe(seconds)
 	}
-	l.PushBack(seconds)
+	observe(m.flowSchemas, obs.FlowSchema)
+	observe(m.priorityLevels, obs.PriorityLevel)
 }
 
 // ObserveFailure implements ResponseMetric.
@@ -59,9 +315,25 @@ func (m *responseMetricImpl) ObserveFailure(method string, url string, now time.
 		return
 	}
 
+	oerr := newResponseError(method, url, now, seconds, err)
+	if m.live != nil {
+		m.live.observeFailure(oerr)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	key := fmt.Sprintf("%s %s", method, url)
+	m.countsLocked(key).Failed++
+	m.errors.observe(oerr)
+	observeHistogram(m.outcomeLatencies, outcomeOf(oerr), seconds)
+	if m.series != nil {
+		m.series.observeFailure(now, key, oerr)
+	}
+}
+
+// newResponseError categorizes err into types.ResponseError.
+func newResponseError(method string, url string, now time.Time, seconds float64, err error) types.ResponseError {
 	oerr := types.ResponseError{
 		Method:    method,
 		URL:       url,
@@ -69,17 +341,25 @@ func (m *responseMetricImpl) ObserveFailure(method string, url string, now time.
 		Duration:  seconds,
 	}
 
-	// HTTP Code -> HTTP2 -> Connection -> Unknown
+	// Assertion -> HTTP Code -> HTTP2 -> Client Timeout -> Connection -> Unknown
+	assertionErr, isAssertionErr := isAssertionError(err)
 	code := codeFromHTTP(err)
 	http2Err, isHTTP2Err := isHTTP2Error(err)
+	timeoutErr, isTimeoutErr := isClientTimeoutError(err)
 	connErr, isConnErr := isConnectionError(err)
 	switch {
+	case isAssertionErr:
+		oerr.Type = types.ResponseErrorTypeAssertion
+		oerr.Message = assertionErr
 	case code != 0:
 		oerr.Type = types.ResponseErrorTypeHTTP
 		oerr.Code = code
 	case isHTTP2Err:
 		oerr.Type = types.ResponseErrorTypeHTTP2Protocol
 		oerr.Message = http2Err
+	case isTimeoutErr:
+		oerr.Type = types.ResponseErrorTypeClientTimeout
+		oerr.Message = timeoutErr
 	case isConnErr:
 		oerr.Type = types.ResponseErrorTypeConnection
 		oerr.Message = connErr
@@ -87,45 +367,172 @@ func (m *responseMetricImpl) ObserveFailure(method string, url string, now time.
 		oerr.Type = types.ResponseErrorTypeUnknown
 		oerr.Message = err.Error()
 	}
-	m.errors.PushBack(oerr)
+	return oerr
 }
 
 // ObserveReceivedBytes implements ResponseMetric.
-func (m *responseMetricImpl) ObserveReceivedBytes(bytes int64) {
-	atomic.AddInt64(&m.receivedBytes, bytes)
+func (m *responseMetricImpl) ObserveReceivedBytes(method string, url string, bytes int64) {
+	if m.live != nil {
+		m.live.observeReceivedBytes(bytes)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.receivedBytes += bytes
+	m.countsLocked(fmt.Sprintf("%s %s", method, url)).ReceivedBytes += bytes
+	if m.series != nil {
+		m.series.observeReceivedBytes(time.Now(), bytes)
+	}
+}
+
+// ObserveLimiterWait implements ResponseMetric.
+func (m *responseMetricImpl) ObserveLimiterWait(seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.limiterWait.Waits++
+	m.limiterWait.TotalSeconds += seconds
 }
 
 // Gather implements ResponseMetric.
 func (m *responseMetricImpl) Gather() types.ResponseStats {
-	return types.ResponseStats{
-		Errors:             m.dumpErrors(),
-		LatenciesByURL:     m.dumpLatencies(),
-		TotalReceivedBytes: atomic.LoadInt64(&m.receivedBytes),
+	stats := types.ResponseStats{
+		DecodeByURL:     m.dumpDecodeReports(),
+		ByFlowSchema:    m.dumpFlowControlReports(m.flowSchemas),
+		ByPriorityLevel: m.dumpFlowControlReports(m.priorityLevels),
 	}
+	stats.TotalReceivedBytes, stats.CountsByURL, stats.LimiterWait = m.dumpCounts()
+	stats.Errors, stats.ErrorClasses = m.dumpErrors()
+	stats.LatencyHistogramsByOutcome, stats.LatencyHistogramsByRetries = m.dumpOutcomeLatencies()
+	stats.LatencyHistogramsByURL, stats.LatenciesByURL = m.dumpLatencies()
+	stats.PhaseLatencyHistograms, stats.PhaseLatencies = m.dumpPhaseLatencies()
+	stats.TimeSeries = m.dumpTimeSeries()
+	return stats
 }
 
-func (m *responseMetricImpl) dumpLatencies() map[string][]float64 {
+func (m *responseMetricImpl) dumpCounts() (int64, map[string]*types.RequestCounts, *types.LimiterWaitReport) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	res := make(map[string][]float64)
-	for u, latencies := range m.latenciesByURLs {
-		res[u] = make([]float64, 0, latencies.Len())
+	counts := make(map[string]*types.RequestCounts, len(m.counts))
+	for u, c := range m.counts {
+		copied := *c
+		counts[u] = &copied
+	}
 
-		for e := latencies.Front(); e != nil; e = e.Next() {
-			res[u] = append(res[u], e.Value.(float64))
-		}
+	var limiterWait *types.LimiterWaitReport
+	if m.limiterWait.Waits > 0 {
+		limiterWait = MergeLimiterWaitReports(nil, &m.limiterWait)
+	}
+	return m.receivedBytes, counts, limiterWait
+}
+
+func (m *responseMetricImpl) dumpOutcomeLatencies() (map[string]*types.LatencyHistogram, map[int]*types.LatencyHistogram) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return snapshotHistograms(m.outcomeLatencies), snapshotHistograms(m.retriedLatencies)
+}
+
+// snapshotHistograms returns the snapshot of hists, or nil if it's empty.
+func snapshotHistograms[K comparable](hists map[K]*Histogram) map[K]*types.LatencyHistogram {
+	if len(hists) == 0 {
+		return nil
+	}
+
+	res := make(map[K]*types.LatencyHistogram, len(hists))
+	for k, h := range hists {
+		res[k] = h.Snapshot()
 	}
 	return res
 }
 
-func (m *responseMetricImpl) dumpErrors() []types.ResponseError {
+func (m *responseMetricImpl) dumpTimeSeries() *types.TimeSeries {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.series == nil {
+		return nil
+	}
+	return m.series.dump()
+}
+
+func (m *responseMetricImpl) dumpFlowControlReports(reports map[string]*types.FlowControlReport) map[string]*types.FlowControlReport {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	res := make([]types.ResponseError, 0, m.errors.Len())
-	for e := m.errors.Front(); e != nil; e = e.Next() {
-		res = append(res, e.Value.(types.ResponseError))
+	if len(reports) == 0 {
+		return nil
+	}
+
+	res := make(map[string]*types.FlowControlReport, len(reports))
+	for uid, r := range reports {
+		copied := *r
+		copied.Histogram = m.flowControlLatencies[r].hist.Snapshot()
+		copied.Latencies = m.flowControlLatencies[r].rawSamples()
+		res[uid] = &copied
 	}
 	return res
 }
+
+func (m *responseMetricImpl) dumpDecodeReports() map[string]*types.DecodeReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.decodeByURLs) == 0 {
+		return nil
+	}
+
+	res := make(map[string]*types.DecodeReport, len(m.decodeByURLs))
+	for u, r := range m.decodeByURLs {
+		copied := *r
+		copied.Histogram = m.decodeLatencies[u].hist.Snapshot()
+		copied.Latencies = m.decodeLatencies[u].rawSamples()
+		res[u] = &copied
+	}
+	return res
+}
+
+func (m *responseMetricImpl) dumpPhaseLatencies() (map[types.RequestPhase]*types.LatencyHistogram, map[types.RequestPhase][]float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hists := make(map[types.RequestPhase]*types.LatencyHistogram, len(m.phaseLatencies))
+	var raw map[types.RequestPhase][]float64
+	for phase, l := range m.phaseLatencies {
+		hists[phase] = l.hist.Snapshot()
+		if m.keepRaw {
+			if raw == nil {
+				raw = make(map[types.RequestPhase][]float64, len(m.phaseLatencies))
+			}
+			raw[phase] = l.rawSamples()
+		}
+	}
+	return hists, raw
+}
+
+func (m *responseMetricImpl) dumpLatencies() (map[string]*types.LatencyHistogram, map[string][]float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hists := make(map[string]*types.LatencyHistogram, len(m.latenciesByURLs))
+	var raw map[string][]float64
+	for u, l := range m.latenciesByURLs {
+		hists[u] = l.hist.Snapshot()
+		if m.keepRaw {
+			if raw == nil {
+				raw = make(map[string][]float64, len(m.latenciesByURLs))
+			}
+			raw[u] = l.rawSamples()
+		}
+	}
+	return hists, raw
+}
+
+func (m *responseMetricImpl) dumpErrors() ([]types.ResponseError, []types.ResponseErrorClass) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.errors.dumpSample(), m.errors.dumpClasses()
+}
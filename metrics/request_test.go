@@ -8,6 +8,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/url"
 	"syscall"
 	"testing"
 	"time"
@@ -109,7 +111,7 @@ func TestResponseMetric_ObserveFailure(t *testing.T) {
 			URL:       "10",
 			Timestamp: observedAt,
 			Duration:  dur.Seconds(),
-			Type:      types.ResponseErrorTypeConnection,
+			Type:      types.ResponseErrorTypeClientTimeout,
 			Message:   "context deadline exceeded",
 		},
 		{
@@ -141,6 +143,22 @@ func TestResponseMetric_ObserveFailure(t *testing.T) {
 			URL:       "14",
 			Timestamp: observedAt,
 			Duration:  dur.Seconds(),
+			Type:      types.ResponseErrorTypeClientTimeout,
+			Message:   "context deadline exceeded",
+		},
+		{
+			Method:    "GET",
+			URL:       "15",
+			Timestamp: observedAt,
+			Duration:  dur.Seconds(),
+			Type:      types.ResponseErrorTypeConnection,
+			Message:   "dial tcp: i/o timeout",
+		},
+		{
+			Method:    "GET",
+			URL:       "16",
+			Timestamp: observedAt,
+			Duration:  dur.Seconds(),
 			Type:      types.ResponseErrorTypeUnknown,
 			Message:   "unknown",
 		},
@@ -175,6 +193,8 @@ func TestResponseMetric_ObserveFailure(t *testing.T) {
 		fmt.Errorf("oops: %w", syscall.ECONNRESET),
 		fmt.Errorf("oops: %w", syscall.ECONNREFUSED),
 		fmt.Errorf("oops: %w", io.ErrUnexpectedEOF),
+		&url.Error{Op: "Get", URL: "https://localhost/api/v1/pods?timeout=1s", Err: context.DeadlineExceeded},
+		&net.OpError{Op: "dial", Net: "tcp", Err: &timeoutNetError{}},
 		// unknown
 		fmt.Errorf("unknown"),
 	}
@@ -186,3 +206,36 @@ func TestResponseMetric_ObserveFailure(t *testing.T) {
 	errors := m.Gather().Errors
 	assert.Equal(t, expectedErrors, errors)
 }
+
+// timeoutNetError is a net.Error which is timeout.
+type timeoutNetError struct{}
+
+func (*timeoutNetError) Error() string   { return "i/o timeout" }
+func (*timeoutNetError) Timeout() bool   { return true }
+func (*timeoutNetError) Temporary() bool { return true }
+
+func TestResponseMetricOutcomeLatencies(t *testing.T) {
+	m := NewResponseMetric()
+	m.ObserveResponse(200, 0, 0.1)
+	m.ObserveResponse(200, 2, 3)
+	m.ObserveResponse(0, 0, 0.1)
+	m.ObserveFailure("GET", "/api/v1/pods", time.Now(), 5, apierrors.NewTooManyRequests("slow down", 1))
+	m.ObserveFailure("GET", "/api/v1/pods", time.Now(), 0.01, apierrors.NewInternalError(errors.New("oops")))
+	m.ObserveFailure("GET", "/api/v1/pods", time.Now(), 30, context.DeadlineExceeded)
+
+	stats := m.Gather()
+	counts := map[string]uint64{}
+	for outcome, h := range stats.LatencyHistogramsByOutcome {
+		counts[outcome] = h.Count
+	}
+	assert.Equal(t, map[string]uint64{
+		"200": 2,
+		"429": 1,
+		"500": 1,
+		string(types.ResponseErrorTypeClientTimeout): 1,
+	}, counts)
+	assert.InEpsilon(t, 5, BuildPercentileLatenciesFromHistogram(stats.LatencyHistogramsByOutcome["429"])[0][1], 0.01)
+
+	assert.Len(t, stats.LatencyHistogramsByRetries, 1)
+	assert.Equal(t, uint64(1), stats.LatencyHistogramsByRetries[2].Count)
+}
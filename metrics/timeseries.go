@@ -0,0 +1,216 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+// timeSeriesRelativeAccuracy is the relative accuracy of histograms in time
+// series. It's coarser than DefaultRelativeAccuracy to keep the report
+// small since there is one histogram per interval.
+const timeSeriesRelativeAccuracy = 0.05
+
+// timeSeriesRecorder buckets observations by interval.
+type timeSeriesRecorder struct {
+	interval time.Duration
+	// points is keyed by the index of interval since Unix epoch.
+	points map[int64]*timeSeriesPoint
+}
+
+type timeSeriesPoint struct {
+	requests   int
+	errorStats map[string]int32
+	bytes      int64
+	hist       *Histogram
+	byURL      map[string]*timeSeriesURLPoint
+}
+
+type timeSeriesURLPoint struct {
+	requests int
+	errors   int
+	hist     *Histogram
+}
+
+func newTimeSeriesRecorder(interval time.Duration) *timeSeriesRecorder {
+	return &timeSeriesRecorder{
+		interval: interval,
+		points:   map[int64]*timeSeriesPoint{},
+	}
+}
+
+// point returns the point of interval which includes t.
+func (r *timeSeriesRecorder) point(t time.Time) *timeSeriesPoint {
+	idx := t.UnixNano() / int64(r.interval)
+	p, ok := r.points[idx]
+	if !ok {
+		p = &timeSeriesPoint{byURL: map[string]*timeSeriesURLPoint{}}
+		r.points[idx] = p
+	}
+	return p
+}
+
+// urlPoint returns the point of request in interval which includes t.
+func (r *timeSeriesRecorder) urlPoint(t time.Time, key string) *timeSeriesURLPoint {
+	p := r.point(t)
+	up, ok := p.byURL[key]
+	if !ok {
+		up = &timeSeriesURLPoint{}
+		p.byURL[key] = up
+	}
+	return up
+}
+
+func (r *timeSeriesRecorder) observeLatency(t time.Time, key string, seconds float64) {
+	p := r.point(t)
+	p.requests++
+	if p.hist == nil {
+		p.hist = newHistogram(timeSeriesRelativeAccuracy)
+	}
+	p.hist.Observe(seconds)
+
+	up := r.urlPoint(t, key)
+	up.requests++
+	if up.hist == nil {
+		up.hist = newHistogram(timeSeriesRelativeAccuracy)
+	}
+	up.hist.Observe(seconds)
+}
+
+func (r *timeSeriesRecorder) observeFailure(t time.Time, key string, err types.ResponseError) {
+	p := r.point(t)
+	p.requests++
+	if p.errorStats == nil {
+		p.errorStats = map[string]int32{}
+	}
+	p.errorStats[errorStatKey(err)]++
+
+	up := r.urlPoint(t, key)
+	up.requests++
+	up.errors++
+}
+
+func (r *timeSeriesRecorder) observeReceivedBytes(t time.Time, bytes int64) {
+	r.point(t).bytes += bytes
+}
+
+// dump returns the series with percentile latencies.
+func (r *timeSeriesRecorder) dump() *types.TimeSeries {
+	idxs := make([]int64, 0, len(r.points))
+	for idx := range r.points {
+		idxs = append(idxs, idx)
+	}
+	sort.Slice(idxs, func(i, j int) bool { return idxs[i] < idxs[j] })
+
+	ts := &types.TimeSeries{
+		IntervalSeconds: r.interval.Seconds(),
+		Points:          make([]types.TimeSeriesPoint, 0, len(idxs)),
+	}
+	for _, idx := range idxs {
+		p := r.points[idx]
+
+		point := types.TimeSeriesPoint{
+			Timestamp:     time.Unix(0, idx*int64(r.interval)).UTC(),
+			Requests:      p.requests,
+			ReceivedBytes: p.bytes,
+		}
+		if len(p.errorStats) > 0 {
+			point.ErrorStats = make(map[string]int32, len(p.errorStats))
+			for k, v := range p.errorStats {
+				point.ErrorStats[k] = v
+			}
+		}
+		if p.hist != nil {
+			point.Histogram = p.hist.Snapshot()
+			point.PercentileLatencies = BuildPercentileLatenciesFromHistogram(point.Histogram)
+		}
+		if len(p.byURL) > 0 {
+			point.ByURL = make(map[string]*types.TimeSeriesURLPoint, len(p.byURL))
+			for key, up := range p.byURL {
+				urlPoint := &types.TimeSeriesURLPoint{
+					Requests: up.requests,
+					Errors:   up.errors,
+				}
+				if up.hist != nil {
+					urlPoint.Histogram = up.hist.Snapshot()
+					urlPoint.PercentileLatencies = BuildPercentileLatenciesFromHistogram(urlPoint.Histogram)
+				}
+				point.ByURL[key] = urlPoint
+			}
+		}
+		ts.Points = append(ts.Points, point)
+	}
+	return ts
+}
+
+// MergeTimeSeries merges the points of src into dst by timestamp and
+// returns dst. The percentile latencies of merged points are rebuilt. Both
+// should have the same interval.
+func MergeTimeSeries(dst, src *types.TimeSeries) (*types.TimeSeries, error) {
+	if src == nil {
+		return dst, nil
+	}
+	if dst == nil {
+		dst = &types.TimeSeries{IntervalSeconds: src.IntervalSeconds}
+	}
+	if dst.IntervalSeconds != src.IntervalSeconds {
+		return dst, fmt.Errorf("can't merge time series with interval %vs into %vs",
+			src.IntervalSeconds, dst.IntervalSeconds)
+	}
+
+	merged := append(make([]types.TimeSeriesPoint, 0, len(dst.Points)+len(src.Points)), dst.Points...)
+	idxs := make(map[int64]int, len(merged))
+	for i := range merged {
+		idxs[merged[i].Timestamp.UnixNano()] = i
+	}
+	for i := range src.Points {
+		sp := &src.Points[i]
+		idx, ok := idxs[sp.Timestamp.UnixNano()]
+		if !ok {
+			merged = append(merged, types.TimeSeriesPoint{Timestamp: sp.Timestamp})
+			idx = len(merged) - 1
+			idxs[sp.Timestamp.UnixNano()] = idx
+		}
+		mergeTimeSeriesPoint(&merged[idx], sp)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	})
+	dst.Points = merged
+	return dst, nil
+}
+
+// mergeTimeSeriesPoint merges src into dst and rebuilds percentiles.
+func mergeTimeSeriesPoint(dst, src *types.TimeSeriesPoint) {
+	dst.Requests += src.Requests
+	dst.ReceivedBytes += src.ReceivedBytes
+	for k, v := range src.ErrorStats {
+		if dst.ErrorStats == nil {
+			dst.ErrorStats = map[string]int32{}
+		}
+		dst.ErrorStats[k] += v
+	}
+	dst.Histogram = MergeLatencyHistograms(dst.Histogram, src.Histogram)
+	dst.PercentileLatencies = BuildPercentileLatenciesFromHistogram(dst.Histogram)
+
+	for key, sup := range src.ByURL {
+		if dst.ByURL == nil {
+			dst.ByURL = map[string]*types.TimeSeriesURLPoint{}
+		}
+		dup, ok := dst.ByURL[key]
+		if !ok {
+			dup = &types.TimeSeriesURLPoint{}
+			dst.ByURL[key] = dup
+		}
+		dup.Requests += sup.Requests
+		dup.Errors += sup.Errors
+		dup.Histogram = MergeLatencyHistograms(dup.Histogram, sup.Histogram)
+		dup.PercentileLatencies = BuildPercentileLatenciesFromHistogram(dup.Histogram)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestResponseMetricTimeSeries(t *testing.T) {
+	m := NewResponseMetric(WithTimeSeriesOpt(time.Minute))
+	m.ObserveLatency("GET", "/api/v1/pods", 0.1)
+	m.ObserveLatency("GET", "/api/v1/pods", 0.2)
+	m.ObserveReceivedBytes("GET", "/api/v1/pods", 100)
+	m.ObserveFailure("GET", "/api/v1/nodes", time.Now(), 1, apierrors.NewTooManyRequests("slow down", 1))
+
+	ts := m.Gather().TimeSeries
+	require.NotNil(t, ts)
+	assert.Equal(t, float64(60), ts.IntervalSeconds)
+
+	// Observations might cross the minute boundary.
+	requests, bytes, errs := 0, int64(0), int32(0)
+	for _, p := range ts.Points {
+		assert.Equal(t, p.Timestamp, p.Timestamp.Truncate(time.Minute))
+		requests += p.Requests
+		bytes += p.ReceivedBytes
+		errs += p.ErrorStats[fmt.Sprintf("%s/%d", types.ResponseErrorTypeHTTP, 429)]
+	}
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, int64(100), bytes)
+	assert.Equal(t, int32(1), errs)
+
+	assert.Nil(t, NewResponseMetric().Gather().TimeSeries)
+}
+
+func TestMergeTimeSeries(t *testing.T) {
+	t0 := time.Unix(1700000000, 0).UTC()
+	t1 := t0.Add(time.Second)
+
+	newPoint := func(ts time.Time, latencies ...float64) types.TimeSeriesPoint {
+		h := HistogramFromLatencies(latencies)
+		return types.TimeSeriesPoint{
+			Timestamp: ts,
+			Requests:  len(latencies),
+			Histogram: h,
+			ByURL: map[string]*types.TimeSeriesURLPoint{
+				"GET /api/v1/pods": {Requests: len(latencies), Histogram: h},
+			},
+		}
+	}
+
+	a := &types.TimeSeries{IntervalSeconds: 1, Points: []types.TimeSeriesPoint{newPoint(t1, 0.1)}}
+	b := &types.TimeSeries{IntervalSeconds: 1, Points: []types.TimeSeriesPoint{newPoint(t0, 0.2), newPoint(t1, 0.3, 0.4)}}
+
+	merged, err := MergeTimeSeries(nil, a)
+	require.NoError(t, err)
+	merged, err = MergeTimeSeries(merged, b)
+	require.NoError(t, err)
+
+	require.Len(t, merged.Points, 2)
+	assert.Equal(t, t0, merged.Points[0].Timestamp)
+	assert.Equal(t, 1, merged.Points[0].Requests)
+	assert.Equal(t, t1, merged.Points[1].Timestamp)
+	assert.Equal(t, 3, merged.Points[1].Requests)
+	assert.Equal(t, 3, merged.Points[1].ByURL["GET /api/v1/pods"].Requests)
+	assert.Equal(t, uint64(3), merged.Points[1].Histogram.Count)
+	assert.Equal(t, 0.4, merged.Points[1].PercentileLatencies[len(merged.Points[1].PercentileLatencies)-1][1])
+
+	// The source isn't changed.
+	assert.Equal(t, 1, a.Points[0].Requests)
+
+	_, err = MergeTimeSeries(merged, &types.TimeSeries{IntervalSeconds: 10})
+	assert.Error(t, err)
+}
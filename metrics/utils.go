@@ -4,6 +4,7 @@
 package metrics
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -25,8 +26,6 @@ func BuildPercentileLatencies(latencies []float64) [][2]float64 {
 		return nil
 	}
 
-	var percentiles = []float64{0, 0.5, 0.90, 0.95, 0.99, 1}
-
 	res := make([][2]float64, len(percentiles))
 
 	n := len(latencies)
@@ -46,18 +45,22 @@ func BuildErrorStatsGroupByType(errors []types.ResponseError) map[string]int32 {
 	res := map[string]int32{}
 
 	for _, err := range errors {
-		var key string
-		switch err.Type {
-		case types.ResponseErrorTypeHTTP:
-			key = fmt.Sprintf("%s/%d", err.Type, err.Code)
-		default:
-			key = fmt.Sprintf("%s/%s", err.Type, err.Message)
-		}
-		res[key]++
+		res[errorStatKey(err)]++
 	}
 	return res
 }
 
+// errorStatKey returns the key of error in error stats. The variable parts
+// of message are masked.
+func errorStatKey(err types.ResponseError) string {
+	switch err.Type {
+	case types.ResponseErrorTypeHTTP:
+		return fmt.Sprintf("%s/%d", err.Type, err.Code)
+	default:
+		return fmt.Sprintf("%s/%s", err.Type, errorMessageClass(err.Message))
+	}
+}
+
 var (
 	// errHTTP2ClientConnectionLost is used to track unexported http2 error.
 	errHTTP2ClientConnectionLost = errors.New("http2: client connection lost")
@@ -111,6 +114,27 @@ func codeFromHTTP(err error) int {
 	}
 }
 
+// AssertionFailure is implemented by errors which mean that the response
+// didn't match the expectation.
+type AssertionFailure interface {
+	error
+	// AssertionFailure returns the short description about mismatch.
+	AssertionFailure() string
+}
+
+// isAssertionError returns true if it's AssertionFailure.
+func isAssertionError(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+
+	var failure AssertionFailure
+	if errors.As(err, &failure) {
+		return failure.AssertionFailure(), true
+	}
+	return "", false
+}
+
 // isHTTP2Error returns true if it's related to http2 error.
 func isHTTP2Error(err error) (string, bool) {
 	if err == nil {
@@ -136,6 +160,33 @@ func isHTTP2Error(err error) (string, bool) {
 	return "", false
 }
 
+// isClientTimeoutError returns true if the request didn't finish before
+// client-side timeout. The timeouts of dial and TLS handshake are treated as
+// connection errors.
+func isClientTimeoutError(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+
+	if strings.Contains(err.Error(), errTLSHandshakeTimeout.Error()) {
+		return "", false
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return "", false
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return context.DeadlineExceeded.Error(), true
+	case isTimeoutError(err):
+		return err.Error(), true
+	default:
+		return "", false
+	}
+}
+
 // isConnectionError returns true if it's related to connection error.
 func isConnectionError(err error) (string, bool) {
 	if err == nil {
@@ -0,0 +1,426 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/metrics"
+)
+
+// LoadReport reads RunnerMetricReport from file written by `kperf runner
+// run`, `kperf runnergroup result` or `runkperf bench`. The aggregated report
+// is used for multiple specs.
+func LoadReport(path string) (*types.RunnerMetricReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report %s: %w", path, err)
+	}
+
+	var wrapped struct {
+		// Aggregated is set by MultiSpecRunnerMetricReport.
+		Aggregated *types.RunnerMetricReport `json:"aggregated"`
+		// Result is set by runkperf's BenchmarkReport.
+		Result *types.RunnerMetricReport `json:"result"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, fmt.Errorf("failed to decode report %s: %w", path, err)
+	}
+
+	r := wrapped.Aggregated
+	if r == nil {
+		r = wrapped.Result
+	}
+	if r == nil {
+		r = &types.RunnerMetricReport{}
+		if err := json.Unmarshal(data, r); err != nil {
+			return nil, fmt.Errorf("failed to decode report %s: %w", path, err)
+		}
+	}
+	if r.Duration == "" {
+		return nil, fmt.Errorf("%s isn't a runner or benchmark report", path)
+	}
+	return r, nil
+}
+
+// Thresholds are the regression limits of DiffReports. Negative value
+// disables the limit.
+type Thresholds struct {
+	// Percentiles are the quantiles checked against LatencyIncreasePercent.
+	Percentiles []float64
+	// LatencyIncreasePercent is the maximum increase of latency percentiles
+	// in percent of baseline.
+	LatencyIncreasePercent float64
+	// MinLatencyIncrease is the increase of latency in seconds below which
+	// the latency isn't considered regressed, so that fast requests don't
+	// fail the check by noise.
+	MinLatencyIncrease float64
+	// ErrorRateIncrease is the maximum increase of error rate in
+	// percentage points.
+	ErrorRateIncrease float64
+	// ThroughputDecreasePercent is the maximum decrease of achieved QPS in
+	// percent of baseline.
+	ThroughputDecreasePercent float64
+}
+
+const (
+	// MetricErrorRate is the name of error rate in percent.
+	MetricErrorRate = "error rate (%)"
+	// MetricAchievedQPS is the name of achieved QPS.
+	MetricAchievedQPS = "achieved QPS"
+	// MetricDispatched is the name of dispatched requests. It's the only
+	// metric of the key found in one report.
+	MetricDispatched = "dispatched"
+)
+
+// The values of DiffRow.Missing.
+const (
+	MissingInBaseline = "baseline"
+	MissingInTarget   = "target"
+)
+
+// DiffRow is the comparison of one metric.
+type DiffRow struct {
+	// Key is what's compared, for instance, overall, "verb LIST" or
+	// "url GET /api/v1/pods". URLs don't have scheme and host, so that
+	// reports from different clusters match.
+	Key string `json:"key"`
+	// Metric is the name of metric, for instance, p99, MetricErrorRate
+	// or MetricAchievedQPS.
+	Metric string `json:"metric"`
+	// Baseline is the value in baseline.
+	Baseline float64 `json:"baseline"`
+	// Target is the value in target.
+	Target float64 `json:"target"`
+	// Delta is Target - Baseline.
+	Delta float64 `json:"delta"`
+	// DeltaPercent is Delta in percent of Baseline. It's zero if Baseline
+	// is zero.
+	DeltaPercent float64 `json:"deltaPercent"`
+	// Regressed is true if the delta exceeds the threshold.
+	Regressed bool `json:"regressed,omitempty"`
+	// Missing is MissingInBaseline or MissingInTarget if the key is found
+	// in one report only. The value of the missing side is zero.
+	Missing string `json:"missing,omitempty"`
+
+	// latency is true if the metric is latency percentile in seconds.
+	latency bool
+}
+
+// Diff is the comparison between baseline and one target.
+type Diff struct {
+	// Baseline is the name of baseline report.
+	Baseline string `json:"baseline"`
+	// Target is the name of target report.
+	Target string `json:"target"`
+	// Rows are the compared metrics.
+	Rows []DiffRow `json:"rows"`
+}
+
+// Regressions returns the rows which exceed thresholds.
+func (d *Diff) Regressions() []DiffRow {
+	var res []DiffRow
+	for _, row := range d.Rows {
+		if row.Regressed {
+			res = append(res, row)
+		}
+	}
+	return res
+}
+
+// reportView is the metrics of report by key.
+type reportView struct {
+	keys []string
+	// percentiles is keyed by key.
+	percentiles map[string][][2]float64
+	// errorRates is keyed by key. It's in percent.
+	errorRates map[string]float64
+	// qps is keyed by key.
+	qps map[string]float64
+	// dispatched is keyed by key. Every key has it.
+	dispatched map[string]float64
+}
+
+const keyOverall = "overall"
+
+func newReportView(r *types.RunnerMetricReport) *reportView {
+	v := &reportView{
+		percentiles: map[string][][2]float64{},
+		errorRates:  map[string]float64{},
+		qps:         map[string]float64{},
+		dispatched:  map[string]float64{},
+	}
+	seconds := 0.0
+	if d, err := time.ParseDuration(r.Duration); err == nil {
+		seconds = d.Seconds()
+	}
+
+	v.keys = append(v.keys, keyOverall)
+	v.percentiles[keyOverall] = r.PercentileLatencies
+	if r.Dispatched > 0 || r.CountsByURL != nil {
+		v.errorRates[keyOverall] = ratePercent(r.Failed, r.Dispatched)
+		v.qps[keyOverall] = r.AchievedQPS
+		v.dispatched[keyOverall] = float64(r.Dispatched)
+	} else {
+		// The report from runner which doesn't count requests.
+		failed := 0
+		for _, n := range r.ErrorStats {
+			failed += int(n)
+		}
+		v.errorRates[keyOverall] = ratePercent(failed, r.Total)
+		v.dispatched[keyOverall] = float64(r.Total)
+	}
+
+	for _, verb := range sortedKeys(r.ByVerb) {
+		a := r.ByVerb[verb]
+		key := "verb " + verb
+		v.keys = append(v.keys, key)
+		v.percentiles[key] = a.PercentileLatencies
+		v.errorRates[key] = ratePercent(a.Failures, a.Total)
+		v.dispatched[key] = float64(a.Total)
+		if seconds > 0 {
+			v.qps[key] = float64(a.Total-a.Failures) / seconds
+		}
+	}
+
+	// The report keys URLs by "METHOD URL", where URL includes scheme
+	// and host. Keys which differ in host only are merged.
+	rawKeys := map[string][]string{}
+	for _, raw := range sortedKeys(unionKeys(r.PercentileLatenciesByURL, r.CountsByURL)) {
+		key := "url " + stripURLHost(raw)
+		rawKeys[key] = append(rawKeys[key], raw)
+	}
+	for _, key := range sortedKeys(rawKeys) {
+		v.keys = append(v.keys, key)
+
+		var counts *types.RequestCounts
+		var hist *types.LatencyHistogram
+		for _, raw := range rawKeys[key] {
+			if c := r.CountsByURL[raw]; c != nil {
+				if counts == nil {
+					counts = &types.RequestCounts{}
+				}
+				counts.Dispatched += c.Dispatched
+				counts.Failed += c.Failed
+				counts.AchievedQPS += c.AchievedQPS
+			}
+			hist = metrics.MergeLatencyHistograms(hist, r.LatencyHistogramsByURL[raw])
+		}
+
+		v.percentiles[key] = r.PercentileLatenciesByURL[rawKeys[key][0]]
+		if len(rawKeys[key]) > 1 && hist != nil {
+			v.percentiles[key] = metrics.BuildPercentileLatenciesFromHistogram(hist)
+		}
+		switch {
+		case counts != nil:
+			v.errorRates[key] = ratePercent(counts.Failed, counts.Dispatched)
+			v.qps[key] = counts.AchievedQPS
+			v.dispatched[key] = float64(counts.Dispatched)
+		case hist != nil:
+			// The report from runner which doesn't count requests.
+			v.dispatched[key] = float64(hist.Count)
+		default:
+			v.dispatched[key] = 0
+		}
+	}
+	return v
+}
+
+// unionKeys returns the keys of both maps.
+func unionKeys[V1, V2 any](m1 map[string]V1, m2 map[string]V2) map[string]struct{} {
+	res := make(map[string]struct{}, len(m1)+len(m2))
+	for k := range m1 {
+		res[k] = struct{}{}
+	}
+	for k := range m2 {
+		res[k] = struct{}{}
+	}
+	return res
+}
+
+// stripURLHost removes scheme and host from the URL of "METHOD URL" key.
+func stripURLHost(key string) string {
+	method, rawURL, _ := strings.Cut(key, " ")
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return key
+	}
+	return method + " " + u.RequestURI()
+}
+
+func ratePercent(n, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+// DiffReports compares target with baseline overall, by verb and by URL.
+// Only the metrics available in both reports are compared. The key found in
+// one report only has MetricDispatched row with Missing set.
+func DiffReports(baseline, target *types.RunnerMetricReport, th Thresholds) []DiffRow {
+	bv, tv := newReportView(baseline), newReportView(target)
+	checked := map[float64]bool{}
+	for _, q := range th.Percentiles {
+		checked[q] = true
+	}
+
+	keys := append([]string{}, bv.keys...)
+	for _, key := range tv.keys {
+		if _, ok := bv.dispatched[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	var rows []DiffRow
+	for _, key := range keys {
+		_, inBaseline := bv.dispatched[key]
+		_, inTarget := tv.dispatched[key]
+		if !inBaseline || !inTarget {
+			row := newDiffRow(key, MetricDispatched, bv.dispatched[key], tv.dispatched[key])
+			row.Missing = MissingInBaseline
+			if inBaseline {
+				row.Missing = MissingInTarget
+			}
+			rows = append(rows, row)
+			continue
+		}
+
+		for _, bp := range bv.percentiles[key] {
+			for _, tp := range tv.percentiles[key] {
+				if bp[0] != tp[0] {
+					continue
+				}
+				row := newDiffRow(key, percentileLabels([]float64{bp[0]})[0], bp[1], tp[1])
+				row.latency = true
+				row.Regressed = checked[bp[0]] && th.LatencyIncreasePercent >= 0 &&
+					row.Delta > th.MinLatencyIncrease && exceedsPercent(row, th.LatencyIncreasePercent)
+				rows = append(rows, row)
+			}
+		}
+
+		if b, ok := bv.errorRates[key]; ok {
+			if t, ok := tv.errorRates[key]; ok {
+				row := newDiffRow(key, MetricErrorRate, b, t)
+				row.Regressed = th.ErrorRateIncrease >= 0 && row.Delta > th.ErrorRateIncrease
+				rows = append(rows, row)
+			}
+		}
+
+		if b, ok := bv.qps[key]; ok {
+			if t, ok := tv.qps[key]; ok {
+				row := newDiffRow(key, MetricAchievedQPS, b, t)
+				row.Regressed = th.ThroughputDecreasePercent >= 0 && row.Baseline > 0 &&
+					-row.DeltaPercent > th.ThroughputDecreasePercent
+				rows = append(rows, row)
+			}
+		}
+	}
+	return rows
+}
+
+func newDiffRow(key, metric string, baseline, target float64) DiffRow {
+	row := DiffRow{Key: key, Metric: metric, Baseline: baseline, Target: target, Delta: target - baseline}
+	if baseline != 0 {
+		row.DeltaPercent = row.Delta * 100 / baseline
+	}
+	return row
+}
+
+// exceedsPercent returns true if row increased more than limit percent of
+// baseline. Any increase from zero exceeds.
+func exceedsPercent(row DiffRow, limit float64) bool {
+	if row.Baseline == 0 {
+		return row.Delta > 0
+	}
+	return row.DeltaPercent > limit
+}
+
+// RenderDiffs writes diffs in format. Markdown has one table per diff and
+// CSV has one row per metric.
+func RenderDiffs(w io.Writer, format Format, diffs []Diff) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diffs); err != nil {
+			return fmt.Errorf("failed to encode json: %w", err)
+		}
+		return nil
+	case FormatMarkdown:
+		for _, d := range diffs {
+			writeMarkdownTable(w, "##", diffTable(d))
+		}
+		return nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		for idx, d := range diffs {
+			t := diffTable(d)
+			if idx == 0 {
+				_ = cw.Write(append([]string{"baseline report", "target report"}, t.Header...))
+			}
+			for _, row := range t.Rows {
+				_ = cw.Write(append([]string{d.Baseline, d.Target}, row...))
+			}
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return fmt.Errorf("failed to write csv: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q for diff", format)
+	}
+}
+
+func diffTable(d Diff) table {
+	t := table{
+		Title:  fmt.Sprintf("%s vs %s", d.Baseline, d.Target),
+		Header: []string{"key", "metric", "baseline", "target", "delta", "delta (%)", "regressed"},
+	}
+
+	for _, row := range d.Rows {
+		format := formatFloat
+		if row.latency {
+			format = formatSeconds
+		}
+		deltaPercent := "-"
+		if row.Baseline != 0 {
+			deltaPercent = signed(formatFloat(row.DeltaPercent))
+		}
+		regressed := ""
+		if row.Regressed {
+			regressed = "yes"
+		}
+		baseline, target, delta := format(row.Baseline), format(row.Target), signed(format(row.Delta))
+		switch row.Missing {
+		case MissingInBaseline:
+			baseline, delta, deltaPercent = "-", "-", "-"
+		case MissingInTarget:
+			target, delta, deltaPercent = "-", "-", "-"
+		}
+		t.Rows = append(t.Rows, []string{row.Key, row.Metric, baseline, target, delta, deltaPercent, regressed})
+	}
+	return t
+}
+
+// signed prefixes + to the non-negative number.
+func signed(v string) string {
+	if strings.HasPrefix(v, "-") {
+		return v
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil && f == 0 {
+		return v
+	}
+	return "+" + v
+}
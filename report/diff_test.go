@@ -0,0 +1,137 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadReport(t *testing.T) {
+	dir := t.TempDir()
+	r := newTestReport()
+
+	for name, v := range map[string]interface{}{
+		"plain.json":      r,
+		"aggregated.json": map[string]interface{}{"aggregated": r},
+		"bench.json":      map[string]interface{}{"description": "test", "result": r},
+	} {
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, data, 0600))
+
+		got, err := LoadReport(path)
+		require.NoError(t, err, name)
+		assert.Equal(t, r.Dispatched, got.Dispatched, name)
+		assert.Equal(t, r.PercentileLatenciesByURL, got.PercentileLatenciesByURL, name)
+	}
+
+	path := filepath.Join(dir, "other.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"foo": 1}`), 0600))
+	_, err := LoadReport(path)
+	assert.Error(t, err)
+}
+
+func TestDiffReports(t *testing.T) {
+	th := Thresholds{
+		Percentiles:               []float64{0.99},
+		LatencyIncreasePercent:    10,
+		MinLatencyIncrease:        0.005,
+		ErrorRateIncrease:         1,
+		ThroughputDecreasePercent: 10,
+	}
+
+	// The target runs against another cluster.
+	baseline, target := newTestReport(), newTestReport()
+	moveURLs(target, "https://10.0.0.1/", "https://10.0.0.2/")
+
+	rows := DiffReports(baseline, target, th)
+	require.NotEmpty(t, rows)
+	d := Diff{Rows: rows}
+	assert.Empty(t, d.Regressions())
+	for _, row := range rows {
+		assert.Empty(t, row.Missing, row.Key)
+	}
+
+	target.PercentileLatenciesByURL["GET https://10.0.0.2/api/v1/pods"] = [][2]float64{{0.5, 0.2}, {0.99, 0.6}}
+	target.CountsByURL["GET https://10.0.0.2/api/v1/nodes"] = &types.RequestCounts{Dispatched: 1, Succeeded: 1, AchievedQPS: 0.4}
+	target.CountsByURL["GET https://10.0.0.2/api/v1/namespaces?limit=500"] = &types.RequestCounts{Dispatched: 3, Succeeded: 3, AchievedQPS: 1.5}
+
+	d = Diff{Baseline: "base.json", Target: "target.json", Rows: DiffReports(baseline, target, th)}
+	regressed := map[string]string{}
+	for _, row := range d.Regressions() {
+		regressed[row.Key] = row.Metric
+	}
+	assert.Equal(t, map[string]string{
+		"url GET /api/v1/pods":  "p99",
+		"url GET /api/v1/nodes": MetricAchievedQPS,
+	}, regressed)
+
+	// The URL found in one report is reported with the number of requests.
+	missing := map[string]DiffRow{}
+	for _, row := range d.Rows {
+		if row.Missing != "" {
+			missing[row.Key] = row
+		}
+	}
+	assert.Equal(t, map[string]DiffRow{
+		"url GET /api/v1/namespaces?limit=500": {
+			Key: "url GET /api/v1/namespaces?limit=500", Metric: MetricDispatched,
+			Target: 3, Delta: 3, Missing: MissingInBaseline,
+		},
+	}, missing)
+	missing = map[string]DiffRow{}
+	for _, row := range DiffReports(target, baseline, th) {
+		if row.Missing != "" {
+			missing[row.Key] = row
+		}
+	}
+	require.Len(t, missing, 1)
+	assert.Equal(t, MissingInTarget, missing["url GET /api/v1/namespaces?limit=500"].Missing)
+	assert.Equal(t, float64(3), missing["url GET /api/v1/namespaces?limit=500"].Baseline)
+
+	// Disabled thresholds never regress.
+	th.LatencyIncreasePercent, th.ThroughputDecreasePercent = -1, -1
+	d.Rows = DiffReports(baseline, target, th)
+	assert.Empty(t, d.Regressions())
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderDiffs(&buf, FormatMarkdown, []Diff{d}))
+	assert.Contains(t, buf.String(), "## base.json vs target.json\n")
+	assert.Contains(t, buf.String(), "| url GET /api/v1/nodes | achieved QPS | 0.50 | 0.40 | -0.10 | -20.00 |  |\n")
+	assert.Contains(t, buf.String(), "| url GET /api/v1/namespaces?limit=500 | dispatched | - | 3.00 | - | - |  |\n")
+	assert.Error(t, RenderDiffs(&buf, FormatHTML, []Diff{d}))
+}
+
+// moveURLs replaces the prefix of per-URL keys, like the report of another
+// cluster.
+func moveURLs(r *types.RunnerMetricReport, from, to string) {
+	move := func(key string) string {
+		return strings.Replace(key, " "+from, " "+to, 1)
+	}
+
+	counts := map[string]*types.RequestCounts{}
+	for k, v := range r.CountsByURL {
+		counts[move(k)] = v
+	}
+	hists := map[string]*types.LatencyHistogram{}
+	for k, v := range r.LatencyHistogramsByURL {
+		hists[move(k)] = v
+	}
+	percentiles := map[string][][2]float64{}
+	for k, v := range r.PercentileLatenciesByURL {
+		percentiles[move(k)] = v
+	}
+	r.CountsByURL, r.LatencyHistogramsByURL, r.PercentileLatenciesByURL = counts, hists, percentiles
+}
@@ -0,0 +1,325 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/metrics"
+)
+
+// chartQuantiles are the quantiles on x axis of latency distribution.
+var chartQuantiles = []float64{0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 0.95, 0.99, 0.999, 1}
+
+// maxChartURLs is the maximum number of URLs drawn in latency chart. The
+// most requested ones are picked.
+const maxChartURLs = 8
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ if .Title }}{{ .Title }}{{ else }}kperf report{{ end }}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292f; }
+table { border-collapse: collapse; margin-bottom: 1.5em; font-size: 0.9em; }
+th, td { border: 1px solid #d0d7de; padding: 4px 8px; text-align: right; }
+th { background: #f6f8fa; }
+td:first-child, th:first-child { text-align: left; }
+pre { background: #f6f8fa; padding: 1em; overflow: auto; }
+svg { display: block; margin-bottom: 1.5em; }
+</style>
+</head>
+<body>
+{{ if .Title }}<h1>{{ .Title }}</h1>{{ end }}
+{{ if .Info }}<pre>{{ .Info }}</pre>{{ end }}
+{{ range .Sections }}
+<h2>{{ .Title }}</h2>
+{{ range .Charts }}{{ . }}{{ end }}
+{{ range .Tables }}{{ template "table" . }}{{ end }}
+{{ end }}
+{{ with .Search }}{{ template "table" . }}{{ end }}
+</body>
+</html>
+{{ define "table" }}
+<h3>{{ .Title }}</h3>
+<table>
+<tr>{{ range .Header }}<th>{{ . }}</th>{{ end }}</tr>
+{{ range .Rows }}<tr>{{ range . }}<td>{{ . }}</td>{{ end }}</tr>
+{{ end }}</table>
+{{ end }}
+`))
+
+type htmlSection struct {
+	Title  string
+	Charts []template.HTML
+	Tables []table
+}
+
+// renderHTML renders a single HTML file with tables and SVG charts, which
+// doesn't depend on any external resource.
+func renderHTML(w io.Writer, doc *Document) error {
+	data := struct {
+		Title    string
+		Info     string
+		Sections []htmlSection
+		Search   *table
+	}{Title: doc.Title}
+
+	if len(doc.Info) > 0 {
+		info, err := infoJSON(doc.Info)
+		if err != nil {
+			return err
+		}
+		data.Info = info
+	}
+
+	for _, s := range doc.Sections {
+		section := htmlSection{Title: s.Title, Tables: sectionTables(s.Report)}
+		for _, c := range sectionCharts(s.Report) {
+			section.Charts = append(section.Charts, c.svg())
+		}
+		data.Sections = append(data.Sections, section)
+	}
+
+	if doc.Search != nil {
+		t := searchTable(doc.Search)
+		data.Search = &t
+	}
+
+	if err := htmlTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render html: %w", err)
+	}
+	return nil
+}
+
+// sectionCharts returns the latency distribution and, if available, time
+// series charts of report.
+func sectionCharts(r *types.RunnerMetricReport) []*chart {
+	var charts []*chart
+	if c := latencyChart(r); c != nil {
+		charts = append(charts, c)
+	}
+	return append(charts, timeSeriesCharts(r.TimeSeries)...)
+}
+
+// latencyChart draws latency by percentile of all the succeeded requests
+// and the most requested URLs.
+func latencyChart(r *types.RunnerMetricReport) *chart {
+	c := &chart{title: "Latency distribution", xLabel: "percentile", yLabel: "seconds"}
+
+	// The report from runner which doesn't support histogram only has
+	// percentiles.
+	if len(r.LatencyHistogramsByURL) == 0 {
+		if len(r.PercentileLatencies) == 0 {
+			return nil
+		}
+		c.series = append(c.series, quantileSeries("all", r.PercentileLatencies))
+		c.xTicks = quantileTicks(quantilesOf(r.PercentileLatencies))
+		return c
+	}
+
+	var all *types.LatencyHistogram
+	keys := make([]string, 0, len(r.LatencyHistogramsByURL))
+	for k, h := range r.LatencyHistogramsByURL {
+		all = metrics.MergeLatencyHistograms(all, h)
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ci, cj := r.LatencyHistogramsByURL[keys[i]].Count, r.LatencyHistogramsByURL[keys[j]].Count
+		if ci != cj {
+			return ci > cj
+		}
+		return keys[i] < keys[j]
+	})
+
+	c.series = append(c.series, quantileSeries("all", metrics.BuildQuantilesFromHistogram(all, chartQuantiles)))
+	if len(keys) > 1 {
+		for _, k := range keys[:min(len(keys), maxChartURLs)] {
+			c.series = append(c.series,
+				quantileSeries(k, metrics.BuildQuantilesFromHistogram(r.LatencyHistogramsByURL[k], chartQuantiles)))
+		}
+	}
+	c.xTicks = quantileTicks(chartQuantiles)
+	return c
+}
+
+// quantileSeries places latencies evenly on x axis in order of quantile
+// so that the tail isn't squeezed.
+func quantileSeries(name string, l [][2]float64) chartSeries {
+	s := chartSeries{name: name}
+	for i, p := range l {
+		s.points = append(s.points, [2]float64{float64(i), p[1]})
+	}
+	return s
+}
+
+func quantileTicks(qs []float64) []chartTick {
+	ticks := make([]chartTick, 0, len(qs))
+	for i, label := range percentileLabels(qs) {
+		ticks = append(ticks, chartTick{x: float64(i), label: label})
+	}
+	return ticks
+}
+
+// timeSeriesCharts draws throughput and latency over time.
+func timeSeriesCharts(ts *types.TimeSeries) []*chart {
+	if ts == nil || len(ts.Points) == 0 || ts.IntervalSeconds <= 0 {
+		return nil
+	}
+
+	throughput := &chart{title: "Throughput", xLabel: "seconds since start", yLabel: "requests per second"}
+	latency := &chart{title: "Latency over time", xLabel: "seconds since start", yLabel: "seconds"}
+
+	requests := chartSeries{name: "requests"}
+	failures := chartSeries{name: "errors"}
+	p50 := chartSeries{name: "p50"}
+	p99 := chartSeries{name: "p99"}
+
+	start := ts.Points[0].Timestamp
+	for _, p := range ts.Points {
+		x := p.Timestamp.Sub(start).Seconds()
+
+		errs := 0
+		for _, n := range p.ErrorStats {
+			errs += int(n)
+		}
+		requests.points = append(requests.points, [2]float64{x, float64(p.Requests) / ts.IntervalSeconds})
+		failures.points = append(failures.points, [2]float64{x, float64(errs) / ts.IntervalSeconds})
+
+		l := p.PercentileLatencies
+		if p.Histogram != nil {
+			l = metrics.BuildQuantilesFromHistogram(p.Histogram, []float64{0.5, 0.99})
+		}
+		for _, q := range l {
+			switch q[0] {
+			case 0.5:
+				p50.points = append(p50.points, [2]float64{x, q[1]})
+			case 0.99:
+				p99.points = append(p99.points, [2]float64{x, q[1]})
+			}
+		}
+	}
+
+	throughput.series = []chartSeries{requests, failures}
+	latency.series = []chartSeries{p50, p99}
+	return []*chart{throughput, latency}
+}
+
+const (
+	chartWidth        = 800
+	chartHeight       = 320
+	chartMarginLeft   = 70
+	chartMarginRight  = 20
+	chartMarginTop    = 30
+	chartMarginBottom = 50
+)
+
+// chartColors is the palette of series.
+var chartColors = []string{"#0969da", "#cf222e", "#1a7f37", "#8250df", "#bf8700", "#1b7c83", "#bc4c00", "#6e7781", "#e85aad"}
+
+// chart is a line chart rendered as inline SVG.
+type chart struct {
+	title  string
+	xLabel string
+	yLabel string
+	// xTicks labels x axis. It's evenly spaced values if it's empty.
+	xTicks []chartTick
+	series []chartSeries
+}
+
+type chartTick struct {
+	x     float64
+	label string
+}
+
+type chartSeries struct {
+	name   string
+	points [][2]float64
+}
+
+// svg renders the chart. The y axis starts from zero.
+func (c *chart) svg() template.HTML {
+	minX, maxX, maxY := math.Inf(1), math.Inf(-1), 0.0
+	for _, s := range c.series {
+		for _, p := range s.points {
+			minX, maxX, maxY = math.Min(minX, p[0]), math.Max(maxX, p[0]), math.Max(maxY, p[1])
+		}
+	}
+	if math.IsInf(minX, 1) {
+		minX, maxX = 0, 1
+	}
+	if maxX <= minX {
+		maxX = minX + 1
+	}
+	if maxY <= 0 {
+		maxY = 1
+	}
+	maxY *= 1.1
+
+	plotW := float64(chartWidth - chartMarginLeft - chartMarginRight)
+	plotH := float64(chartHeight - chartMarginTop - chartMarginBottom)
+	sx := func(x float64) float64 { return chartMarginLeft + (x-minX)/(maxX-minX)*plotW }
+	sy := func(y float64) float64 { return chartMarginTop + plotH - y/maxY*plotH }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-size="11">`,
+		chartWidth, chartHeight, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="18" font-size="14" font-weight="bold">%s</text>`,
+		chartMarginLeft, template.HTMLEscapeString(c.title))
+
+	// y axis with grid lines.
+	for i := 0; i <= 5; i++ {
+		y := maxY * float64(i) / 5
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#eaeef2"/>`,
+			chartMarginLeft, sy(y), chartWidth-chartMarginRight, sy(y))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end">%s</text>`,
+			chartMarginLeft-6, sy(y)+4, strconv.FormatFloat(y, 'g', 3, 64))
+	}
+	fmt.Fprintf(&b, `<text transform="translate(14,%d) rotate(-90)" text-anchor="middle">%s</text>`,
+		chartMarginTop+int(plotH)/2, template.HTMLEscapeString(c.yLabel))
+
+	// x axis.
+	ticks := c.xTicks
+	if len(ticks) == 0 {
+		for i := 0; i <= 5; i++ {
+			x := minX + (maxX-minX)*float64(i)/5
+			ticks = append(ticks, chartTick{x: x, label: strconv.FormatFloat(x, 'g', 4, 64)})
+		}
+	}
+	fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#57606a"/>`,
+		chartMarginLeft, sy(0), chartWidth-chartMarginRight, sy(0))
+	for _, t := range ticks {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">%s</text>`,
+			sx(t.x), sy(0)+16, template.HTMLEscapeString(t.label))
+	}
+	fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`,
+		chartMarginLeft+plotW/2, chartHeight-8, template.HTMLEscapeString(c.xLabel))
+
+	// series and legend.
+	for i, s := range c.series {
+		color := chartColors[i%len(chartColors)]
+		points := make([]string, 0, len(s.points))
+		for _, p := range s.points {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", sx(p[0]), sy(p[1])))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"/>`,
+			color, strings.Join(points, " "))
+
+		ly := chartMarginTop + 4 + i*14
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`, chartMarginLeft+10, ly, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`, chartMarginLeft+24, ly+9, template.HTMLEscapeString(s.name))
+	}
+	b.WriteString(`</svg>`)
+
+	//nolint:gosec // all the text has been escaped.
+	return template.HTML(b.String())
+}
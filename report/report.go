@@ -0,0 +1,334 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+// Package report renders benchmark reports in human-friendly formats.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+// Format is the format of rendered report.
+type Format string
+
+const (
+	// FormatJSON is the indented JSON, which keeps every field.
+	FormatJSON Format = "json"
+	// FormatMarkdown renders summary and per-URL tables in Markdown.
+	FormatMarkdown Format = "markdown"
+	// FormatCSV renders per-URL percentile rows.
+	FormatCSV Format = "csv"
+	// FormatHTML renders a single HTML file with embedded charts.
+	FormatHTML Format = "html"
+)
+
+// Formats is the list of supported formats.
+var Formats = []Format{FormatJSON, FormatMarkdown, FormatCSV, FormatHTML}
+
+// ParseFormat parses format name. The empty name means FormatJSON.
+func ParseFormat(name string) (Format, error) {
+	if name == "" {
+		return FormatJSON, nil
+	}
+
+	for _, f := range Formats {
+		if string(f) == strings.ToLower(name) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported output format %q, it should be one of %v", name, Formats)
+}
+
+// Section is one titled report in the document.
+type Section struct {
+	// Title is the title of section.
+	Title string
+	// Report is the report of section.
+	Report *types.RunnerMetricReport
+}
+
+// Document is what to render.
+type Document struct {
+	// Title is the title of document.
+	Title string
+	// Info is additional information, which is rendered as JSON.
+	Info map[string]interface{}
+	// Sections are the reports in order.
+	Sections []Section
+	// Search is the report of throughput search if any.
+	Search *types.ThroughputSearchReport
+}
+
+// Render writes doc to w in format. The JSON format isn't handled here since
+// callers encode their own report types as-is.
+func Render(w io.Writer, format Format, doc *Document) error {
+	switch format {
+	case FormatMarkdown:
+		return renderMarkdown(w, doc)
+	case FormatCSV:
+		return renderCSV(w, doc)
+	case FormatHTML:
+		return renderHTML(w, doc)
+	default:
+		return fmt.Errorf("unsupported output format %q for rendering", format)
+	}
+}
+
+// table is the format-independent table.
+type table struct {
+	Title  string
+	Header []string
+	Rows   [][]string
+}
+
+// sectionTables returns the tables of report.
+func sectionTables(r *types.RunnerMetricReport) []table {
+	tables := []table{summaryTable(r)}
+
+	qs := quantilesOf(r.PercentileLatencies)
+	tables = append(tables, table{
+		Title:  "Latency (seconds)",
+		Header: append([]string{"requests"}, percentileLabels(qs)...),
+		Rows:   [][]string{append([]string{"succeeded"}, percentileCells(qs, r.PercentileLatencies)...)},
+	})
+
+	if t, ok := urlTable(r); ok {
+		tables = append(tables, t)
+	}
+
+	if len(r.ByVerb) > 0 {
+		lists := [][][2]float64{}
+		for _, a := range r.ByVerb {
+			lists = append(lists, a.PercentileLatencies)
+		}
+		qs := quantilesOf(lists...)
+
+		t := table{
+			Title:  "Latency by verb (seconds)",
+			Header: append([]string{"verb", "total", "failures"}, percentileLabels(qs)...),
+		}
+		for _, verb := range sortedKeys(r.ByVerb) {
+			a := r.ByVerb[verb]
+			row := []string{verb, strconv.Itoa(a.Total), strconv.Itoa(a.Failures)}
+			t.Rows = append(t.Rows, append(row, percentileCells(qs, a.PercentileLatencies)...))
+		}
+		tables = append(tables, t)
+	}
+
+	if len(r.PercentileLatenciesByOutcome) > 0 {
+		lists := [][][2]float64{}
+		for _, l := range r.PercentileLatenciesByOutcome {
+			lists = append(lists, l)
+		}
+		qs := quantilesOf(lists...)
+
+		t := table{
+			Title:  "Latency by outcome (seconds)",
+			Header: append([]string{"outcome", "requests"}, percentileLabels(qs)...),
+		}
+		for _, outcome := range sortedKeys(r.PercentileLatenciesByOutcome) {
+			count := ""
+			if h := r.LatencyHistogramsByOutcome[outcome]; h != nil {
+				count = strconv.FormatUint(h.Count, 10)
+			}
+			row := []string{outcome, count}
+			t.Rows = append(t.Rows, append(row, percentileCells(qs, r.PercentileLatenciesByOutcome[outcome])...))
+		}
+		tables = append(tables, t)
+	}
+
+	if t, ok := errorTable(r); ok {
+		tables = append(tables, t)
+	}
+	return tables
+}
+
+func summaryTable(r *types.RunnerMetricReport) table {
+	t := table{Title: "Summary", Header: []string{"metric", "value"}}
+	add := func(k, v string) {
+		t.Rows = append(t.Rows, []string{k, v})
+	}
+
+	add("duration", r.Duration)
+	add("total", strconv.Itoa(r.Total))
+	if r.CountsByURL != nil {
+		add("succeeded", strconv.Itoa(r.Succeeded))
+		add("failed", strconv.Itoa(r.Failed))
+		if r.Dispatched > 0 {
+			add("error rate (%)", formatFloat(float64(r.Failed)*100/float64(r.Dispatched)))
+		}
+		add("achieved QPS", formatFloat(r.AchievedQPS))
+	}
+	add("received bytes", strconv.FormatInt(r.TotalReceivedBytes, 10))
+	if r.LimiterWait != nil {
+		add("mean limiter wait (seconds)", formatSeconds(r.LimiterWait.MeanSeconds))
+	}
+	if r.Aborted {
+		add("aborted", r.AbortReason)
+	}
+	if r.Interrupted {
+		add("interrupted", "true")
+	}
+	return t
+}
+
+// urlTable returns per-URL counts and percentiles.
+func urlTable(r *types.RunnerMetricReport) (table, bool) {
+	keys := map[string]struct{}{}
+	lists := [][][2]float64{}
+	for k, l := range r.PercentileLatenciesByURL {
+		keys[k] = struct{}{}
+		lists = append(lists, l)
+	}
+	for k := range r.CountsByURL {
+		keys[k] = struct{}{}
+	}
+	if len(keys) == 0 {
+		return table{}, false
+	}
+	qs := quantilesOf(lists...)
+
+	t := table{
+		Title: "Per URL (seconds)",
+		Header: append([]string{"method", "url", "dispatched", "succeeded", "failed", "achieved QPS", "received bytes"},
+			percentileLabels(qs)...),
+	}
+	for _, key := range sortedKeys(keys) {
+		method, u, _ := strings.Cut(key, " ")
+		row := []string{method, u, "", "", "", "", ""}
+		if c := r.CountsByURL[key]; c != nil {
+			row[2] = strconv.Itoa(c.Dispatched)
+			row[3] = strconv.Itoa(c.Succeeded)
+			row[4] = strconv.Itoa(c.Failed)
+			row[5] = formatFloat(c.AchievedQPS)
+			row[6] = strconv.FormatInt(c.ReceivedBytes, 10)
+		}
+		t.Rows = append(t.Rows, append(row, percentileCells(qs, r.PercentileLatenciesByURL[key])...))
+	}
+	return t, true
+}
+
+// errorTable returns error classes, or error stats for reports without
+// classes.
+func errorTable(r *types.RunnerMetricReport) (table, bool) {
+	if len(r.ErrorClasses) > 0 {
+		t := table{
+			Title:  "Errors",
+			Header: []string{"type", "code", "message", "method", "url", "count", "first seen", "last seen"},
+		}
+		for _, c := range r.ErrorClasses {
+			code := ""
+			if c.Code != 0 {
+				code = strconv.Itoa(c.Code)
+			}
+			t.Rows = append(t.Rows, []string{string(c.Type), code, c.MessageClass, c.Method, c.URL,
+				strconv.Itoa(c.Count), c.FirstSeen.UTC().Format("15:04:05.000"), c.LastSeen.UTC().Format("15:04:05.000")})
+		}
+		return t, true
+	}
+
+	if len(r.ErrorStats) > 0 {
+		t := table{Title: "Errors", Header: []string{"error", "count"}}
+		for _, k := range sortedKeys(r.ErrorStats) {
+			t.Rows = append(t.Rows, []string{k, strconv.Itoa(int(r.ErrorStats[k]))})
+		}
+		return t, true
+	}
+	return table{}, false
+}
+
+// searchTable returns the trials of throughput search.
+func searchTable(r *types.ThroughputSearchReport) table {
+	title := fmt.Sprintf("Throughput search (max QPS %s, achieved %s)",
+		formatFloat(r.MaxQPS), formatFloat(r.MaxAchievedQPS))
+	if r.Interrupted {
+		title += ", interrupted"
+	}
+	t := table{
+		Title:  title,
+		Header: []string{"offered QPS", "achieved QPS", "total", "duration", "p50 (seconds)", "p99 (seconds)", "error rate (%)", "passed"},
+	}
+	for _, trial := range r.Trials {
+		t.Rows = append(t.Rows, []string{
+			formatFloat(trial.OfferedQPS), formatFloat(trial.AchievedQPS), strconv.Itoa(trial.Total), trial.Duration,
+			formatSeconds(trial.P50Latency), formatSeconds(trial.P99Latency), formatFloat(trial.ErrorRate),
+			strconv.FormatBool(trial.Passed),
+		})
+	}
+	return t
+}
+
+// infoJSON returns the indented JSON of info.
+func infoJSON(info map[string]interface{}) (string, error) {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode info: %w", err)
+	}
+	return string(data), nil
+}
+
+// quantilesOf returns sorted quantiles which appear in any of lists.
+func quantilesOf(lists ...[][2]float64) []float64 {
+	seen := map[float64]struct{}{}
+	for _, l := range lists {
+		for _, p := range l {
+			seen[p[0]] = struct{}{}
+		}
+	}
+
+	qs := make([]float64, 0, len(seen))
+	for q := range seen {
+		qs = append(qs, q)
+	}
+	sort.Float64s(qs)
+	return qs
+}
+
+// percentileLabels returns labels like p50 and p99.9 for quantiles.
+func percentileLabels(qs []float64) []string {
+	labels := make([]string, 0, len(qs))
+	for _, q := range qs {
+		labels = append(labels, "p"+strconv.FormatFloat(math.Round(q*1e6)/1e4, 'f', -1, 64))
+	}
+	return labels
+}
+
+// percentileCells returns latencies of l at quantiles. It's empty if l
+// doesn't have the quantile.
+func percentileCells(qs []float64, l [][2]float64) []string {
+	cells := make([]string, len(qs))
+	for i, q := range qs {
+		for _, p := range l {
+			if p[0] == q {
+				cells[i] = formatSeconds(p[1])
+				break
+			}
+		}
+	}
+	return cells
+}
+
+func formatSeconds(v float64) string {
+	return strconv.FormatFloat(v, 'f', 4, 64)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,111 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestReport() *types.RunnerMetricReport {
+	pods := metrics.HistogramFromLatencies([]float64{0.1, 0.2, 0.3})
+	nodes := metrics.HistogramFromLatencies([]float64{1})
+	start := time.Unix(1700000000, 0)
+
+	return &types.RunnerMetricReport{
+		Total:       5,
+		Duration:    "2s",
+		Dispatched:  5,
+		Succeeded:   4,
+		Failed:      1,
+		AchievedQPS: 2,
+		CountsByURL: map[string]*types.RequestCounts{
+			"GET https://10.0.0.1/api/v1/pods":  {Dispatched: 4, Succeeded: 3, Failed: 1, AchievedQPS: 1.5},
+			"GET https://10.0.0.1/api/v1/nodes": {Dispatched: 1, Succeeded: 1, AchievedQPS: 0.5},
+		},
+		LatencyHistogramsByURL: map[string]*types.LatencyHistogram{
+			"GET https://10.0.0.1/api/v1/pods":  pods,
+			"GET https://10.0.0.1/api/v1/nodes": nodes,
+		},
+		PercentileLatencies: metrics.BuildPercentileLatenciesFromHistogram(metrics.MergeLatencyHistograms(pods, nodes)),
+		PercentileLatenciesByURL: map[string][][2]float64{
+			"GET https://10.0.0.1/api/v1/pods":  metrics.BuildPercentileLatenciesFromHistogram(pods),
+			"GET https://10.0.0.1/api/v1/nodes": metrics.BuildPercentileLatenciesFromHistogram(nodes),
+		},
+		ErrorClasses: []types.ResponseErrorClass{
+			{Method: "GET", URL: "/api/v1/pods", Type: types.ResponseErrorTypeHTTP, Code: 429, Count: 1, FirstSeen: start, LastSeen: start},
+		},
+		TimeSeries: &types.TimeSeries{
+			IntervalSeconds: 1,
+			Points: []types.TimeSeriesPoint{
+				{Timestamp: start, Requests: 3, Histogram: pods},
+				{Timestamp: start.Add(time.Second), Requests: 2, ErrorStats: map[string]int32{"http/429": 1}, Histogram: nodes},
+			},
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSON, f)
+
+	f, err = ParseFormat("Markdown")
+	require.NoError(t, err)
+	assert.Equal(t, FormatMarkdown, f)
+
+	_, err = ParseFormat("yaml")
+	assert.Error(t, err)
+}
+
+func TestRender(t *testing.T) {
+	doc := &Document{
+		Title:    "node10_job1_pod100",
+		Info:     map[string]interface{}{"apiserver": "v1.30"},
+		Sections: []Section{{Title: "Result", Report: newTestReport()}},
+		Search: &types.ThroughputSearchReport{
+			MaxQPS: 100,
+			Trials: []types.ThroughputSearchTrial{{OfferedQPS: 100, AchievedQPS: 99, Passed: true}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, FormatMarkdown, doc))
+	md := buf.String()
+	assert.Contains(t, md, "# node10_job1_pod100\n")
+	assert.Contains(t, md, "| achieved QPS | 2.00 |\n")
+	assert.Contains(t, md, "| method | url | dispatched | succeeded | failed | achieved QPS | received bytes | p0 | p50 | p90 | p95 | p99 | p100 |\n")
+	assert.Contains(t, md, "| GET | https://10.0.0.1/api/v1/pods | 4 | 3 | 1 | 1.50 | 0 |")
+	assert.Contains(t, md, "| http | 429 |  | GET | /api/v1/pods | 1 |")
+	assert.Contains(t, md, "| 100.00 | 99.00 |")
+
+	buf.Reset()
+	require.NoError(t, Render(&buf, FormatCSV, doc))
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, []string{"section", "method", "url", "dispatched", "succeeded", "failed", "achieved_qps", "received_bytes",
+		"p0", "p50", "p90", "p95", "p99", "p100"}, records[0])
+	assert.Equal(t, []string{"Result", "GET", "https://10.0.0.1/api/v1/nodes", "1", "1", "0", "0.50", "0"}, records[1][:8])
+	assert.Equal(t, "1.0000", records[1][len(records[1])-1])
+
+	buf.Reset()
+	require.NoError(t, Render(&buf, FormatHTML, doc))
+	html := buf.String()
+	assert.Contains(t, html, "<title>node10_job1_pod100</title>")
+	assert.Contains(t, html, ">Latency distribution</text>")
+	assert.Contains(t, html, ">Throughput</text>")
+	assert.Contains(t, html, "<td>https://10.0.0.1/api/v1/pods</td>")
+	assert.NotContains(t, html, "<script")
+
+	assert.Error(t, Render(&buf, FormatJSON, doc))
+}
@@ -0,0 +1,119 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package report
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// renderMarkdown renders summary and per-URL tables in Markdown.
+func renderMarkdown(w io.Writer, doc *Document) error {
+	bw := bufio.NewWriter(w)
+
+	if doc.Title != "" {
+		fmt.Fprintf(bw, "# %s\n\n", doc.Title)
+	}
+
+	if len(doc.Info) > 0 {
+		info, err := infoJSON(doc.Info)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "```json\n%s\n```\n\n", info)
+	}
+
+	for _, s := range doc.Sections {
+		fmt.Fprintf(bw, "## %s\n\n", s.Title)
+		for _, t := range sectionTables(s.Report) {
+			writeMarkdownTable(bw, "###", t)
+		}
+	}
+
+	if doc.Search != nil {
+		writeMarkdownTable(bw, "##", searchTable(doc.Search))
+	}
+	return bw.Flush()
+}
+
+func writeMarkdownTable(w io.Writer, heading string, t table) {
+	fmt.Fprintf(w, "%s %s\n\n", heading, t.Title)
+	writeMarkdownRow(w, t.Header)
+
+	sep := make([]string, len(t.Header))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	writeMarkdownRow(w, sep)
+
+	for _, row := range t.Rows {
+		writeMarkdownRow(w, row)
+	}
+	fmt.Fprintln(w)
+}
+
+func writeMarkdownRow(w io.Writer, cells []string) {
+	escaped := make([]string, len(cells))
+	for i, c := range cells {
+		escaped[i] = strings.ReplaceAll(c, "|", `\|`)
+	}
+	fmt.Fprintf(w, "| %s |\n", strings.Join(escaped, " | "))
+}
+
+// renderCSV renders one row per URL and section with counts and latency
+// percentiles in seconds. The throughput search, if any, is rendered as
+// trial rows instead since it has no per-URL data.
+func renderCSV(w io.Writer, doc *Document) error {
+	cw := csv.NewWriter(w)
+
+	if len(doc.Sections) == 0 && doc.Search != nil {
+		t := searchTable(doc.Search)
+		if err := cw.Write(t.Header); err != nil {
+			return fmt.Errorf("failed to write csv: %w", err)
+		}
+		if err := cw.WriteAll(t.Rows); err != nil {
+			return fmt.Errorf("failed to write csv: %w", err)
+		}
+		return nil
+	}
+
+	// All the rows share the same percentile columns.
+	lists := [][][2]float64{}
+	for _, s := range doc.Sections {
+		for _, l := range s.Report.PercentileLatenciesByURL {
+			lists = append(lists, l)
+		}
+	}
+	qs := quantilesOf(lists...)
+
+	header := append([]string{"section", "method", "url", "dispatched", "succeeded", "failed", "achieved_qps", "received_bytes"},
+		percentileLabels(qs)...)
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+
+	for _, s := range doc.Sections {
+		t, ok := urlTable(s.Report)
+		if !ok {
+			continue
+		}
+		for _, row := range t.Rows {
+			key := row[0] + " " + row[1]
+			record := append([]string{s.Title}, row[:7]...)
+			record = append(record, percentileCells(qs, s.Report.PercentileLatenciesByURL[key])...)
+			if err := cw.Write(record); err != nil {
+				return fmt.Errorf("failed to write csv: %w", err)
+			}
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
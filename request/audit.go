@@ -0,0 +1,328 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	authnv1 "k8s.io/api/authentication/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+// maxAuditEventSize is the maximum size of one audit event line.
+const maxAuditEventSize = 16 * 1024 * 1024
+
+// AuditEventFilter selects audit events to replay. Empty field matches all.
+type AuditEventFilter struct {
+	// Verbs are kubernetes verbs, like get, list, create and patch.
+	Verbs []string
+	// Resources are resource names. Subresource is in the format of
+	// resource/subresource, like pods/status.
+	Resources []string
+	// Namespaces are the namespaces of target objects.
+	Namespaces []string
+	// Users are the usernames who sent the requests.
+	Users []string
+}
+
+// match returns true if event is selected by filter.
+func (f *AuditEventFilter) match(ev *auditv1.Event) bool {
+	if len(f.Verbs) > 0 && !slices.Contains(f.Verbs, ev.Verb) {
+		return false
+	}
+
+	resource, namespace := "", ""
+	if ref := ev.ObjectRef; ref != nil {
+		resource, namespace = ref.Resource, ref.Namespace
+		if ref.Subresource != "" {
+			resource = resource + "/" + ref.Subresource
+		}
+	}
+	if len(f.Resources) > 0 && !slices.Contains(f.Resources, resource) {
+		return false
+	}
+	if len(f.Namespaces) > 0 && !slices.Contains(f.Namespaces, namespace) {
+		return false
+	}
+	if len(f.Users) > 0 && !slices.Contains(f.Users, ev.User.Username) {
+		return false
+	}
+	return true
+}
+
+// AuditRequest is the request recovered from one audit event.
+type AuditRequest struct {
+	// Offset is the arrival time relative to the first request.
+	Offset time.Duration
+	// Verb is the kubernetes verb.
+	Verb string
+	// RequestURI is the URI including query parameters.
+	RequestURI string
+	// Body is the request body if the event was logged with request object.
+	Body []byte
+	// ContentType is the content type of Body.
+	ContentType string
+	// User is the user who sent the original request.
+	User authnv1.UserInfo
+}
+
+// LoadAuditRequests reads audit events in JSON lines and returns the requests
+// ordered by arrival time.
+//
+// Each request is recovered from the first of its events with stage
+// RequestReceived or ResponseComplete. Watch requests are skipped because
+// they're long-running. The mutating requests without request object are
+// skipped as well because they can't be rebuilt.
+func LoadAuditRequests(r io.Reader, filter AuditEventFilter) ([]*AuditRequest, error) {
+	type timedRequest struct {
+		at  time.Time
+		req *AuditRequest
+	}
+
+	reqs := make([]timedRequest, 0, 1024)
+	skipped := 0
+
+	err := ReadAuditEvents(r, func(ev *auditv1.Event) {
+		if ev.Verb == "watch" || !filter.match(ev) {
+			return
+		}
+
+		req, err := newAuditRequest(ev)
+		if err != nil {
+			klog.V(5).Infof("Skip audit event %s: %v", ev.AuditID, err)
+			skipped++
+			return
+		}
+		reqs = append(reqs, timedRequest{at: ev.RequestReceivedTimestamp.Time, req: req})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if skipped > 0 {
+		klog.Warningf("Skipped %d audit events which can't be replayed", skipped)
+	}
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	sort.SliceStable(reqs, func(i, j int) bool {
+		return reqs[i].at.Before(reqs[j].at)
+	})
+
+	res := make([]*AuditRequest, 0, len(reqs))
+	for _, r := range reqs {
+		r.req.Offset = r.at.Sub(reqs[0].at)
+		res = append(res, r.req)
+	}
+	return res, nil
+}
+
+// ReadAuditEvents reads audit events in JSON lines and calls fn once per
+// request, with the first of its events with stage RequestReceived or
+// ResponseComplete.
+func ReadAuditEvents(r io.Reader, fn func(ev *auditv1.Event)) error {
+	seen := map[apitypes.UID]struct{}{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxAuditEventSize)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		ev := &auditv1.Event{}
+		if err := json.Unmarshal(line, ev); err != nil {
+			return fmt.Errorf("failed to decode audit event at line %d: %w", lineNum, err)
+		}
+
+		if ev.Stage != auditv1.StageRequestReceived && ev.Stage != auditv1.StageResponseComplete {
+			continue
+		}
+		if _, ok := seen[ev.AuditID]; ok {
+			continue
+		}
+		seen[ev.AuditID] = struct{}{}
+
+		fn(ev)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read audit events: %w", err)
+	}
+	return nil
+}
+
+// newAuditRequest rebuilds request from audit event.
+func newAuditRequest(ev *auditv1.Event) (*AuditRequest, error) {
+	if _, err := auditVerbMethod(ev.Verb); err != nil {
+		return nil, err
+	}
+
+	req := &AuditRequest{
+		Verb:       ev.Verb,
+		RequestURI: ev.RequestURI,
+		User:       ev.User,
+	}
+	if ev.ImpersonatedUser != nil {
+		req.User = *ev.ImpersonatedUser
+	}
+
+	if ev.RequestObject != nil && len(ev.RequestObject.Raw) > 0 {
+		req.Body = ev.RequestObject.Raw
+		req.ContentType = ev.RequestObject.ContentType
+		if req.ContentType == "" {
+			req.ContentType = "application/json"
+		}
+	}
+
+	switch ev.Verb {
+	case "create", "update", "patch":
+		if req.Body == nil {
+			return nil, fmt.Errorf("%s request without request object", ev.Verb)
+		}
+	}
+
+	if ev.Verb == "patch" {
+		req.ContentType = string(guessPatchType(req.Body, ev.RequestURI))
+	}
+	return req, nil
+}
+
+// guessPatchType guesses the patch type because it's not recorded in audit
+// event. The apply patch must have fieldManager and the full object's type
+// meta. The JSON patch is an array. Otherwise, it's merge patch which works
+// for all the resources.
+func guessPatchType(body []byte, requestURI string) apitypes.PatchType {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		return apitypes.JSONPatchType
+	}
+
+	if strings.Contains(requestURI, "fieldManager=") {
+		var meta struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+		}
+		if err := json.Unmarshal(body, &meta); err == nil && meta.APIVersion != "" && meta.Kind != "" {
+			return apitypes.ApplyPatchType
+		}
+	}
+	return apitypes.MergePatchType
+}
+
+// auditVerbMethod returns HTTP method for kubernetes verb.
+func auditVerbMethod(verb string) (string, error) {
+	switch verb {
+	case "get", "list":
+		return http.MethodGet, nil
+	case "create":
+		return http.MethodPost, nil
+	case "update":
+		return http.MethodPut, nil
+	case "patch":
+		return http.MethodPatch, nil
+	case "delete", "deletecollection":
+		return http.MethodDelete, nil
+	default:
+		return "", fmt.Errorf("unsupported verb %q", verb)
+	}
+}
+
+// auditRequestBuilder builds request from AuditRequest.
+type auditRequestBuilder struct {
+	req         *AuditRequest
+	impersonate bool
+	maxRetries  int
+}
+
+// Build implements RequestBuilder.Build.
+func (b *auditRequestBuilder) Build(cli rest.Interface) Requester {
+	// The verb has been validated by LoadAuditRequests.
+	httpMethod, _ := auditVerbMethod(b.req.Verb)
+
+	req := cli.Verb(httpMethod).RequestURI(b.req.RequestURI).MaxRetries(b.maxRetries)
+	if b.req.Body != nil {
+		req = req.Body(b.req.Body).SetHeader("Content-Type", b.req.ContentType)
+	}
+
+	if b.impersonate {
+		req = req.SetHeader("Impersonate-User", b.req.User.Username)
+		if len(b.req.User.Groups) > 0 {
+			req = req.SetHeader("Impersonate-Group", b.req.User.Groups...)
+		}
+		if b.req.User.UID != "" {
+			req = req.SetHeader("Impersonate-Uid", b.req.User.UID)
+		}
+	}
+
+	method := httpMethod
+	if b.req.Verb == "list" {
+		method = "LIST"
+	}
+	return &auditRequester{
+		DiscardRequester: DiscardRequester{
+			BaseRequester: BaseRequester{
+				method: method,
+				req:    req,
+			},
+		},
+	}
+}
+
+// volatileAuditQueryParams are query parameters which vary among requests
+// of the same kind, like pagination tokens.
+var volatileAuditQueryParams = []string{"resourceVersion", "continue", "timeoutSeconds", "timeout"}
+
+// auditRequester is DiscardRequester of replayed audit request.
+type auditRequester struct {
+	DiscardRequester
+}
+
+// MaskedURL masks object name for every verb and drops volatile query
+// parameters, since audit logs carry arbitrary request URIs and metrics
+// are keyed by masked URL.
+func (reqr *auditRequester) MaskedURL() *url.URL {
+	u := *reqr.URL()
+
+	segs := strings.Split(strings.Trim(u.Path, "/"), "/")
+	var idx int
+	switch {
+	case len(segs) >= 2 && segs[0] == "api":
+		idx = 2
+	case len(segs) >= 3 && segs[0] == "apis":
+		idx = 3
+	default:
+		idx = len(segs)
+	}
+	// /namespaces/{namespace} is the namespace object itself.
+	if idx+2 < len(segs) && segs[idx] == "namespaces" {
+		idx += 2
+	}
+	if idx+1 < len(segs) {
+		segs[idx+1] = ":name"
+		u.Path = "/" + strings.Join(segs, "/")
+		u.RawPath = ""
+	}
+
+	query := u.Query()
+	for _, key := range volatileAuditQueryParams {
+		query.Del(key)
+	}
+	u.RawQuery = query.Encode()
+	return &u
+}
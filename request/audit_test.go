@@ -0,0 +1,104 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/request/unstructuredscheme"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+const testAuditLog = `
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"1","stage":"ResponseComplete","requestURI":"/api/v1/namespaces/default/pods?limit=500","verb":"list","user":{"username":"alice","groups":["dev"]},"objectRef":{"resource":"pods","namespace":"default","apiVersion":"v1"},"requestReceivedTimestamp":"2024-01-01T00:00:01.000000Z","stageTimestamp":"2024-01-01T00:00:01.500000Z"}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Request","auditID":"0","stage":"RequestReceived","requestURI":"/api/v1/namespaces/default/configmaps","verb":"create","user":{"username":"bob"},"objectRef":{"resource":"configmaps","namespace":"default","apiVersion":"v1"},"requestObject":{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"x"}},"requestReceivedTimestamp":"2024-01-01T00:00:00.000000Z","stageTimestamp":"2024-01-01T00:00:00.000000Z"}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Request","auditID":"0","stage":"ResponseComplete","requestURI":"/api/v1/namespaces/default/configmaps","verb":"create","user":{"username":"bob"},"objectRef":{"resource":"configmaps","namespace":"default","apiVersion":"v1"},"requestObject":{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"x"}},"requestReceivedTimestamp":"2024-01-01T00:00:00.000000Z","stageTimestamp":"2024-01-01T00:00:00.100000Z"}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"2","stage":"ResponseComplete","requestURI":"/api/v1/namespaces/default/pods/x","verb":"patch","user":{"username":"bob"},"objectRef":{"resource":"pods","namespace":"default","apiVersion":"v1"},"requestReceivedTimestamp":"2024-01-01T00:00:02.000000Z","stageTimestamp":"2024-01-01T00:00:02.100000Z"}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"3","stage":"ResponseStarted","requestURI":"/api/v1/pods?watch=true","verb":"watch","user":{"username":"alice"},"objectRef":{"resource":"pods","apiVersion":"v1"},"requestReceivedTimestamp":"2024-01-01T00:00:03.000000Z","stageTimestamp":"2024-01-01T00:00:03.000000Z"}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"RequestResponse","auditID":"4","stage":"ResponseComplete","requestURI":"/api/v1/namespaces/default/pods/x/status","verb":"patch","user":{"username":"system:serviceaccount:default:sa"},"impersonatedUser":{"username":"carol"},"objectRef":{"resource":"pods","subresource":"status","namespace":"default","apiVersion":"v1"},"requestObject":[{"op":"remove","path":"/status/x"}],"requestReceivedTimestamp":"2024-01-01T00:00:04.000000Z","stageTimestamp":"2024-01-01T00:00:04.100000Z"}
+`
+
+func TestLoadAuditRequests(t *testing.T) {
+	reqs, err := LoadAuditRequests(strings.NewReader(testAuditLog), AuditEventFilter{})
+	require.NoError(t, err)
+	require.Len(t, reqs, 3)
+
+	assert.Equal(t, "create", reqs[0].Verb)
+	assert.Equal(t, time.Duration(0), reqs[0].Offset)
+	assert.Equal(t, "bob", reqs[0].User.Username)
+	assert.Equal(t, "application/json", reqs[0].ContentType)
+	assert.JSONEq(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"x"}}`, string(reqs[0].Body))
+
+	assert.Equal(t, "list", reqs[1].Verb)
+	assert.Equal(t, time.Second, reqs[1].Offset)
+	assert.Equal(t, "/api/v1/namespaces/default/pods?limit=500", reqs[1].RequestURI)
+	assert.Nil(t, reqs[1].Body)
+
+	assert.Equal(t, "patch", reqs[2].Verb)
+	assert.Equal(t, 4*time.Second, reqs[2].Offset)
+	assert.Equal(t, "carol", reqs[2].User.Username)
+	assert.Equal(t, string(apitypes.JSONPatchType), reqs[2].ContentType)
+
+	reqs, err = LoadAuditRequests(strings.NewReader(testAuditLog), AuditEventFilter{
+		Resources: []string{"pods/status"},
+	})
+	require.NoError(t, err)
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "/api/v1/namespaces/default/pods/x/status", reqs[0].RequestURI)
+	assert.Equal(t, time.Duration(0), reqs[0].Offset)
+
+	reqs, err = LoadAuditRequests(strings.NewReader(testAuditLog), AuditEventFilter{
+		Verbs: []string{"list"},
+		Users: []string{"bob"},
+	})
+	require.NoError(t, err)
+	assert.Len(t, reqs, 0)
+}
+
+func TestGuessPatchType(t *testing.T) {
+	assert.Equal(t, apitypes.JSONPatchType, guessPatchType([]byte(` [{"op":"remove","path":"/a"}]`), "/api/v1/pods/x"))
+	assert.Equal(t, apitypes.MergePatchType, guessPatchType([]byte(`{"metadata":{"labels":{"a":"b"}}}`), "/api/v1/pods/x"))
+	assert.Equal(t, apitypes.MergePatchType, guessPatchType([]byte(`{"apiVersion":"v1","kind":"Pod"}`), "/api/v1/pods/x"))
+	assert.Equal(t, apitypes.ApplyPatchType, guessPatchType([]byte(`{"apiVersion":"v1","kind":"Pod"}`), "/api/v1/pods/x?fieldManager=kubectl"))
+}
+
+func TestReplayAuditRequestsMaskedURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	restCfg := &rest.Config{
+		Host: srv.URL,
+		// Make transport uncacheable.
+		Proxy: http.ProxyFromEnvironment,
+	}
+	restCfg.NegotiatedSerializer = unstructuredscheme.NewNegotiatedSerializer()
+	cli, err := rest.UnversionedRESTClientFor(restCfg)
+	require.NoError(t, err)
+
+	reqs := []*AuditRequest{
+		{Verb: "get", RequestURI: "/api/v1/namespaces/default/pods/a"},
+		{Offset: 10 * time.Millisecond, Verb: "get", RequestURI: "/api/v1/namespaces/default/pods/b?resourceVersion=10"},
+	}
+
+	result, err := ReplayAuditRequests(context.Background(), reqs,
+		AuditReplayOptions{ReplayOptions: ReplayOptions{Client: 1}}, []rest.Interface{cli})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Total)
+	require.Len(t, result.LatencyHistogramsByURL, 1)
+	for u := range result.LatencyHistogramsByURL {
+		assert.Equal(t, "GET "+srv.URL+"/api/v1/namespaces/default/pods/:name", u)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// throughputBucket is the resolution of throughputRecorder.
+	throughputBucket = 100 * time.Millisecond
+	// recoveryBaselineWindow is the window before churn event used as the
+	// baseline throughput.
+	recoveryBaselineWindow = 5 * time.Second
+	// recoveryRatio is the ratio of baseline throughput regarded as
+	// recovered.
+	recoveryRatio = 0.9
+)
+
+// throughputRecorder counts succeeded requests in fixed-size time buckets.
+type throughputRecorder struct {
+	mu      sync.Mutex
+	start   time.Time
+	buckets []int
+}
+
+// newThroughputRecorder returns new instance of throughputRecorder.
+func newThroughputRecorder(start time.Time) *throughputRecorder {
+	return &throughputRecorder{start: start}
+}
+
+// observe records one succeeded request finished at now.
+func (r *throughputRecorder) observe(now time.Time) {
+	idx := int(now.Sub(r.start) / throughputBucket)
+	if idx < 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for len(r.buckets) <= idx {
+		r.buckets = append(r.buckets, 0)
+	}
+	r.buckets[idx]++
+}
+
+// count returns the number of succeeded requests in [from, from+d).
+func (r *throughputRecorder) count(from time.Time, d time.Duration) int {
+	first := int(from.Sub(r.start) / throughputBucket)
+	last := first + int(d/throughputBucket)
+
+	total := 0
+	for idx := max(first, 0); idx < last && idx < len(r.buckets); idx++ {
+		total += r.buckets[idx]
+	}
+	return total
+}
+
+// recoverySeconds returns how long it took the throughput over one second
+// to get back to recoveryRatio of the baseline after at.
+func (r *throughputRecorder) recoverySeconds(at, end time.Time) *float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Align to bucket so that partial bucket isn't counted.
+	at = r.start.Add(at.Sub(r.start).Truncate(throughputBucket))
+
+	baselineFrom := at.Add(-recoveryBaselineWindow)
+	if baselineFrom.Before(r.start) {
+		baselineFrom = r.start
+	}
+	window := at.Sub(baselineFrom)
+	if window < time.Second {
+		return nil
+	}
+	baseline := float64(r.count(baselineFrom, window)) / window.Seconds()
+	if baseline == 0 {
+		return nil
+	}
+
+	for from := at; !from.Add(time.Second).After(end); from = from.Add(throughputBucket) {
+		if float64(r.count(from, time.Second)) >= baseline*recoveryRatio {
+			res := from.Sub(at).Seconds()
+			return &res
+		}
+	}
+	return nil
+}
+
+// runConnChurn closes connections tracked by ct based on churn until ctx is
+// done, and returns the events in time order.
+func runConnChurn(ctx context.Context, churn *types.ConnChurn, ct *ConnTracker) []types.ConnChurnEvent {
+	var events []types.ConnChurnEvent
+
+	// nextPeriod returns a nil channel if it's disabled so that it blocks
+	// forever.
+	nextPeriod := func() <-chan time.Time {
+		if churn.Interval <= 0 {
+			return nil
+		}
+		d := churn.Interval + rand.Float64()*churn.Jitter //nolint:gosec
+		return time.After(time.Duration(d * float64(time.Second)))
+	}
+
+	var stormCh <-chan time.Time
+	if churn.StormAt > 0 {
+		stormTimer := time.NewTimer(time.Duration(churn.StormAt * float64(time.Second)))
+		defer stormTimer.Stop()
+		stormCh = stormTimer.C
+	}
+
+	periodCh := nextPeriod()
+	for {
+		select {
+		case <-ctx.Done():
+			return events
+		case now := <-stormCh:
+			closed := ct.closeConns(1)
+			klog.V(2).InfoS("Closed all connections", "closed", closed)
+			events = append(events, types.ConnChurnEvent{Timestamp: now, Storm: true, Closed: closed})
+		case now := <-periodCh:
+			closed := ct.closeConns(churn.Fraction)
+			klog.V(5).InfoS("Closed connections", "closed", closed)
+			events = append(events, types.ConnChurnEvent{Timestamp: now, Closed: closed})
+			periodCh = nextPeriod()
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThroughputRecorderRecoverySeconds(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := newThroughputRecorder(start)
+
+	// 10 requests per 100ms for the first 5 seconds, nothing for 2
+	// seconds and then back to normal.
+	for ms := 0; ms < 10000; ms += 10 {
+		if ms >= 5000 && ms < 7000 {
+			continue
+		}
+		r.observe(start.Add(time.Duration(ms) * time.Millisecond))
+	}
+	end := start.Add(10 * time.Second)
+
+	got := r.recoverySeconds(start.Add(5*time.Second), end)
+	require.NotNil(t, got)
+	// The first one-second window with >= 90 requests is [6.9s, 7.9s).
+	assert.InDelta(t, 1.9, *got, 1e-9)
+
+	// No baseline.
+	assert.Nil(t, r.recoverySeconds(start.Add(500*time.Millisecond), end))
+
+	// Never recovered.
+	assert.Nil(t, r.recoverySeconds(start.Add(5*time.Second), start.Add(6*time.Second)))
+}
+
+func TestConnTrackerCloseConns(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ct := NewConnTracker()
+	conns := make([]net.Conn, 0, 4)
+	for i := 0; i < 4; i++ {
+		conn, err := ct.DialContext(context.Background(), "tcp", lis.Addr().String())
+		require.NoError(t, err)
+		conns = append(conns, conn)
+	}
+
+	assert.Equal(t, 2, ct.closeConns(0.5))
+	assert.Len(t, ct.conns, 2)
+
+	// Closing by caller untracks the connection only once.
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	assert.Len(t, ct.conns, 0)
+	assert.Equal(t, 0, ct.closeConns(1))
+
+	report := ct.report()
+	assert.Equal(t, 4, report.Dials)
+	assert.Len(t, report.DialLatencies, 4)
+	assert.Equal(t, 2, report.Closed)
+
+	ct.reset()
+	assert.Equal(t, 0, ct.report().Dials)
+}
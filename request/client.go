@@ -17,10 +17,7 @@ import (
 
 // NewClients creates N rest.Interface.
 //
-// FIXME(weifu):
-//
-// 1. Is it possible to build one http2 client with multiple connections?
-// 2. How to monitor HTTP2 GOAWAY frame?
+// FIXME(weifu): Is it possible to build one http2 client with multiple connections?
 func NewClients(kubeCfgPath string, connsNum int, opts ...ClientCfgOpt) ([]rest.Interface, error) {
 	var cfg = defaultClientCfg
 	for _, opt := range opts {
@@ -43,6 +40,15 @@ func NewClients(kubeCfgPath string, connsNum int, opts ...ClientCfgOpt) ([]rest.
 	// REF: https://github.com/kubernetes/client-go/blob/c5938c6876a62f53c1f4ee55b879ca5c74253ae8/transport/cache.go#L154
 	restCfg.Proxy = http.ProxyFromEnvironment
 
+	// Record requests sent over the wire for trace. It's no-op unless the
+	// request's context asks for it.
+	restCfg.Wrap(newTraceRoundTripper)
+	// Record API Priority and Fairness headers in responses, including
+	// the ones retried by client-go.
+	restCfg.Wrap(newFlowControlRoundTripper)
+	// Propagate W3C trace context of sampled requests.
+	restCfg.Wrap(newTraceParentRoundTripper)
+
 	err = cfg.apply(restCfg)
 	if err != nil {
 		return nil, err
@@ -52,7 +58,13 @@ func NewClients(kubeCfgPath string, connsNum int, opts ...ClientCfgOpt) ([]rest.
 	for i := 0; i < connsNum; i++ {
 		cfgShallowCopy := *restCfg
 
-		restCli, err := rest.UnversionedRESTClientFor(&cfgShallowCopy)
+		if len(cfg.endpoints) > 0 {
+			if err := pinToEndpoint(&cfgShallowCopy, cfg.endpoints[i%len(cfg.endpoints)]); err != nil {
+				return nil, err
+			}
+		}
+
+		restCli, err := newRESTClient(&cfg, &cfgShallowCopy, i)
 		if err != nil {
 			return nil, err
 		}
@@ -61,6 +73,32 @@ func NewClients(kubeCfgPath string, connsNum int, opts ...ClientCfgOpt) ([]rest.
 	return restClients, nil
 }
 
+// newRESTClient creates the idx-th rest.Interface. HTTP/2 frames are
+// observed by the connection tracker if any.
+func newRESTClient(cfg *clientCfg, restCfg *rest.Config, idx int) (rest.Interface, error) {
+	var stats *http2ConnStats
+	if cfg.connTracker != nil {
+		stats = cfg.connTracker.http2Conn(idx)
+	}
+
+	var (
+		httpCli *http.Client
+		err     error
+	)
+	switch {
+	case useHTTP2Transport(cfg, restCfg):
+		httpCli, err = newHTTP2Client(restCfg, cfg.http2, stats)
+	case useHTTP2ObservedTransport(cfg, restCfg):
+		httpCli, err = newHTTP2ObservedClient(restCfg, stats)
+	default:
+		return rest.UnversionedRESTClientFor(restCfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rest.UnversionedRESTClientForConfigAndClient(restCfg, httpCli)
+}
+
 // defaultClientCfg is default setting for http client.
 var defaultClientCfg = clientCfg{
 	qps:         float64(math.MaxInt32),
@@ -72,6 +110,9 @@ type clientCfg struct {
 	qps          float64
 	contentType  types.ContentType
 	disableHTTP2 bool
+	connTracker  *ConnTracker
+	endpoints    []string
+	http2        *types.HTTP2Spec
 }
 
 // apply sets value to k8s.io/client-go/rest.Config.
@@ -91,6 +132,10 @@ func (cfg *clientCfg) apply(restCfg *rest.Config) error {
 		restCfg.ContentType = "application/json"
 	case types.ContentTypeProtobuffer:
 		restCfg.ContentType = "application/vnd.kubernetes.protobuf"
+	case types.ContentTypeCBOR:
+		// Request bodies are built in json.
+		restCfg.ContentType = "application/json"
+		restCfg.AcceptContentTypes = "application/cbor,application/json"
 	default:
 		return fmt.Errorf("invalid content type: %s", cfg.contentType)
 	}
@@ -99,6 +144,10 @@ func (cfg *clientCfg) apply(restCfg *rest.Config) error {
 	if cfg.disableHTTP2 {
 		restCfg.NextProtos = []string{"http/1.1"}
 	}
+
+	if cfg.connTracker != nil {
+		restCfg.Dial = cfg.connTracker.DialContext
+	}
 	return nil
 }
 
@@ -134,3 +183,26 @@ func WithClientDisableHTTP2Opt(b bool) ClientCfgOpt {
 		cfg.disableHTTP2 = b
 	}
 }
+
+// WithClientConnTrackerOpt tracks connections by ConnTracker.
+func WithClientConnTrackerOpt(ct *ConnTracker) ClientCfgOpt {
+	return func(cfg *clientCfg) {
+		cfg.connTracker = ct
+	}
+}
+
+// WithClientEndpointsOpt pins the i-th client to endpoints[i % len(endpoints)].
+// The format of endpoint is IP or IP:PORT.
+func WithClientEndpointsOpt(endpoints []string) ClientCfgOpt {
+	return func(cfg *clientCfg) {
+		cfg.endpoints = endpoints
+	}
+}
+
+// WithClientHTTP2Opt applies HTTP/2 settings. It's ignored if HTTP/2 is
+// disabled.
+func WithClientHTTP2Opt(spec *types.HTTP2Spec) ClientCfgOpt {
+	return func(cfg *clientCfg) {
+		cfg.http2 = spec
+	}
+}
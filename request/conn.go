@@ -0,0 +1,214 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"crypto/tls"
+	"math"
+	"math/rand"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/metrics"
+)
+
+// ConnTracker tracks connections dialed by clients so that they can be torn
+// down on purpose, and records dials and TLS handshakes.
+type ConnTracker struct {
+	dialer *net.Dialer
+
+	mu    sync.Mutex
+	conns map[*trackedConn]struct{}
+	stats connStats
+	// http2Conns stores the HTTP/2 stats of each client by index.
+	http2Conns []*http2ConnStats
+}
+
+// connStats is the observation since the last reset.
+type connStats struct {
+	dials              int
+	dialErrors         int
+	dialLatencies      []float64
+	tlsHandshakes      int
+	tlsHandshakeErrors int
+	closed             int
+}
+
+// NewConnTracker returns new instance of ConnTracker.
+func NewConnTracker() *ConnTracker {
+	return &ConnTracker{
+		// Align with k8s.io/client-go/transport's default dialer.
+		dialer: &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+		conns: map[*trackedConn]struct{}{},
+	}
+}
+
+// DialContext dials and tracks the connection.
+func (t *ConnTracker) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	start := time.Now()
+	conn, err := t.dialer.DialContext(ctx, network, address)
+	latency := time.Since(start).Seconds()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stats.dials++
+	if err != nil {
+		t.stats.dialErrors++
+		return nil, err
+	}
+	t.stats.dialLatencies = append(t.stats.dialLatencies, latency)
+
+	tc := &trackedConn{Conn: conn, t: t}
+	t.conns[tc] = struct{}{}
+	return tc, nil
+}
+
+// withClientTrace returns context which reports TLS handshakes to tracker.
+//
+// NOTE: The handshake is performed by http.Transport after dial so that
+// it can't be observed by DialContext.
+func (t *ConnTracker) withClientTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+
+			t.stats.tlsHandshakes++
+			if err != nil {
+				t.stats.tlsHandshakeErrors++
+			}
+		},
+	})
+}
+
+// closeConns closes the given fraction of live connections, at least one,
+// and returns the number of closed connections.
+func (t *ConnTracker) closeConns(fraction float64) int {
+	t.mu.Lock()
+	conns := make([]*trackedConn, 0, len(t.conns))
+	for c := range t.conns {
+		conns = append(conns, c)
+	}
+	t.mu.Unlock()
+
+	if len(conns) == 0 || fraction <= 0 {
+		return 0
+	}
+
+	n := int(math.Ceil(float64(len(conns)) * math.Min(fraction, 1)))
+	rand.Shuffle(len(conns), func(i, j int) {
+		conns[i], conns[j] = conns[j], conns[i]
+	})
+
+	closed := 0
+	for _, c := range conns[:n] {
+		if first, _ := c.close(); first {
+			closed++
+		}
+	}
+
+	t.mu.Lock()
+	t.stats.closed += closed
+	t.mu.Unlock()
+	return closed
+}
+
+// http2Conn returns the HTTP/2 stats of the idx-th client.
+func (t *ConnTracker) http2Conn(idx int) *http2ConnStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for len(t.http2Conns) <= idx {
+		t.http2Conns = append(t.http2Conns, &http2ConnStats{})
+	}
+	return t.http2Conns[idx]
+}
+
+// reset clears the observation but keeps tracking live connections.
+func (t *ConnTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stats = connStats{}
+	for _, s := range t.http2Conns {
+		s.reset()
+	}
+}
+
+// report returns the observation since the last reset.
+func (t *ConnTracker) report() *types.ConnectionReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	latencies := append([]float64(nil), t.stats.dialLatencies...)
+
+	var http2Report *types.HTTP2Report
+	if len(t.http2Conns) > 0 {
+		http2Report = &types.HTTP2Report{
+			PerConnection: make([]types.HTTP2ConnectionReport, 0, len(t.http2Conns)),
+		}
+		for _, s := range t.http2Conns {
+			r := s.snapshot()
+			http2Report.GoAways += r.GoAways
+			http2Report.RefusedStreams += r.RefusedStreams
+			http2Report.Replacements += r.Replacements
+			http2Report.LostPings += r.LostPings
+			http2Report.PerConnection = append(http2Report.PerConnection, r)
+		}
+	}
+
+	dialHistogram := metrics.HistogramFromLatencies(latencies)
+	return &types.ConnectionReport{
+		Dials:                   t.stats.dials,
+		DialErrors:              t.stats.dialErrors,
+		DialLatencies:           latencies,
+		DialLatencyHistogram:    dialHistogram,
+		PercentileDialLatencies: metrics.BuildPercentileLatenciesFromHistogram(dialHistogram),
+		TLSHandshakes:           t.stats.tlsHandshakes,
+		TLSHandshakeErrors:      t.stats.tlsHandshakeErrors,
+		Closed:                  t.stats.closed,
+		HTTP2:                   http2Report,
+	}
+}
+
+// untrack removes the closed connection.
+func (t *ConnTracker) untrack(c *trackedConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.conns, c)
+}
+
+// trackedConn untracks itself once it's closed.
+type trackedConn struct {
+	net.Conn
+
+	t    *ConnTracker
+	once sync.Once
+}
+
+// Close implements net.Conn.
+func (c *trackedConn) Close() error {
+	_, err := c.close()
+	return err
+}
+
+// close closes the connection and returns true if it's the first close.
+func (c *trackedConn) close() (first bool, err error) {
+	err = net.ErrClosed
+	c.once.Do(func() {
+		first = true
+		err = c.Conn.Close()
+		c.t.untrack(c)
+	})
+	return first, err
+}
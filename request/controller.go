@@ -0,0 +1,89 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const (
+	// defaultControllerQPS aligns with client-go's rest.DefaultQPS.
+	defaultControllerQPS = 5
+	// defaultControllerBurst aligns with client-go's rest.DefaultBurst.
+	defaultControllerBurst = 10
+	// defaultControllerMaxRetries aligns with rest.Request's default.
+	defaultControllerMaxRetries = 10
+	// defaultControllerBackoffBase is the initial delay of URL backoff.
+	defaultControllerBackoffBase = time.Second
+	// defaultControllerBackoffMax is the maximum delay of URL backoff.
+	defaultControllerBackoffMax = 10 * time.Second
+)
+
+// maxRetries returns the ceiling of retries for requests built by spec.
+func maxRetries(spec *types.LoadProfileSpec) int {
+	emu := spec.ControllerEmulation
+	if emu == nil {
+		return spec.MaxRetries
+	}
+
+	switch {
+	case emu.MaxRetries < 0:
+		return 0
+	case emu.MaxRetries == 0:
+		return defaultControllerMaxRetries
+	default:
+		return emu.MaxRetries
+	}
+}
+
+// clientThrottle is the client-side throttling of one client, which is the
+// same as a controller built on client-go's defaults.
+//
+// NOTE: rest.Interface shares one rate limiter across all the clients which
+// reuse the connection. So the throttling is applied per client instead.
+type clientThrottle struct {
+	limiter flowcontrol.RateLimiter
+	backoff rest.BackoffManager
+}
+
+// newClientThrottle returns new instance of clientThrottle.
+func newClientThrottle(spec *types.ControllerEmulationSpec) *clientThrottle {
+	qps := float32(spec.QPS)
+	if qps == 0 {
+		qps = defaultControllerQPS
+	}
+	burst := spec.Burst
+	if burst == 0 {
+		burst = defaultControllerBurst
+	}
+
+	var backoff rest.BackoffManager = &rest.NoBackoff{}
+	if spec.BackoffBase >= 0 {
+		base, max := defaultControllerBackoffBase, defaultControllerBackoffMax
+		if spec.BackoffBase > 0 {
+			base = time.Duration(spec.BackoffBase * float64(time.Second))
+		}
+		if spec.BackoffMax > 0 {
+			max = time.Duration(spec.BackoffMax * float64(time.Second))
+		}
+		backoff = &rest.URLBackoff{Backoff: flowcontrol.NewBackOff(base, max)}
+	}
+
+	return &clientThrottle{
+		limiter: flowcontrol.NewTokenBucketRateLimiter(qps, burst),
+		backoff: backoff,
+	}
+}
+
+// wait blocks until the client is allowed to send the request.
+func (t *clientThrottle) wait(ctx context.Context, req Requester) error {
+	req.BackOff(t.backoff)
+	return t.limiter.Wait(ctx)
+}
@@ -0,0 +1,43 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"testing"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/rest"
+)
+
+func TestMaxRetries(t *testing.T) {
+	spec := &types.LoadProfileSpec{MaxRetries: 3}
+	assert.Equal(t, 3, maxRetries(spec))
+
+	spec.ControllerEmulation = &types.ControllerEmulationSpec{}
+	assert.Equal(t, defaultControllerMaxRetries, maxRetries(spec))
+
+	spec.ControllerEmulation.MaxRetries = -1
+	assert.Equal(t, 0, maxRetries(spec))
+
+	spec.ControllerEmulation.MaxRetries = 2
+	assert.Equal(t, 2, maxRetries(spec))
+}
+
+func TestNewClientThrottle(t *testing.T) {
+	throttle := newClientThrottle(&types.ControllerEmulationSpec{})
+	assert.Equal(t, float32(defaultControllerQPS), throttle.limiter.QPS())
+	assert.IsType(t, &rest.URLBackoff{}, throttle.backoff)
+
+	// Burst allows that many requests without waiting.
+	for i := 0; i < defaultControllerBurst; i++ {
+		assert.True(t, throttle.limiter.TryAccept())
+	}
+	assert.False(t, throttle.limiter.TryAccept())
+
+	throttle = newClientThrottle(&types.ControllerEmulationSpec{QPS: 50, BackoffBase: -1})
+	assert.Equal(t, float32(50), throttle.limiter.QPS())
+	assert.IsType(t, &rest.NoBackoff{}, throttle.backoff)
+}
@@ -0,0 +1,261 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	rtmetrics "runtime/metrics"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/runtime/serializer/cbor"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	mediaTypeJSON     = "application/json"
+	mediaTypeProtobuf = "application/vnd.kubernetes.protobuf"
+	mediaTypeCBOR     = "application/cbor"
+)
+
+var (
+	// metaScheme only knows meta.k8s.io/v1 objects, for instance,
+	// PartialObjectMetadataList.
+	metaScheme = runtime.NewScheme()
+	metaCodecs = serializer.NewCodecFactory(metaScheme)
+
+	cborSerializer = cbor.NewSerializer(metaScheme, metaScheme)
+)
+
+func init() {
+	metav1.AddToGroupVersion(metaScheme, schema.GroupVersion{Version: "v1"})
+	if err := metav1.AddMetaToScheme(metaScheme); err != nil {
+		panic(err)
+	}
+}
+
+// decodeInfoKey is the context key of decodeInfo.
+type decodeInfoKey struct{}
+
+// decodeInfo records the cost of decoding the response.
+type decodeInfo struct {
+	// received is when the whole response has been received, before
+	// decoding. The latency of request ends at received so that it doesn't
+	// include client-side decoding.
+	received time.Time
+	// decoded is true if the response body has been decoded.
+	decoded        bool
+	seconds        float64
+	allocatedBytes uint64
+	// items is the number of items if the response is a list, or -1.
+	items int
+}
+
+// withDecodeInfo returns context which asks responseDecoder to fill info.
+func withDecodeInfo(ctx context.Context, info *decodeInfo) context.Context {
+	return context.WithValue(ctx, decodeInfoKey{}, info)
+}
+
+// ResponseAssertionError means that the response didn't match
+// WeightedRequest.Expect.
+type ResponseAssertionError struct {
+	// Message shows the mismatch.
+	Message string
+}
+
+// Error implements error interface.
+func (e *ResponseAssertionError) Error() string {
+	return "assertion failed: " + e.Message
+}
+
+// AssertionFailure implements metrics.AssertionFailure.
+func (e *ResponseAssertionError) AssertionFailure() string {
+	return e.Message
+}
+
+// responseDecoder reads the whole response, decodes it into objects and
+// asserts on it.
+type responseDecoder struct {
+	target      types.DecodeTarget
+	contentType types.ContentType
+	expect      *types.ResponseExpectation
+	// list is true if the response is expected to be a list.
+	list bool
+}
+
+// newResponseDecoder returns new instance of responseDecoder for the given
+// kind of request.
+func newResponseDecoder(spec *types.LoadProfileSpec, r *types.WeightedRequest) *responseDecoder {
+	target := spec.Decode.Target
+	if target == "" {
+		target = types.DecodeTargetUnstructured
+	}
+	return &responseDecoder{
+		target:      target,
+		contentType: spec.ContentType,
+		expect:      r.Expect,
+		list:        r.StaleList != nil || r.QuorumList != nil,
+	}
+}
+
+// accept returns the Accept header asking for metadata-only objects, or
+// empty if the default is fine.
+//
+// REF: https://github.com/kubernetes/client-go/blob/v0.31.1/metadata/metadata.go
+func (d *responseDecoder) accept() string {
+	if d.target != types.DecodeTargetMetadata {
+		return ""
+	}
+
+	kind := "PartialObjectMetadata"
+	if d.list {
+		kind = "PartialObjectMetadataList"
+	}
+	params := ";as=" + kind + ";g=meta.k8s.io;v=v1"
+
+	switch d.contentType {
+	case types.ContentTypeProtobuffer:
+		return mediaTypeProtobuf + params + "," + mediaTypeJSON + params + "," + mediaTypeJSON
+	case types.ContentTypeCBOR:
+		return mediaTypeCBOR + params + "," + mediaTypeJSON + params + "," + mediaTypeJSON
+	default:
+		return mediaTypeJSON + params + "," + mediaTypeJSON
+	}
+}
+
+// do sends the request, decodes the response and returns the size of
+// response body.
+func (d *responseDecoder) do(ctx context.Context, req *rest.Request) (int64, error) {
+	if accept := d.accept(); accept != "" {
+		req.SetHeader("Accept", accept)
+	}
+
+	result := req.Do(ctx)
+
+	info, _ := ctx.Value(decodeInfoKey{}).(*decodeInfo)
+	if info == nil {
+		info = &decodeInfo{}
+	}
+	info.received = time.Now()
+
+	var code int
+	result.StatusCode(&code)
+
+	body, err := result.Raw()
+	bytes := int64(len(body))
+	if err != nil {
+		if d.expect != nil && d.expect.StatusCode != 0 && code == d.expect.StatusCode {
+			return bytes, nil
+		}
+		return bytes, err
+	}
+
+	if d.expect != nil && d.expect.StatusCode != 0 && code != d.expect.StatusCode {
+		return bytes, &ResponseAssertionError{
+			Message: fmt.Sprintf("expected status code %d, got %d", d.expect.StatusCode, code),
+		}
+	}
+
+	var contentType string
+	result.ContentType(&contentType)
+
+	if err := d.decode(contentType, body, info); err != nil {
+		return bytes, fmt.Errorf("failed to decode %s response: %w", contentType, err)
+	}
+	return bytes, d.assertItems(info.items)
+}
+
+// decode decodes body based on the content type and fills info.
+func (d *responseDecoder) decode(contentType string, body []byte, info *decodeInfo) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return err
+	}
+
+	startAllocated := heapAllocatedBytes()
+	start := time.Now()
+
+	var obj runtime.Object
+	switch mediaType {
+	case mediaTypeJSON:
+		if d.target == types.DecodeTargetMetadata {
+			obj, _, err = metaCodecs.UniversalDeserializer().Decode(body, nil, nil)
+		} else {
+			obj, err = runtime.Decode(unstructured.UnstructuredJSONScheme, body)
+		}
+	case mediaTypeProtobuf:
+		if d.target == types.DecodeTargetMetadata {
+			obj, _, err = metaCodecs.UniversalDeserializer().Decode(body, nil, nil)
+		} else {
+			obj, _, err = scheme.Codecs.UniversalDeserializer().Decode(body, nil, nil)
+		}
+	case mediaTypeCBOR:
+		var into runtime.Object
+		if d.target != types.DecodeTargetMetadata {
+			into = &unstructured.Unstructured{}
+			if d.list {
+				into = &unstructured.UnstructuredList{}
+			}
+		}
+		obj, _, err = cborSerializer.Decode(body, nil, into)
+	default:
+		return fmt.Errorf("unsupported media type %s", mediaType)
+	}
+
+	info.seconds = time.Since(start).Seconds()
+	info.allocatedBytes = heapAllocatedBytes() - startAllocated
+	if err != nil {
+		return err
+	}
+
+	info.decoded = true
+	info.items = -1
+	if meta.IsListType(obj) {
+		info.items = meta.LenList(obj)
+	}
+	return nil
+}
+
+// assertItems returns ResponseAssertionError if the number of items
+// doesn't match the expectation.
+func (d *responseDecoder) assertItems(items int) error {
+	if d.expect == nil || items < 0 {
+		return nil
+	}
+
+	if d.expect.Items != nil && items != *d.expect.Items {
+		return &ResponseAssertionError{
+			Message: fmt.Sprintf("expected %d items, got %d", *d.expect.Items, items),
+		}
+	}
+	if items < d.expect.MinItems {
+		return &ResponseAssertionError{
+			Message: fmt.Sprintf("expected at least %d items, got %d", d.expect.MinItems, items),
+		}
+	}
+	return nil
+}
+
+// heapAllocatedBytes returns the cumulative bytes allocated in heap by the
+// process.
+//
+// NOTE: It's cheaper than runtime.ReadMemStats which stops the world.
+func heapAllocatedBytes() uint64 {
+	sample := []rtmetrics.Sample{{Name: "/gc/heap/allocs:bytes"}}
+	rtmetrics.Read(sample)
+	if sample[0].Value.Kind() != rtmetrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,138 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/request/unstructuredscheme"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+)
+
+func TestResponseDecoder(t *testing.T) {
+	podList := `{"kind":"PodList","apiVersion":"v1","metadata":{},"items":[` +
+		`{"metadata":{"name":"a"}},{"metadata":{"name":"b"}},{"metadata":{"name":"c"}}]}`
+	metaList := `{"kind":"PartialObjectMetadataList","apiVersion":"meta.k8s.io/v1","metadata":{},"items":[` +
+		`{"metadata":{"name":"a"}},{"metadata":{"name":"b"}}]}`
+
+	var cborList bytes.Buffer
+	require.NoError(t, cborSerializer.Encode(&unstructured.UnstructuredList{
+		Object: map[string]interface{}{"kind": "PodList", "apiVersion": "v1"},
+		Items: []unstructured.Unstructured{
+			{Object: map[string]interface{}{"kind": "Pod", "apiVersion": "v1"}},
+		},
+	}, &cborList))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept := r.Header.Get("Accept")
+		switch {
+		case r.URL.Path == "/missing":
+			w.Header().Set("Content-Type", mediaTypeJSON)
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+		case strings.HasPrefix(accept, mediaTypeCBOR):
+			w.Header().Set("Content-Type", mediaTypeCBOR)
+			_, _ = w.Write(cborList.Bytes())
+		case strings.Contains(accept, "as=PartialObjectMetadataList"):
+			w.Header().Set("Content-Type", mediaTypeJSON)
+			_, _ = w.Write([]byte(metaList))
+		default:
+			w.Header().Set("Content-Type", mediaTypeJSON)
+			_, _ = w.Write([]byte(podList))
+		}
+	}))
+	defer srv.Close()
+
+	restCfg := &rest.Config{
+		Host: srv.URL,
+		// Make transport uncacheable.
+		Proxy: http.ProxyFromEnvironment,
+	}
+	restCfg.NegotiatedSerializer = unstructuredscheme.NewNegotiatedSerializer()
+	cli, err := rest.UnversionedRESTClientFor(restCfg)
+	require.NoError(t, err)
+
+	three, two := 3, 2
+	for _, tc := range []struct {
+		name    string
+		path    string
+		accept  string
+		decoder *responseDecoder
+		items   int
+		err     string
+	}{
+		{
+			name:    "unstructured",
+			path:    "/api/v1/pods",
+			decoder: &responseDecoder{target: types.DecodeTargetUnstructured, list: true, expect: &types.ResponseExpectation{Items: &three}},
+			items:   3,
+		},
+		{
+			name:    "items mismatch",
+			path:    "/api/v1/pods",
+			decoder: &responseDecoder{target: types.DecodeTargetUnstructured, list: true, expect: &types.ResponseExpectation{Items: &two}},
+			items:   3,
+			err:     "expected 2 items, got 3",
+		},
+		{
+			name:    "metadata",
+			path:    "/api/v1/pods",
+			decoder: &responseDecoder{target: types.DecodeTargetMetadata, list: true, expect: &types.ResponseExpectation{MinItems: 3}},
+			items:   2,
+			err:     "expected at least 3 items, got 2",
+		},
+		{
+			name:    "cbor",
+			path:    "/api/v1/pods",
+			accept:  "application/cbor,application/json",
+			decoder: &responseDecoder{target: types.DecodeTargetUnstructured, list: true},
+			items:   1,
+		},
+		{
+			name:    "expected status code",
+			path:    "/missing",
+			decoder: &responseDecoder{target: types.DecodeTargetUnstructured, expect: &types.ResponseExpectation{StatusCode: http.StatusNotFound}},
+		},
+		{
+			name:    "unexpected status code",
+			path:    "/api/v1/pods",
+			decoder: &responseDecoder{target: types.DecodeTargetUnstructured, list: true, expect: &types.ResponseExpectation{StatusCode: http.StatusNotFound}},
+			err:     "expected status code 404, got 200",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := cli.Get().AbsPath(tc.path)
+			if tc.accept != "" {
+				req = req.SetHeader("Accept", tc.accept)
+			}
+
+			info := &decodeInfo{}
+			n, err := tc.decoder.do(withDecodeInfo(context.Background(), info), req)
+			assert.Greater(t, n, int64(0))
+			assert.False(t, info.received.IsZero())
+			if tc.err != "" {
+				var aerr *ResponseAssertionError
+				require.ErrorAs(t, err, &aerr)
+				assert.Equal(t, tc.err, aerr.Message)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if tc.items > 0 {
+				assert.True(t, info.decoded)
+				assert.Equal(t, tc.items, info.items)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// DiscoverAPIServerEndpoints returns the ready apiserver endpoints in the
+// format of IP:PORT from the default/kubernetes EndpointSlice.
+func DiscoverAPIServerEndpoints(ctx context.Context, kubeCfgPath string) ([]string, error) {
+	restCfg, err := clientcmd.BuildConfigFromFlags("", kubeCfgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	slices, err := clientset.DiscoveryV1().EndpointSlices(metav1.NamespaceDefault).List(ctx,
+		metav1.ListOptions{LabelSelector: discoveryv1.LabelServiceName + "=kubernetes"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kubernetes endpointslices: %w", err)
+	}
+
+	res := make([]string, 0)
+	for _, slice := range slices.Items {
+		port := int32(0)
+		for _, p := range slice.Ports {
+			if p.Name != nil && *p.Name == "https" && p.Port != nil {
+				port = *p.Port
+			}
+		}
+		if port == 0 {
+			continue
+		}
+
+		for _, ep := range slice.Endpoints {
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+				continue
+			}
+			for _, addr := range ep.Addresses {
+				res = append(res, net.JoinHostPort(addr, strconv.Itoa(int(port))))
+			}
+		}
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no ready endpoints in kubernetes endpointslices")
+	}
+
+	sort.Strings(res)
+	return res, nil
+}
+
+// pinToEndpoint makes the client dial endpoint instead of the host in
+// restCfg. The host is still used as TLS server name so that the server's
+// certificate is verified as usual.
+func pinToEndpoint(restCfg *rest.Config, endpoint string) error {
+	u, err := url.Parse(restCfg.Host)
+	if err != nil {
+		return fmt.Errorf("failed to parse host %s: %w", restCfg.Host, err)
+	}
+
+	endpointHost, endpointPort := endpoint, ""
+	if h, p, err := net.SplitHostPort(endpoint); err == nil {
+		endpointHost, endpointPort = h, p
+	}
+
+	if restCfg.TLSClientConfig.ServerName == "" {
+		restCfg.TLSClientConfig.ServerName = u.Hostname()
+	}
+
+	dial := restCfg.Dial
+	if dial == nil {
+		// Align with k8s.io/client-go/transport's default dialer.
+		dial = (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+	restCfg.Dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+		port := endpointPort
+		if port == "" {
+			_, p, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+			port = p
+		}
+		return dial(ctx, network, net.JoinHostPort(endpointHost, port))
+	}
+	return nil
+}
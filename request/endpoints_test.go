@@ -0,0 +1,52 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+)
+
+func TestPinToEndpoint(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.ServerName))
+	}))
+	defer srv.Close()
+
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+
+	// The certificate of httptest is issued to example.com and 127.0.0.1.
+	// example.com is never resolved because the connection is pinned.
+	restCfg := &rest.Config{
+		Host: "https://example.com:" + port,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}),
+		},
+		// Make transport uncacheable like NewClients.
+		Proxy: func(*http.Request) (*url.URL, error) { return nil, nil },
+	}
+	require.NoError(t, pinToEndpoint(restCfg, "127.0.0.1"))
+	assert.Equal(t, "example.com", restCfg.ServerName)
+
+	rt, err := rest.TransportFor(restCfg)
+	require.NoError(t, err)
+
+	resp, err := (&http.Client{Transport: rt}).Get(restCfg.Host)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	sni, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", string(sni))
+}
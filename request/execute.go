@@ -0,0 +1,146 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/kperf/metrics"
+
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/klog/v2"
+)
+
+// requestExecutor sends requests and observes their responses. It's shared
+// by Schedule and replay so that both report the same metrics.
+type requestExecutor struct {
+	cfg *scheduleCfg
+	// decode is true if responses are decoded.
+	decode bool
+	// abortMonitor is optional.
+	abortMonitor *metrics.AbortMonitor
+	// throughput is optional.
+	throughput *atomic.Pointer[throughputRecorder]
+}
+
+// execute sends req and observes the response into respMetric. The response
+// is observed into endpointMetric as well if it's not nil and not warming up.
+func (e *requestExecutor) execute(req Requester, respMetric, endpointMetric metrics.ResponseMetric, inWarmup bool) {
+	cfg := e.cfg
+
+	phases := newPhaseTimer()
+	reqCtx := phases.withClientTrace(context.Background())
+	if cfg.connTracker != nil {
+		reqCtx = cfg.connTracker.withClientTrace(reqCtx)
+	}
+	var rtInfo *roundTripInfo
+	if cfg.traceWriter != nil {
+		rtInfo = &roundTripInfo{}
+		reqCtx = withRoundTripInfo(reqCtx, rtInfo)
+	}
+	fcInfo := &flowControlInfo{}
+	reqCtx = withFlowControlInfo(reqCtx, fcInfo)
+	var decoded *decodeInfo
+	if e.decode {
+		decoded = &decodeInfo{}
+		reqCtx = withDecodeInfo(reqCtx, decoded)
+	}
+
+	if cfg.liveMetrics != nil {
+		cfg.liveMetrics.ObserveInflight(1)
+	}
+
+	method, u := req.Method(), req.MaskedURL().String()
+	respMetric.ObserveDispatch(method, u)
+	start := time.Now()
+
+	var span trace.Span
+	if cfg.otlp != nil {
+		reqCtx, span = cfg.otlp.startSpan(reqCtx, req, start)
+	}
+
+	bytes, err := req.Do(reqCtx)
+	if cfg.liveMetrics != nil {
+		cfg.liveMetrics.ObserveInflight(-1)
+	}
+	// Based on HTTP2 Spec Section 8.1 [1],
+	//
+	// A server can send a complete response prior to the client
+	// sending an entire request if the response does not depend
+	// on any portion of the request that has not been sent and
+	// received. When this is true, a server MAY request that the
+	// client abort transmission of a request without error by
+	// sending a RST_STREAM with an error code of NO_ERROR after
+	// sending a complete response (i.e., a frame with the END_STREAM
+	// flag). Clients MUST NOT discard responses as a result of receiving
+	// such a RST_STREAM, though clients can always discard responses
+	// at their discretion for other reasons.
+	//
+	// We should mark NO_ERROR as nil here.
+	//
+	// [1]: https://httpwg.org/specs/rfc7540.html#HttpSequence
+	if err != nil && isHTTP2StreamNoError(err) {
+		err = nil
+	}
+
+	end := time.Now()
+	// The decode cost is only reported by ObserveDecode.
+	if decoded != nil && !decoded.received.IsZero() {
+		end = decoded.received
+	}
+	latency := end.Sub(start).Seconds()
+
+	if rtInfo != nil {
+		rec := newTraceRecord(req, rtInfo, start, latency, bytes, err)
+		rec.Warmup = inWarmup
+		if werr := cfg.traceWriter.Write(rec); werr != nil {
+			klog.V(2).ErrorS(werr, "failed to write trace record")
+		}
+	}
+	statusCode, retries := fcInfo.response()
+	if span != nil {
+		cfg.otlp.endSpan(span, end, statusCode, retries, bytes, inWarmup, err)
+	}
+
+	if inWarmup {
+		endpointMetric = nil
+	}
+
+	respMetric.ObserveReceivedBytes(method, u, bytes)
+	if obs, ok := fcInfo.observation(); ok {
+		respMetric.ObserveFlowControl(obs, latency, err != nil)
+	}
+	if decoded != nil && decoded.decoded {
+		respMetric.ObserveDecode(method, u, decoded.seconds, decoded.allocatedBytes, decoded.items)
+	}
+	if err != nil {
+		respMetric.ObserveFailure(method, u, end, latency, err)
+		if endpointMetric != nil {
+			endpointMetric.ObserveFailure(method, u, end, latency, err)
+		}
+		if e.abortMonitor != nil {
+			e.abortMonitor.ObserveFailure(end, latency, err)
+		}
+		klog.V(5).Infof("Request stream failed: %v", err)
+		return
+	}
+	respMetric.ObserveLatency(method, u, latency)
+	respMetric.ObserveResponse(statusCode, retries, latency)
+	for phase, seconds := range phases.phases(end) {
+		respMetric.ObservePhaseLatency(phase, seconds)
+	}
+	if endpointMetric != nil {
+		endpointMetric.ObserveLatency(method, u, latency)
+	}
+	if e.abortMonitor != nil {
+		e.abortMonitor.ObserveLatency(end, latency)
+	}
+	if e.throughput != nil {
+		if recorder := e.throughput.Load(); recorder != nil && !inWarmup {
+			recorder.observe(end)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/metrics"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// headerFlowSchemaUID is the response header set by API Priority and
+	// Fairness with the UID of matched flow schema.
+	headerFlowSchemaUID = "X-Kubernetes-PF-FlowSchema-UID"
+	// headerPriorityLevelUID is the response header set by API Priority
+	// and Fairness with the UID of matched priority level.
+	headerPriorityLevelUID = "X-Kubernetes-PF-PriorityLevel-UID"
+)
+
+// flowControlInfoKey is the context key of flowControlInfo.
+type flowControlInfoKey struct{}
+
+// flowControlInfo records API Priority and Fairness headers of responses.
+type flowControlInfo struct {
+	metrics.FlowControlObservation
+
+	// attempts is the number of round trips, including retries.
+	attempts int
+	// statusCode is the status code of the last response.
+	statusCode int
+}
+
+// withFlowControlInfo returns context which asks flowControlRoundTripper to
+// fill info.
+func withFlowControlInfo(ctx context.Context, info *flowControlInfo) context.Context {
+	return context.WithValue(ctx, flowControlInfoKey{}, info)
+}
+
+// observation returns the observation if apiserver classified the request.
+func (info *flowControlInfo) observation() (metrics.FlowControlObservation, bool) {
+	obs := info.FlowControlObservation
+	obs.Retries = max(info.attempts-1, 0)
+	return obs, obs.FlowSchema != "" || obs.PriorityLevel != ""
+}
+
+// response returns the status code of the last response and the number of
+// retries made by client.
+func (info *flowControlInfo) response() (statusCode, retries int) {
+	return info.statusCode, max(info.attempts-1, 0)
+}
+
+// flowControlRoundTripper fills flowControlInfo if the request's context
+// has one. client-go retries in rest.Request so that every retry goes
+// through it.
+type flowControlRoundTripper struct {
+	rt http.RoundTripper
+}
+
+// newFlowControlRoundTripper wraps rt with flowControlRoundTripper.
+func newFlowControlRoundTripper(rt http.RoundTripper) http.RoundTripper {
+	return &flowControlRoundTripper{rt: rt}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *flowControlRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	info, ok := req.Context().Value(flowControlInfoKey{}).(*flowControlInfo)
+	if !ok {
+		return t.rt.RoundTrip(req)
+	}
+
+	info.attempts++
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	info.statusCode = resp.StatusCode
+	if uid := resp.Header.Get(headerFlowSchemaUID); uid != "" {
+		info.FlowSchema = uid
+	}
+	if uid := resp.Header.Get(headerPriorityLevelUID); uid != "" {
+		info.PriorityLevel = uid
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		info.Throttled++
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			info.RetryAfterSeconds = max(info.RetryAfterSeconds, seconds)
+		}
+	}
+	return resp, nil
+}
+
+// WrappedRoundTripper implements k8s.io/client-go/transport.WrappedRoundTripper.
+func (t *flowControlRoundTripper) WrappedRoundTripper() http.RoundTripper {
+	return t.rt
+}
+
+// FlowControlNames maps the UIDs of flow schemas and priority levels to
+// their names.
+type FlowControlNames struct {
+	FlowSchemas    map[string]string
+	PriorityLevels map[string]string
+}
+
+// ListFlowControlNames lists flow schemas and priority levels from
+// flowcontrol.apiserver.k8s.io/v1 API.
+func ListFlowControlNames(ctx context.Context, kubeCfgPath string) (*FlowControlNames, error) {
+	restCfg, err := clientcmd.BuildConfigFromFlags("", kubeCfgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	fss, err := clientset.FlowcontrolV1().FlowSchemas().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list flowschemas: %w", err)
+	}
+	pls, err := clientset.FlowcontrolV1().PriorityLevelConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prioritylevelconfigurations: %w", err)
+	}
+
+	names := &FlowControlNames{
+		FlowSchemas:    make(map[string]string, len(fss.Items)),
+		PriorityLevels: make(map[string]string, len(pls.Items)),
+	}
+	for _, fs := range fss.Items {
+		names.FlowSchemas[string(fs.UID)] = fs.Name
+	}
+	for _, pl := range pls.Items {
+		names.PriorityLevels[string(pl.UID)] = pl.Name
+	}
+	return names, nil
+}
+
+// HasFlowControlStats returns true if any request of the result, including
+// warmup, has been classified by API Priority and Fairness.
+func (r *Result) HasFlowControlStats() bool {
+	if len(r.ByFlowSchema) > 0 || len(r.ByPriorityLevel) > 0 {
+		return true
+	}
+	return r.Warmup != nil && r.Warmup.HasFlowControlStats()
+}
+
+// ResolveFlowControlNames re-keys the flow control stats of the result,
+// including warmup, by names. The UID is kept if it's unknown.
+func (r *Result) ResolveFlowControlNames(names *FlowControlNames) {
+	r.ByFlowSchema = resolveFlowControlNames(r.ByFlowSchema, names.FlowSchemas)
+	r.ByPriorityLevel = resolveFlowControlNames(r.ByPriorityLevel, names.PriorityLevels)
+	if r.Warmup != nil {
+		r.Warmup.ResolveFlowControlNames(names)
+	}
+}
+
+// resolveFlowControlNames returns reports keyed by names.
+func resolveFlowControlNames(reports map[string]*types.FlowControlReport, names map[string]string) map[string]*types.FlowControlReport {
+	if len(reports) == 0 {
+		return reports
+	}
+
+	res := make(map[string]*types.FlowControlReport, len(reports))
+	for key, r := range reports {
+		if name, ok := names[r.UID]; ok {
+			key = name
+		}
+		res[key] = r
+	}
+	return res
+}
@@ -0,0 +1,90 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/metrics"
+	"github.com/Azure/kperf/request/unstructuredscheme"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+)
+
+func TestFlowControlRoundTripper(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(headerFlowSchemaUID, "fs-uid")
+		w.Header().Set(headerPriorityLevelUID, "pl-uid")
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	restCfg := &rest.Config{
+		Host: srv.URL,
+		// Make transport uncacheable.
+		Proxy: http.ProxyFromEnvironment,
+	}
+	restCfg.NegotiatedSerializer = unstructuredscheme.NewNegotiatedSerializer()
+	restCfg.Wrap(newFlowControlRoundTripper)
+	cli, err := rest.UnversionedRESTClientFor(restCfg)
+	require.NoError(t, err)
+
+	info := &flowControlInfo{}
+	_, err = cli.Get().AbsPath("/api/v1/pods").MaxRetries(1).
+		DoRaw(withFlowControlInfo(context.Background(), info))
+	require.NoError(t, err)
+
+	obs, ok := info.observation()
+	require.True(t, ok)
+	assert.Equal(t, metrics.FlowControlObservation{
+		FlowSchema:    "fs-uid",
+		PriorityLevel: "pl-uid",
+		Throttled:     1,
+		Retries:       1,
+	}, obs)
+
+	statusCode, retries := info.response()
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, 1, retries)
+}
+
+func TestResolveFlowControlNames(t *testing.T) {
+	result := &Result{
+		ResponseStats: types.ResponseStats{
+			ByFlowSchema: map[string]*types.FlowControlReport{
+				"fs-uid":  {UID: "fs-uid", Requests: 1},
+				"unknown": {UID: "unknown", Requests: 2},
+			},
+		},
+		Warmup: &Result{
+			ResponseStats: types.ResponseStats{
+				ByPriorityLevel: map[string]*types.FlowControlReport{
+					"pl-uid": {UID: "pl-uid", Requests: 3},
+				},
+			},
+		},
+	}
+	require.True(t, result.HasFlowControlStats())
+
+	result.ResolveFlowControlNames(&FlowControlNames{
+		FlowSchemas:    map[string]string{"fs-uid": "kperf"},
+		PriorityLevels: map[string]string{"pl-uid": "workload-low"},
+	})
+	assert.Equal(t, 1, result.ByFlowSchema["kperf"].Requests)
+	assert.Equal(t, 2, result.ByFlowSchema["unknown"].Requests)
+	assert.Equal(t, 3, result.Warmup.ByPriorityLevel["workload-low"].Requests)
+}
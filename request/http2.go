@@ -0,0 +1,430 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"golang.org/x/net/http2"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// defaultHTTP2ReadIdleTimeout aligns with client-go's default.
+	defaultHTTP2ReadIdleTimeout = 30 * time.Second
+	// defaultHTTP2PingTimeout aligns with client-go's default.
+	defaultHTTP2PingTimeout = 15 * time.Second
+
+	// http2FrameHeaderLen is the length of HTTP/2 frame header.
+	http2FrameHeaderLen = 9
+	// http2MaxKeptPayload is the maximum bytes of payload kept for parsing.
+	// It's enough for GOAWAY's error code, RST_STREAM and SETTINGS.
+	http2MaxKeptPayload = 256
+)
+
+// useHTTP2Transport returns true if the client should be built on top of
+// newHTTP2Client, which only speaks HTTP/2, because HTTP/2 settings are
+// explicitly set.
+func useHTTP2Transport(cfg *clientCfg, restCfg *rest.Config) bool {
+	return cfg.http2 != nil && !cfg.disableHTTP2 && isHTTPSHost(restCfg.Host)
+}
+
+// useHTTP2ObservedTransport returns true if the client should be built on
+// top of newHTTP2ObservedClient so that HTTP/2 frames are observed.
+func useHTTP2ObservedTransport(cfg *clientCfg, restCfg *rest.Config) bool {
+	return cfg.http2 == nil && !cfg.disableHTTP2 && cfg.connTracker != nil && isHTTPSHost(restCfg.Host)
+}
+
+func isHTTPSHost(host string) bool {
+	u, err := url.Parse(host)
+	return err == nil && u.Scheme == "https"
+}
+
+// newHTTP2Client returns http.Client which only speaks HTTP/2.
+//
+// NOTE: client-go's transport doesn't expose the HTTP/2 settings, except
+// for environment variables, and HTTP/2 frames. So the client uses
+// golang.org/x/net/http2.Transport directly and observes the frames read
+// from server after TLS layer.
+func newHTTP2Client(restCfg *rest.Config, spec *types.HTTP2Spec, stats *http2ConnStats) (*http.Client, error) {
+	tlsCfg, err := rest.TLSConfigFor(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build TLS config: %w", err)
+	}
+
+	dial := restCfg.Dial
+	if dial == nil {
+		// Align with k8s.io/client-go/transport's default dialer.
+		dial = (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+
+	t2 := &http2.Transport{
+		TLSClientConfig: tlsCfg,
+		ReadIdleTimeout: defaultHTTP2ReadIdleTimeout,
+		PingTimeout:     defaultHTTP2PingTimeout,
+		DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return dialHTTP2(ctx, dial, network, addr, cfg, stats)
+		},
+	}
+	if spec != nil {
+		switch {
+		case spec.ReadIdleTimeout < 0:
+			t2.ReadIdleTimeout = 0
+		case spec.ReadIdleTimeout > 0:
+			t2.ReadIdleTimeout = time.Duration(spec.ReadIdleTimeout * float64(time.Second))
+		}
+		if spec.PingTimeout > 0 {
+			t2.PingTimeout = time.Duration(spec.PingTimeout * float64(time.Second))
+		}
+		t2.StrictMaxConcurrentStreams = spec.StrictMaxConcurrentStreams
+	}
+	if stats != nil {
+		t2.CountError = stats.countError
+	}
+
+	rt, err := rest.HTTPWrappersForConfig(restCfg, t2)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: rt, Timeout: restCfg.Timeout}, nil
+}
+
+// newHTTP2ObservedClient returns http.Client which behaves like client-go's
+// default one, including proxy and falling back to HTTP/1.1, but observes
+// the frames of HTTP/2 connections.
+//
+// NOTE: http2.ConfigureTransports hands over the negotiated HTTP/2
+// connections to its internal pool, which can't be observed. So the
+// connections are handed over to http2ObservedConnPool instead.
+func newHTTP2ObservedClient(restCfg *rest.Config, stats *http2ConnStats) (*http.Client, error) {
+	tlsCfg, err := rest.TLSConfigFor(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build TLS config: %w", err)
+	}
+
+	proxy := restCfg.Proxy
+	if proxy == nil {
+		proxy = http.ProxyFromEnvironment
+	}
+	dial := restCfg.Dial
+	if dial == nil {
+		// Align with k8s.io/client-go/transport's default dialer.
+		dial = (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+
+	// Align with k8s.io/apimachinery/pkg/util/net.SetTransportDefaults.
+	t1 := &http.Transport{
+		Proxy:               proxy,
+		TLSClientConfig:     tlsCfg,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConnsPerHost: 25,
+		IdleConnTimeout:     90 * time.Second,
+		DialContext:         dial,
+		DisableCompression:  restCfg.DisableCompression,
+	}
+	t2, err := http2.ConfigureTransports(t1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure HTTP/2: %w", err)
+	}
+	t2.ReadIdleTimeout = defaultHTTP2ReadIdleTimeout
+	t2.PingTimeout = defaultHTTP2PingTimeout
+	t2.CountError = stats.countError
+
+	pool := &http2ObservedConnPool{
+		t:     t2,
+		stats: stats,
+		conns: map[string][]*http2.ClientConn{},
+	}
+	t2.ConnPool = pool
+	t1.TLSNextProto[http2.NextProtoTLS] = pool.upgrade
+
+	rt, err := rest.HTTPWrappersForConfig(restCfg, t1)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: rt, Timeout: restCfg.Timeout}, nil
+}
+
+// http2ObservedConnPool implements http2.ClientConnPool with the HTTP/2
+// connections negotiated by http.Transport. It never dials, so that
+// http.Transport dials new connection if there is no available one.
+type http2ObservedConnPool struct {
+	t     *http2.Transport
+	stats *http2ConnStats
+
+	mu    sync.Mutex
+	conns map[string][]*http2.ClientConn
+}
+
+// GetClientConn implements http2.ClientConnPool.
+func (p *http2ObservedConnPool) GetClientConn(_ *http.Request, addr string) (*http2.ClientConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, cc := range p.conns[addr] {
+		if cc.ReserveNewRequest() {
+			return cc, nil
+		}
+	}
+	// http2.ConfigureTransports turns it into http.ErrSkipAltProtocol.
+	return nil, http2.ErrNoCachedConn
+}
+
+// MarkDead implements http2.ClientConnPool.
+func (p *http2ObservedConnPool) MarkDead(cc *http2.ClientConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for addr, conns := range p.conns {
+		if idx := slices.Index(conns, cc); idx >= 0 {
+			p.conns[addr] = slices.Delete(conns, idx, idx+1)
+			if len(p.conns[addr]) == 0 {
+				delete(p.conns, addr)
+			}
+			return
+		}
+	}
+}
+
+// upgrade implements http.Transport.TLSNextProto for HTTP/2.
+func (p *http2ObservedConnPool) upgrade(authority string, c *tls.Conn) http.RoundTripper {
+	p.stats.dialed()
+	cc, err := p.t.NewClientConn(&http2ObservedConn{Conn: c, stats: p.stats})
+	if err != nil {
+		c.Close()
+		return erringRoundTripper{err: err}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.conns[authority] = append(p.conns[authority], cc)
+	return p.t
+}
+
+// erringRoundTripper returns err for every request.
+type erringRoundTripper struct {
+	err error
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt erringRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, rt.err
+}
+
+// dialHTTP2 dials TLS connection which negotiates HTTP/2. It reports the
+// connect and TLS handshake to httptrace since http2.Transport doesn't.
+func dialHTTP2(ctx context.Context,
+	dial func(ctx context.Context, network, address string) (net.Conn, error),
+	network, addr string,
+	cfg *tls.Config,
+	stats *http2ConnStats,
+) (net.Conn, error) {
+	trace := httptrace.ContextClientTrace(ctx)
+
+	if trace != nil && trace.ConnectStart != nil {
+		trace.ConnectStart(network, addr)
+	}
+	rawConn, err := dial(ctx, network, addr)
+	if trace != nil && trace.ConnectDone != nil {
+		trace.ConnectDone(network, addr, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if trace != nil && trace.TLSHandshakeStart != nil {
+		trace.TLSHandshakeStart()
+	}
+	conn := tls.Client(rawConn, cfg)
+	err = conn.HandshakeContext(ctx)
+	if trace != nil && trace.TLSHandshakeDone != nil {
+		trace.TLSHandshakeDone(conn.ConnectionState(), err)
+	}
+	if err != nil {
+		rawConn.Close()
+		return nil, err
+	}
+
+	if p := conn.ConnectionState().NegotiatedProtocol; p != http2.NextProtoTLS {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected ALPN protocol %q, server doesn't support HTTP/2", p)
+	}
+
+	if stats == nil {
+		return conn, nil
+	}
+	stats.dialed()
+	return &http2ObservedConn{Conn: conn, stats: stats}, nil
+}
+
+// http2ConnStats records the HTTP/2 connections dialed by one client.
+type http2ConnStats struct {
+	mu sync.Mutex
+	// live is the number of connections which can take new streams.
+	live int
+	// everDialed is true if any connection has been dialed.
+	everDialed bool
+	report     types.HTTP2ConnectionReport
+}
+
+// dialed records new connection.
+func (s *http2ConnStats) dialed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.everDialed && s.live == 0 {
+		s.report.Replacements++
+	}
+	s.everDialed = true
+	s.live++
+	s.report.Dials++
+}
+
+// retired records that the connection can't take new streams.
+func (s *http2ConnStats) retired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.live--
+}
+
+// countError implements http2.Transport.CountError.
+func (s *http2ConnStats) countError(errType string) {
+	if errType != "conn_close_lost_ping" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.report.LostPings++
+}
+
+// observeFrame records the frame received from server.
+func (s *http2ConnStats) observeFrame(typ http2.FrameType, flags http2.Flags, payload []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch typ {
+	case http2.FrameGoAway:
+		s.report.GoAways++
+	case http2.FrameRSTStream:
+		if len(payload) >= 4 && http2.ErrCode(binary.BigEndian.Uint32(payload)) == http2.ErrCodeRefusedStream {
+			s.report.RefusedStreams++
+		}
+	case http2.FrameSettings:
+		if flags.Has(http2.FlagSettingsAck) {
+			return
+		}
+		for ; len(payload) >= 6; payload = payload[6:] {
+			if http2.SettingID(binary.BigEndian.Uint16(payload)) == http2.SettingMaxConcurrentStreams {
+				s.report.MaxConcurrentStreams = binary.BigEndian.Uint32(payload[2:])
+			}
+		}
+	}
+}
+
+// reset clears the observation but keeps tracking live connections.
+func (s *http2ConnStats) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.report = types.HTTP2ConnectionReport{
+		MaxConcurrentStreams: s.report.MaxConcurrentStreams,
+	}
+}
+
+// snapshot returns the observation since the last reset.
+func (s *http2ConnStats) snapshot() types.HTTP2ConnectionReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.report
+}
+
+// http2ObservedConn parses the frame headers read from server.
+//
+// NOTE: http2.Transport reads connection in one goroutine so that parsing
+// state doesn't need lock.
+type http2ObservedConn struct {
+	*tls.Conn
+
+	stats *http2ConnStats
+	once  sync.Once
+
+	header    [http2FrameHeaderLen]byte
+	headerLen int
+	remaining int
+	payload   []byte
+}
+
+// Read implements net.Conn.
+func (c *http2ObservedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.parse(p[:n])
+	return n, err
+}
+
+// Close implements net.Conn.
+func (c *http2ObservedConn) Close() error {
+	c.retire()
+	return c.Conn.Close()
+}
+
+// retire marks the connection unable to take new streams once.
+func (c *http2ObservedConn) retire() {
+	c.once.Do(c.stats.retired)
+}
+
+// parse consumes bytes read from server and reports finished frames.
+func (c *http2ObservedConn) parse(b []byte) {
+	for len(b) > 0 {
+		if c.headerLen < http2FrameHeaderLen {
+			n := copy(c.header[c.headerLen:], b)
+			c.headerLen += n
+			b = b[n:]
+			if c.headerLen < http2FrameHeaderLen {
+				return
+			}
+
+			c.remaining = int(c.header[0])<<16 | int(c.header[1])<<8 | int(c.header[2])
+			c.payload = c.payload[:0]
+		}
+
+		n := min(len(b), c.remaining)
+		if keep := min(n, http2MaxKeptPayload-len(c.payload)); keep > 0 {
+			c.payload = append(c.payload, b[:keep]...)
+		}
+		c.remaining -= n
+		b = b[n:]
+
+		if c.remaining == 0 {
+			typ, flags := http2.FrameType(c.header[3]), http2.Flags(c.header[4])
+			c.stats.observeFrame(typ, flags, c.payload)
+			if typ == http2.FrameGoAway {
+				c.retire()
+			}
+			c.headerLen = 0
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"k8s.io/client-go/rest"
+)
+
+func TestHTTP2ObservedConnParse(t *testing.T) {
+	var buf bytes.Buffer
+	fr := http2.NewFramer(&buf, nil)
+	require.NoError(t, fr.WriteSettings(http2.Setting{ID: http2.SettingMaxConcurrentStreams, Val: 100}))
+	require.NoError(t, fr.WriteSettingsAck())
+	require.NoError(t, fr.WriteData(1, false, bytes.Repeat([]byte("x"), 1024)))
+	require.NoError(t, fr.WriteRSTStream(3, http2.ErrCodeRefusedStream))
+	require.NoError(t, fr.WriteRSTStream(5, http2.ErrCodeCancel))
+	require.NoError(t, fr.WriteGoAway(5, http2.ErrCodeNo, []byte("bye")))
+
+	stats := &http2ConnStats{}
+	stats.dialed()
+	conn := &http2ObservedConn{stats: stats}
+
+	// Feed bytes in small chunks to cover frames split across reads.
+	data := buf.Bytes()
+	for len(data) > 0 {
+		n := min(len(data), 7)
+		conn.parse(data[:n])
+		data = data[n:]
+	}
+
+	assert.Equal(t, types.HTTP2ConnectionReport{
+		Dials:                1,
+		GoAways:              1,
+		RefusedStreams:       1,
+		MaxConcurrentStreams: 100,
+	}, stats.snapshot())
+
+	// The connection went away so that next dial is replacement.
+	stats.dialed()
+	assert.Equal(t, 1, stats.snapshot().Replacements)
+}
+
+func TestHTTP2Client(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	restCfg := &rest.Config{
+		Host:            srv.URL,
+		TLSClientConfig: rest.TLSClientConfig{Insecure: true},
+	}
+
+	stats := &http2ConnStats{}
+	cli, err := newHTTP2Client(restCfg, &types.HTTP2Spec{StrictMaxConcurrentStreams: true}, stats)
+	require.NoError(t, err)
+
+	get := func() error {
+		timer := newPhaseTimer()
+		req, err := http.NewRequestWithContext(timer.withClientTrace(context.Background()), http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := cli.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		assert.Equal(t, "HTTP/2.0", resp.Proto)
+		_, err = io.ReadAll(resp.Body)
+		return err
+	}
+
+	require.NoError(t, get())
+	r := stats.snapshot()
+	assert.Equal(t, 1, r.Dials)
+	assert.NotZero(t, r.MaxConcurrentStreams)
+
+	srv.CloseClientConnections()
+	assert.Eventually(t, func() bool {
+		_ = get()
+		return stats.snapshot().Dials == 2
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, 1, stats.snapshot().Replacements)
+}
+
+func TestHTTP2ObservedClient(t *testing.T) {
+	for _, enableHTTP2 := range []bool{true, false} {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("ok"))
+		}))
+		srv.EnableHTTP2 = enableHTTP2
+		srv.StartTLS()
+		defer srv.Close()
+
+		proxied := 0
+		restCfg := &rest.Config{
+			Host:            srv.URL,
+			TLSClientConfig: rest.TLSClientConfig{Insecure: true},
+			Proxy: func(*http.Request) (*url.URL, error) {
+				proxied++
+				return nil, nil
+			},
+		}
+
+		stats := &http2ConnStats{}
+		cli, err := newHTTP2ObservedClient(restCfg, stats)
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			resp, err := cli.Get(srv.URL)
+			require.NoError(t, err)
+			_, err = io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			if enableHTTP2 {
+				assert.Equal(t, "HTTP/2.0", resp.Proto)
+			} else {
+				// Falls back to HTTP/1.1.
+				assert.Equal(t, "HTTP/1.1", resp.Proto)
+			}
+		}
+		assert.NotZero(t, proxied)
+
+		r := stats.snapshot()
+		if !enableHTTP2 {
+			assert.Zero(t, r.Dials)
+			continue
+		}
+		// The connection is reused.
+		assert.Equal(t, 1, r.Dials)
+		assert.NotZero(t, r.MaxConcurrentStreams)
+
+		srv.CloseClientConnections()
+		assert.Eventually(t, func() bool {
+			resp, err := cli.Get(srv.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			return stats.snapshot().Dials == 2
+		}, 5*time.Second, 50*time.Millisecond)
+	}
+}
@@ -0,0 +1,387 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/kperf/metrics"
+
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+const (
+	// otlpScopeName is the instrumentation scope of exported telemetry.
+	otlpScopeName = "github.com/Azure/kperf"
+
+	// maxQueuedSpans is the maximum number of spans waiting for export.
+	// The spans beyond that are dropped.
+	maxQueuedSpans = 4096
+
+	// defaultOTLPExportInterval is the interval of exporting spans and
+	// metrics.
+	defaultOTLPExportInterval = 5 * time.Second
+
+	// otlpExportTimeout is the timeout of one export request.
+	otlpExportTimeout = 30 * time.Second
+)
+
+// traceParentRoundTripper propagates the span in the request's context by
+// W3C trace context [1], so that apiserver's spans become children of it.
+//
+// [1]: https://www.w3.org/TR/trace-context/
+type traceParentRoundTripper struct {
+	rt         http.RoundTripper
+	propagator propagation.TraceContext
+}
+
+// newTraceParentRoundTripper wraps rt with traceParentRoundTripper.
+func newTraceParentRoundTripper(rt http.RoundTripper) http.RoundTripper {
+	return &traceParentRoundTripper{rt: rt}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *traceParentRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return t.rt.RoundTrip(req)
+	}
+
+	req = utilnet.CloneRequest(req)
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return t.rt.RoundTrip(req)
+}
+
+// WrappedRoundTripper implements k8s.io/client-go/transport.WrappedRoundTripper.
+func (t *traceParentRoundTripper) WrappedRoundTripper() http.RoundTripper {
+	return t.rt
+}
+
+// The transport protocols of OTLP [1], named as the values of
+// OTEL_EXPORTER_OTLP_PROTOCOL.
+//
+// [1]: https://opentelemetry.io/docs/specs/otlp/
+const (
+	OTLPProtocolGRPC         = "grpc"
+	OTLPProtocolHTTPProtobuf = "http/protobuf"
+)
+
+// OTLPExporter exports a span per sampled request, and live metrics if any,
+// to an OTLP endpoint over gRPC or HTTP by OpenTelemetry SDK.
+type OTLPExporter struct {
+	protocol string
+	ratio    float64
+	interval time.Duration
+	live     *metrics.LiveMetrics
+	instance string
+
+	tracer         trace.Tracer
+	tracerProvider *sdktrace.TracerProvider
+	meterProvider  *sdkmetric.MeterProvider
+}
+
+// OTLPExporterOpt is used to update default OTLP exporter setting.
+type OTLPExporterOpt func(*OTLPExporter)
+
+// WithOTLPProtocolOpt updates the transport protocol, which is either
+// OTLPProtocolGRPC or OTLPProtocolHTTPProtobuf. The default is
+// OTLPProtocolHTTPProtobuf.
+func WithOTLPProtocolOpt(protocol string) OTLPExporterOpt {
+	return func(e *OTLPExporter) {
+		e.protocol = protocol
+	}
+}
+
+// WithOTLPSamplingRatioOpt exports spans of the given ratio of requests, in
+// [0, 1]. The default is 1.
+func WithOTLPSamplingRatioOpt(ratio float64) OTLPExporterOpt {
+	return func(e *OTLPExporter) {
+		e.ratio = ratio
+	}
+}
+
+// WithOTLPLiveMetricsOpt exports live metrics as OTLP metrics.
+func WithOTLPLiveMetricsOpt(live *metrics.LiveMetrics) OTLPExporterOpt {
+	return func(e *OTLPExporter) {
+		e.live = live
+	}
+}
+
+// WithOTLPExportIntervalOpt updates the interval of exporting. The default
+// is 5 seconds.
+func WithOTLPExportIntervalOpt(interval time.Duration) OTLPExporterOpt {
+	return func(e *OTLPExporter) {
+		e.interval = interval
+	}
+}
+
+// WithOTLPServiceInstanceOpt sets service.instance.id of resource, for
+// instance, runner's name.
+func WithOTLPServiceInstanceOpt(id string) OTLPExporterOpt {
+	return func(e *OTLPExporter) {
+		e.instance = id
+	}
+}
+
+// NewOTLPExporter returns new instance of OTLPExporter which exports to
+// endpoint, for instance, http://localhost:4318 for HTTP or
+// http://localhost:4317 for gRPC. The https scheme enables TLS. For HTTP, the
+// signal paths, like /v1/traces, are appended to endpoint. The endpoint
+// overrides OTEL_EXPORTER_OTLP_ENDPOINT. Close should be called to export the
+// rest.
+func NewOTLPExporter(endpoint string, opts ...OTLPExporterOpt) (*OTLPExporter, error) {
+	e := &OTLPExporter{
+		protocol: OTLPProtocolHTTPProtobuf,
+		ratio:    1,
+		interval: defaultOTLPExportInterval,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if e.ratio < 0 || e.ratio > 1 {
+		return nil, fmt.Errorf("OTLP sampling ratio %v should be in [0, 1]", e.ratio)
+	}
+	if e.interval <= 0 {
+		return nil, fmt.Errorf("OTLP export interval %v should be positive", e.interval)
+	}
+
+	// The exporters ignore invalid endpoint with an error logged, so it's
+	// validated here.
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OTLP endpoint %s: %w", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("OTLP endpoint %s should be http or https URL", endpoint)
+	}
+
+	ctx := context.Background()
+	var spanExp sdktrace.SpanExporter
+	var metricExp sdkmetric.Exporter
+	switch e.protocol {
+	case OTLPProtocolGRPC:
+		spanExp, err = otlptracegrpc.New(ctx,
+			otlptracegrpc.WithEndpointURL(endpoint),
+			otlptracegrpc.WithTimeout(otlpExportTimeout))
+		if err == nil {
+			metricExp, err = otlpmetricgrpc.New(ctx,
+				otlpmetricgrpc.WithEndpointURL(endpoint),
+				otlpmetricgrpc.WithTimeout(otlpExportTimeout))
+		}
+	case OTLPProtocolHTTPProtobuf:
+		// The URL path is used as it is.
+		baseURL := strings.TrimSuffix(u.String(), "/")
+		spanExp, err = otlptracehttp.New(ctx,
+			otlptracehttp.WithEndpointURL(baseURL+"/v1/traces"),
+			otlptracehttp.WithTimeout(otlpExportTimeout))
+		if err == nil {
+			metricExp, err = otlpmetrichttp.New(ctx,
+				otlpmetrichttp.WithEndpointURL(baseURL+"/v1/metrics"),
+				otlpmetrichttp.WithTimeout(otlpExportTimeout))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q, expected %s or %s",
+			e.protocol, OTLPProtocolGRPC, OTLPProtocolHTTPProtobuf)
+	}
+	if err != nil {
+		if spanExp != nil {
+			_ = spanExp.Shutdown(ctx)
+		}
+		return nil, fmt.Errorf("failed to create OTLP exporter of %s: %w", endpoint, err)
+	}
+
+	attrs := []attribute.KeyValue{semconv.ServiceName("kperf")}
+	if hostname, err := os.Hostname(); err == nil {
+		attrs = append(attrs, semconv.HostName(hostname))
+	}
+	if e.instance != "" {
+		attrs = append(attrs, semconv.ServiceInstanceID(e.instance))
+	}
+	res := resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+
+	e.tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExp,
+			sdktrace.WithBatchTimeout(e.interval),
+			sdktrace.WithMaxQueueSize(maxQueuedSpans),
+		),
+		sdktrace.WithSampler(sdktrace.TraceIDRatioBased(e.ratio)),
+		sdktrace.WithResource(res),
+	)
+	e.tracer = e.tracerProvider.Tracer(otlpScopeName)
+
+	readerOpts := []sdkmetric.PeriodicReaderOption{sdkmetric.WithInterval(e.interval)}
+	if e.live != nil {
+		readerOpts = append(readerOpts, sdkmetric.WithProducer(&liveMetricsProducer{
+			live:  e.live,
+			start: time.Now(),
+		}))
+	}
+	e.meterProvider = sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExp, readerOpts...)),
+		sdkmetric.WithResource(res),
+	)
+	return e, nil
+}
+
+// startSpan starts the client span of req at start. The span is propagated
+// by the returned context. It isn't recorded if req isn't sampled.
+func (e *OTLPExporter) startSpan(ctx context.Context, req Requester, start time.Time) (context.Context, trace.Span) {
+	return e.tracer.Start(ctx, fmt.Sprintf("%s %s", req.Method(), req.MaskedURL().Path),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(start),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method()),
+			semconv.URLFull(req.URL().String()),
+		),
+	)
+}
+
+// endSpan ends span at end with the response of the request. The statusCode
+// is of the last response and retries is the number of retries made by
+// client.
+func (e *OTLPExporter) endSpan(span trace.Span, end time.Time, statusCode, retries int, receivedBytes int64, warmup bool, err error) {
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetAttributes(attribute.Int64("kperf.received_bytes", receivedBytes))
+	if statusCode != 0 {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(statusCode))
+	}
+	if retries > 0 {
+		span.SetAttributes(semconv.HTTPRequestResendCount(retries))
+	}
+	if warmup {
+		span.SetAttributes(attribute.Bool("kperf.warmup", true))
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
+
+// Close exports the rest and stops exporting.
+func (e *OTLPExporter) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), otlpExportTimeout)
+	defer cancel()
+
+	return errors.Join(
+		e.tracerProvider.Shutdown(ctx),
+		e.meterProvider.Shutdown(ctx),
+	)
+}
+
+// liveMetricsProducer produces live metrics for OTLP metric exporter.
+type liveMetricsProducer struct {
+	live  *metrics.LiveMetrics
+	start time.Time
+}
+
+// Produce implements sdkmetric.Producer.
+func (p *liveMetricsProducer) Produce(context.Context) ([]metricdata.ScopeMetrics, error) {
+	mfs, err := p.live.Gather()
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather live metrics: %w", err)
+	}
+	return []metricdata.ScopeMetrics{{
+		Scope:   instrumentation.Scope{Name: otlpScopeName},
+		Metrics: otlpMetricsFromFamilies(mfs, p.start, time.Now()),
+	}}, nil
+}
+
+// otlpMetricsFromFamilies converts Prometheus metrics into OTLP metrics.
+// Counters and histograms are cumulative since start.
+func otlpMetricsFromFamilies(mfs []*dto.MetricFamily, start, now time.Time) []metricdata.Metrics {
+	res := make([]metricdata.Metrics, 0, len(mfs))
+	for _, mf := range mfs {
+		m := metricdata.Metrics{Name: mf.GetName(), Description: mf.GetHelp()}
+
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			sum := metricdata.Sum[float64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+			}
+			for _, metric := range mf.GetMetric() {
+				sum.DataPoints = append(sum.DataPoints, metricdata.DataPoint[float64]{
+					Attributes: otlpLabelAttrs(metric.GetLabel()),
+					StartTime:  start,
+					Time:       now,
+					Value:      metric.GetCounter().GetValue(),
+				})
+			}
+			m.Data = sum
+		case dto.MetricType_GAUGE:
+			gauge := metricdata.Gauge[float64]{}
+			for _, metric := range mf.GetMetric() {
+				gauge.DataPoints = append(gauge.DataPoints, metricdata.DataPoint[float64]{
+					Attributes: otlpLabelAttrs(metric.GetLabel()),
+					Time:       now,
+					Value:      metric.GetGauge().GetValue(),
+				})
+			}
+			m.Data = gauge
+		case dto.MetricType_HISTOGRAM:
+			hist := metricdata.Histogram[float64]{
+				Temporality: metricdata.CumulativeTemporality,
+			}
+			for _, metric := range mf.GetMetric() {
+				h := metric.GetHistogram()
+
+				// Prometheus buckets are cumulative while OTLP ones
+				// aren't. The last OTLP bucket is (last bound, +Inf).
+				dp := metricdata.HistogramDataPoint[float64]{
+					Attributes: otlpLabelAttrs(metric.GetLabel()),
+					StartTime:  start,
+					Time:       now,
+					Count:      h.GetSampleCount(),
+					Sum:        h.GetSampleSum(),
+				}
+				var prev uint64
+				for _, b := range h.GetBucket() {
+					dp.Bounds = append(dp.Bounds, b.GetUpperBound())
+					dp.BucketCounts = append(dp.BucketCounts, b.GetCumulativeCount()-prev)
+					prev = b.GetCumulativeCount()
+				}
+				dp.BucketCounts = append(dp.BucketCounts, h.GetSampleCount()-prev)
+				hist.DataPoints = append(hist.DataPoints, dp)
+			}
+			m.Data = hist
+		default:
+			continue
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+func otlpLabelAttrs(labels []*dto.LabelPair) attribute.Set {
+	attrs := make([]attribute.KeyValue, 0, len(labels))
+	for _, l := range labels {
+		attrs = append(attrs, attribute.String(l.GetName(), l.GetValue()))
+	}
+	return attribute.NewSet(attrs...)
+}
@@ -0,0 +1,231 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/metrics"
+	"github.com/Azure/kperf/request/unstructuredscheme"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/client-go/rest"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	var traceParent string
+	apiserver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParent = r.Header.Get("traceparent")
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer apiserver.Close()
+
+	// collector is the stand-in of OTLP collector.
+	var mu sync.Mutex
+	var traces []*coltracepb.ExportTraceServiceRequest
+	var metricsData []*colmetricpb.ExportMetricsServiceRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		switch r.URL.Path {
+		case "/v1/traces":
+			data := &coltracepb.ExportTraceServiceRequest{}
+			assert.NoError(t, proto.Unmarshal(body, data))
+			traces = append(traces, data)
+		case "/v1/metrics":
+			data := &colmetricpb.ExportMetricsServiceRequest{}
+			assert.NoError(t, proto.Unmarshal(body, data))
+			metricsData = append(metricsData, data)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer collector.Close()
+
+	restCfg := &rest.Config{
+		Host: apiserver.URL,
+		// Make transport uncacheable.
+		Proxy: http.ProxyFromEnvironment,
+	}
+	restCfg.NegotiatedSerializer = unstructuredscheme.NewNegotiatedSerializer()
+	restCfg.Wrap(newTraceParentRoundTripper)
+	cli, err := rest.UnversionedRESTClientFor(restCfg)
+	require.NoError(t, err)
+
+	live := metrics.NewLiveMetrics()
+	exp, err := NewOTLPExporter(collector.URL+"/",
+		WithOTLPLiveMetricsOpt(live),
+		WithOTLPExportIntervalOpt(time.Hour),
+		WithOTLPServiceInstanceOpt("runner-0"),
+	)
+	require.NoError(t, err)
+
+	reqr := &DiscardRequester{BaseRequester: BaseRequester{method: "GET", req: cli.Get().AbsPath("/api/v1/pods")}}
+	start := time.Now()
+	ctx, span := exp.startSpan(context.Background(), reqr, start)
+	require.True(t, span.IsRecording())
+
+	bytes, err := reqr.Do(ctx)
+	require.NoError(t, err)
+	end := time.Now()
+	exp.endSpan(span, end, http.StatusOK, 0, bytes, false, nil)
+	metrics.NewResponseMetric(metrics.WithLiveMetricsOpt(live)).ObserveLatency("GET", "/api/v1/pods", end.Sub(start).Seconds())
+
+	require.NoError(t, exp.Close())
+	sc := span.SpanContext()
+	assert.Equal(t, fmt.Sprintf("00-%s-%s-01", sc.TraceID(), sc.SpanID()), traceParent)
+
+	require.Len(t, traces, 1)
+	spans := traces[0].ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /api/v1/pods", spans[0].Name)
+	assert.Equal(t, tracepb.Span_SPAN_KIND_CLIENT, spans[0].Kind)
+	assert.Equal(t, sc.TraceID().String(), fmt.Sprintf("%x", spans[0].TraceId))
+	assert.Equal(t, sc.SpanID().String(), fmt.Sprintf("%x", spans[0].SpanId))
+	assert.Equal(t, uint64(start.UnixNano()), spans[0].StartTimeUnixNano) //nolint:gosec
+	assert.Equal(t, int64(http.StatusOK), otlpAttr(spans[0].Attributes, "http.response.status_code").GetIntValue())
+	assert.Equal(t, "runner-0", otlpAttr(traces[0].ResourceSpans[0].Resource.Attributes, "service.instance.id").GetStringValue())
+
+	require.Len(t, metricsData, 1)
+	found := map[string]*metricpb.Metric{}
+	for _, m := range metricsData[0].ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		found[m.Name] = m
+	}
+	require.NotNil(t, found["kperf_requests_total"].GetSum())
+	assert.Equal(t, float64(1), found["kperf_requests_total"].GetSum().DataPoints[0].GetAsDouble())
+	require.NotNil(t, found["kperf_inflight_requests"].GetGauge())
+	hist := found["kperf_request_duration_seconds"].GetHistogram()
+	require.NotNil(t, hist)
+	assert.Equal(t, uint64(1), hist.DataPoints[0].Count)
+	assert.Len(t, hist.DataPoints[0].BucketCounts, len(hist.DataPoints[0].ExplicitBounds)+1)
+
+	// Nothing is sampled with zero ratio.
+	exp, err = NewOTLPExporter(collector.URL, WithOTLPSamplingRatioOpt(0))
+	require.NoError(t, err)
+	_, span = exp.startSpan(context.Background(), reqr, time.Now())
+	assert.False(t, span.IsRecording())
+	require.NoError(t, exp.Close())
+
+	_, err = NewOTLPExporter("localhost:4318")
+	assert.Error(t, err)
+
+	_, err = NewOTLPExporter(collector.URL, WithOTLPProtocolOpt("http"))
+	assert.Error(t, err)
+}
+
+func TestOTLPExporterGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	traces, metricsData := &fakeTraceService{}, &fakeMetricsService{}
+	collector := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(collector, traces)
+	colmetricpb.RegisterMetricsServiceServer(collector, metricsData)
+	go func() { _ = collector.Serve(lis) }()
+	defer collector.Stop()
+
+	live := metrics.NewLiveMetrics()
+	exp, err := NewOTLPExporter("http://"+lis.Addr().String(),
+		WithOTLPProtocolOpt(OTLPProtocolGRPC),
+		WithOTLPLiveMetricsOpt(live),
+		WithOTLPExportIntervalOpt(time.Hour),
+	)
+	require.NoError(t, err)
+
+	restCfg := &rest.Config{
+		Host: "https://localhost:6443",
+		// Make transport uncacheable.
+		Proxy: http.ProxyFromEnvironment,
+	}
+	restCfg.NegotiatedSerializer = unstructuredscheme.NewNegotiatedSerializer()
+	cli, err := rest.UnversionedRESTClientFor(restCfg)
+	require.NoError(t, err)
+
+	reqr := &DiscardRequester{BaseRequester: BaseRequester{method: "GET", req: cli.Get().AbsPath("/api/v1/pods")}}
+	_, span := exp.startSpan(context.Background(), reqr, time.Now())
+	exp.endSpan(span, time.Now(), 0, 0, 0, false, errors.New("boom"))
+	metrics.NewResponseMetric(metrics.WithLiveMetricsOpt(live)).ObserveLatency("GET", "/api/v1/pods", 0.1)
+	require.NoError(t, exp.Close())
+
+	require.Len(t, traces.reqs, 1)
+	spans := traces.reqs[0].ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /api/v1/pods", spans[0].Name)
+	assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, spans[0].Status.Code)
+	assert.Equal(t, "boom", spans[0].Status.Message)
+
+	require.Len(t, metricsData.reqs, 1)
+	var names []string
+	for _, m := range metricsData.reqs[0].ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		names = append(names, m.Name)
+	}
+	assert.Contains(t, names, "kperf_requests_total")
+	assert.Contains(t, names, "kperf_request_duration_seconds")
+}
+
+// otlpAttr returns the value of key in attrs, or nil.
+func otlpAttr(attrs []*commonpb.KeyValue, key string) *commonpb.AnyValue {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return nil
+}
+
+// fakeTraceService records export requests of OTLP/gRPC traces.
+type fakeTraceService struct {
+	coltracepb.UnimplementedTraceServiceServer
+
+	mu   sync.Mutex
+	reqs []*coltracepb.ExportTraceServiceRequest
+}
+
+// Export implements coltracepb.TraceServiceServer.
+func (s *fakeTraceService) Export(_ context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reqs = append(s.reqs, req)
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+// fakeMetricsService records export requests of OTLP/gRPC metrics.
+type fakeMetricsService struct {
+	colmetricpb.UnimplementedMetricsServiceServer
+
+	mu   sync.Mutex
+	reqs []*colmetricpb.ExportMetricsServiceRequest
+}
+
+// Export implements colmetricpb.MetricsServiceServer.
+func (s *fakeMetricsService) Export(_ context.Context, req *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reqs = append(s.reqs, req)
+	return &colmetricpb.ExportMetricsServiceResponse{}, nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+// phaseTimer records the duration of each HTTP request phase via httptrace.
+//
+// If the request is retried, the connection phases are summed up and the
+// TTFB and body read phases are from the last attempt.
+type phaseTimer struct {
+	mu sync.Mutex
+
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+
+	durations map[types.RequestPhase]time.Duration
+}
+
+// newPhaseTimer returns new instance of phaseTimer.
+func newPhaseTimer() *phaseTimer {
+	return &phaseTimer{durations: map[types.RequestPhase]time.Duration{}}
+}
+
+// withClientTrace returns context which reports phases to timer.
+func (p *phaseTimer) withClientTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			p.start(&p.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			p.done(&p.dnsStart, types.RequestPhaseDNS)
+		},
+		ConnectStart: func(_, _ string) {
+			p.start(&p.connectStart)
+		},
+		ConnectDone: func(_, _ string, _ error) {
+			p.done(&p.connectStart, types.RequestPhaseConnect)
+		},
+		TLSHandshakeStart: func() {
+			p.start(&p.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			p.done(&p.tlsStart, types.RequestPhaseTLS)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			p.start(&p.wroteRequest)
+		},
+		GotFirstResponseByte: func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+
+			p.firstByte = time.Now()
+			if !p.wroteRequest.IsZero() {
+				p.durations[types.RequestPhaseTTFB] = p.firstByte.Sub(p.wroteRequest)
+				p.wroteRequest = time.Time{}
+			}
+		},
+	})
+}
+
+// start records the start time of phase.
+func (p *phaseTimer) start(t *time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	*t = time.Now()
+}
+
+// done adds the duration since start to the phase.
+func (p *phaseTimer) done(start *time.Time, phase types.RequestPhase) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if start.IsZero() {
+		return
+	}
+	p.durations[phase] += time.Since(*start)
+	*start = time.Time{}
+}
+
+// phases returns the duration in seconds of each observed phase. The body
+// read phase ends at end.
+func (p *phaseTimer) phases(end time.Time) map[types.RequestPhase]float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	res := make(map[types.RequestPhase]float64, len(p.durations)+1)
+	for phase, d := range p.durations {
+		res[phase] = d.Seconds()
+	}
+	if !p.firstByte.IsZero() {
+		res[types.RequestPhaseBodyRead] = end.Sub(p.firstByte).Seconds()
+	}
+	return res
+}
@@ -0,0 +1,55 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhaseTimer(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	cli := srv.Client()
+
+	get := func() map[types.RequestPhase]float64 {
+		timer := newPhaseTimer()
+		req, err := http.NewRequestWithContext(timer.withClientTrace(context.Background()), http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := cli.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		_, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return timer.phases(time.Now())
+	}
+
+	phases := get()
+	assert.NotContains(t, phases, types.RequestPhaseDNS)
+	assert.Contains(t, phases, types.RequestPhaseConnect)
+	assert.Contains(t, phases, types.RequestPhaseTLS)
+	assert.Contains(t, phases, types.RequestPhaseBodyRead)
+	assert.GreaterOrEqual(t, phases[types.RequestPhaseTTFB], 0.05)
+
+	// The connection is reused.
+	phases = get()
+	assert.NotContains(t, phases, types.RequestPhaseConnect)
+	assert.NotContains(t, phases, types.RequestPhaseTLS)
+	assert.GreaterOrEqual(t, phases[types.RequestPhaseTTFB], 0.05)
+}
@@ -42,6 +42,7 @@ func NewWeightedRandomRequests(spec *types.LoadProfileSpec) (*WeightedRandomRequ
 		return nil, fmt.Errorf("invalid load profile spec: %v", err)
 	}
 
+	retries := maxRetries(spec)
 	shares := make([]int, 0, len(spec.Requests))
 	reqBuilders := make([]RESTRequestBuilder, 0, len(spec.Requests))
 	for _, r := range spec.Requests {
@@ -50,27 +51,38 @@ func NewWeightedRandomRequests(spec *types.LoadProfileSpec) (*WeightedRandomRequ
 		var builder RESTRequestBuilder
 		switch {
 		case r.StaleList != nil:
-			builder = newRequestListBuilder(r.StaleList, "0", spec.MaxRetries)
+			builder = newRequestListBuilder(r.StaleList, "0", retries)
 		case r.QuorumList != nil:
-			builder = newRequestListBuilder(r.QuorumList, "", spec.MaxRetries)
+			builder = newRequestListBuilder(r.QuorumList, "", retries)
 		case r.WatchList != nil:
-			builder = newRequestWatchListBuilder(r.WatchList, spec.MaxRetries)
+			builder = newRequestWatchListBuilder(r.WatchList, retries)
 		case r.StaleGet != nil:
-			builder = newRequestGetBuilder(r.StaleGet, "0", spec.MaxRetries)
+			builder = newRequestGetBuilder(r.StaleGet, "0", retries)
 		case r.QuorumGet != nil:
-			builder = newRequestGetBuilder(r.QuorumGet, "", spec.MaxRetries)
+			builder = newRequestGetBuilder(r.QuorumGet, "", retries)
 		case r.GetPodLog != nil:
-			builder = newRequestGetPodLogBuilder(r.GetPodLog, spec.MaxRetries)
+			builder = newRequestGetPodLogBuilder(r.GetPodLog, retries)
 		case r.Patch != nil:
-			builder = newRequestPatchBuilder(r.Patch, "", spec.MaxRetries)
+			builder = newRequestPatchBuilder(r.Patch, "", retries)
 		case r.PostDel != nil:
-			builder = newRequestPostDelBuilder(r.PostDel, "", spec.MaxRetries)
+			builder = newRequestPostDelBuilder(r.PostDel, "", retries)
 		case r.Put != nil:
-			builder = newRequestPutBuilder(r.Put, spec.MaxRetries)
+			builder = newRequestPutBuilder(r.Put, retries)
 		default:
 			return nil, fmt.Errorf("unknown request type: %+v", r)
 		}
-		reqBuilders = append(reqBuilders, builder)
+		// Watch list and pod log responses aren't decoded since they are
+		// handled by informer and plain text respectively.
+		if spec.Decode != nil && r.WatchList == nil && r.GetPodLog == nil {
+			builder = &decodeRequestBuilder{
+				RESTRequestBuilder: builder,
+				decoder:            newResponseDecoder(spec, r),
+			}
+		}
+		reqBuilders = append(reqBuilders, &timeoutRequestBuilder{
+			RESTRequestBuilder: builder,
+			timeout:            requestTimeout(spec, r),
+		})
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -146,6 +158,52 @@ type RESTRequestBuilder interface {
 	Build(cli rest.Interface) Requester
 }
 
+// timeoutRequestBuilder sets timeout for requests built by the underlying
+// RESTRequestBuilder.
+type timeoutRequestBuilder struct {
+	RESTRequestBuilder
+	timeout time.Duration
+}
+
+// Build implements RequestBuilder.Build.
+func (b *timeoutRequestBuilder) Build(cli rest.Interface) Requester {
+	req := b.RESTRequestBuilder.Build(cli)
+	req.Timeout(b.timeout)
+	return req
+}
+
+// decodeRequestBuilder makes requests built by the underlying
+// RESTRequestBuilder decode responses.
+type decodeRequestBuilder struct {
+	RESTRequestBuilder
+	decoder *responseDecoder
+}
+
+// Build implements RequestBuilder.Build.
+func (b *decodeRequestBuilder) Build(cli rest.Interface) Requester {
+	req := b.RESTRequestBuilder.Build(cli)
+	switch r := req.(type) {
+	case *DiscardRequester:
+		r.decoder = b.decoder
+	case *PostDelDiscardRequester:
+		r.decoder = b.decoder
+	}
+	return req
+}
+
+// requestTimeout returns the timeout for given request. WeightedRequest's
+// timeout takes precedence over LoadProfileSpec's.
+func requestTimeout(spec *types.LoadProfileSpec, r *types.WeightedRequest) time.Duration {
+	timeout := spec.Timeout
+	if r.Timeout > 0 {
+		timeout = r.Timeout
+	}
+	if timeout == 0 {
+		return defaultTimeout
+	}
+	return time.Duration(timeout * float64(time.Second))
+}
+
 type requestGetBuilder struct {
 	version         schema.GroupVersion
 	resource        string
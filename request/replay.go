@@ -0,0 +1,251 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/metrics"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+// ReplayOptions defines how to replay recorded requests.
+type ReplayOptions struct {
+	// Speed is the multiplier applied to the original timing. For instance,
+	// 2 means twice as fast. Zero means 1.
+	Speed float64
+	// Client is the total number of HTTP clients sending requests.
+	Client int
+	// Timeout is the client-side timeout for each request. Zero means the
+	// default timeout.
+	Timeout time.Duration
+	// RawSamples keeps every observed latency in result besides
+	// histograms.
+	RawSamples bool
+}
+
+// AuditReplayOptions defines how to replay audit requests.
+type AuditReplayOptions struct {
+	ReplayOptions
+	// MaxRetries is the ceiling of retrying upon 429 status-code.
+	MaxRetries int
+	// Impersonate means requests are sent as the original users.
+	Impersonate bool
+}
+
+// replayRequest is the request with its arrival time relative to the first
+// request.
+type replayRequest struct {
+	offset  time.Duration
+	builder RESTRequestBuilder
+}
+
+// ReplayAuditRequests sends requests to apiserver with the original
+// inter-arrival timing scaled by speed. If all the clients are busy, the
+// requests are delayed until any client is available. The schedule options
+// about tracing and metrics apply to replay as well.
+func ReplayAuditRequests(ctx context.Context, reqs []*AuditRequest, opts AuditReplayOptions, restCli []rest.Interface, schedOpts ...ScheduleOpt) (*Result, error) {
+	replayReqs := make([]replayRequest, 0, len(reqs))
+	for _, req := range reqs {
+		replayReqs = append(replayReqs, replayRequest{
+			offset: req.Offset,
+			builder: &auditRequestBuilder{
+				req:         req,
+				impersonate: opts.Impersonate,
+				maxRetries:  opts.MaxRetries,
+			},
+		})
+	}
+
+	klog.V(2).InfoS("Replaying audit requests", "impersonate", opts.Impersonate)
+	return replay(ctx, replayReqs, opts.ReplayOptions, restCli, schedOpts...)
+}
+
+// ReplayTrace sends requests recorded in trace to apiserver with the
+// original timing scaled by speed. The host of recorded URLs is replaced by
+// restCli's.
+func ReplayTrace(ctx context.Context, records []*types.TraceRecord, opts ReplayOptions, restCli []rest.Interface, schedOpts ...ScheduleOpt) (*Result, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no requests to replay")
+	}
+
+	// The records are written by concurrent clients so that they might
+	// be slightly out of order.
+	records = slices.Clone(records)
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+
+	replayReqs := make([]replayRequest, 0, len(records))
+	for _, rec := range records {
+		u, err := url.Parse(rec.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL %s in trace: %w", rec.URL, err)
+		}
+
+		replayReqs = append(replayReqs, replayRequest{
+			offset: rec.Timestamp.Sub(records[0].Timestamp),
+			builder: &traceRequestBuilder{
+				rec:        rec,
+				requestURI: u.RequestURI(),
+			},
+		})
+	}
+	return replay(ctx, replayReqs, opts, restCli, schedOpts...)
+}
+
+// replay sends requests on time and returns the result.
+func replay(ctx context.Context, reqs []replayRequest, opts ReplayOptions, restCli []rest.Interface, schedOpts ...ScheduleOpt) (*Result, error) {
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("no requests to replay")
+	}
+
+	var cfg scheduleCfg
+	for _, opt := range schedOpts {
+		opt(&cfg)
+	}
+
+	speed := opts.Speed
+	if speed == 0 {
+		speed = 1
+	}
+	if speed < 0 {
+		return nil, fmt.Errorf("speed requires > 0: %v", speed)
+	}
+
+	clients := opts.Client
+	if clients <= 0 {
+		clients = len(restCli)
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	reqBuilderCh := make(chan RESTRequestBuilder)
+	respMetric := metrics.NewResponseMetric(
+		metrics.WithRawSamplesOpt(opts.RawSamples || cfg.rawSamples),
+		metrics.WithTimeSeriesOpt(cfg.interval),
+		metrics.WithLiveMetricsOpt(cfg.liveMetrics),
+	)
+	executor := &requestExecutor{cfg: &cfg}
+
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		// reuse connection if clients > conns
+		cli := restCli[i%len(restCli)]
+		wg.Add(1)
+		go func(cli rest.Interface) {
+			defer wg.Done()
+
+			for builder := range reqBuilderCh {
+				req := builder.Build(cli)
+				req.Timeout(timeout)
+
+				klog.V(5).Infof("Request URL: %s", req.URL())
+				executor.execute(req, respMetric, nil, false)
+			}
+		}(cli)
+	}
+
+	klog.V(2).InfoS("Replaying",
+		"requests", len(reqs),
+		"clients", clients,
+		"connections", len(restCli),
+		"speed", speed,
+	)
+
+	start := time.Now()
+	sent := dispatchReplayRequests(ctx, reqs, speed, start, reqBuilderCh)
+	close(reqBuilderCh)
+	wg.Wait()
+
+	result := &Result{
+		ResponseStats: respMetric.Gather(),
+		Duration:      time.Since(start),
+		Total:         sent,
+	}
+
+	var interruptErr *InterruptError
+	if errors.As(context.Cause(ctx), &interruptErr) {
+		klog.V(2).InfoS("Replay has been interrupted", "signal", interruptErr.Signal)
+		result.Interrupted = true
+	}
+	return result, nil
+}
+
+// dispatchReplayRequests sends requests to reqBuilderCh on time and returns
+// the number of requests sent.
+func dispatchReplayRequests(ctx context.Context, reqs []replayRequest,
+	speed float64, start time.Time, reqBuilderCh chan<- RESTRequestBuilder) int {
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	var maxLag time.Duration
+	for idx, req := range reqs {
+		due := start.Add(time.Duration(float64(req.offset) / speed))
+		if wait := time.Until(due); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-ctx.Done():
+				return idx
+			case <-timer.C:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return idx
+		case reqBuilderCh <- req.builder:
+		}
+
+		if lag := time.Since(due); lag > maxLag {
+			maxLag = lag
+		}
+	}
+
+	if maxLag > time.Second {
+		klog.Warningf("Replay fell behind the original timing by up to %s, consider more clients", maxLag)
+	}
+	return len(reqs)
+}
+
+// traceRequestBuilder builds request from TraceRecord.
+type traceRequestBuilder struct {
+	rec        *types.TraceRecord
+	requestURI string
+}
+
+// Build implements RequestBuilder.Build.
+func (b *traceRequestBuilder) Build(cli rest.Interface) Requester {
+	req := cli.Verb(b.rec.HTTPMethod).RequestURI(b.requestURI)
+	if b.rec.Body != nil {
+		req = req.Body(b.rec.Body)
+	}
+	if b.rec.ContentType != "" {
+		req = req.SetHeader("Content-Type", b.rec.ContentType)
+	}
+
+	baseReq := BaseRequester{
+		method: b.rec.Method,
+		req:    req,
+	}
+	if b.rec.Method == "WATCHLIST" {
+		return &WatchListRequester{BaseRequester: baseReq}
+	}
+	return &DiscardRequester{BaseRequester: baseReq}
+}
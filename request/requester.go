@@ -23,6 +23,7 @@ type Requester interface {
 	URL() *url.URL
 	MaskedURL() *url.URL
 	Timeout(time.Duration)
+	BackOff(rest.BackoffManager)
 	Do(context.Context) (bytes int64, err error)
 }
 
@@ -59,11 +60,22 @@ func (reqr *BaseRequester) Timeout(timeout time.Duration) {
 	reqr.req.Timeout(timeout)
 }
 
+func (reqr *BaseRequester) BackOff(manager rest.BackoffManager) {
+	reqr.req.BackOff(manager)
+}
+
 type DiscardRequester struct {
 	BaseRequester
+
+	// decoder decodes the response instead of discarding it if it's set.
+	decoder *responseDecoder
 }
 
 func (reqr *DiscardRequester) Do(ctx context.Context) (bytes int64, err error) {
+	if reqr.decoder != nil {
+		return reqr.decoder.do(ctx, reqr.req)
+	}
+
 	respBody, err := reqr.req.Stream(ctx)
 	if err != nil {
 		return 0, err
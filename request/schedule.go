@@ -6,8 +6,11 @@ package request
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Azure/kperf/api/types"
@@ -19,6 +22,8 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// defaultTimeout is used when neither LoadProfileSpec nor WeightedRequest
+// specifies timeout.
 const defaultTimeout = 60 * time.Second
 
 // Result contains responseStats vlaues from Gather() and adds Duration and Total values separately
@@ -28,12 +33,121 @@ type Result struct {
 	Duration time.Duration
 	// Total means the total number of requests.
 	Total int
+	// AbortReason is not empty if the run was stopped by abort conditions.
+	AbortReason string
+	// Warmup is the result of warmup phase if any.
+	Warmup *Result
+	// Interrupted is true if the run was stopped by InterruptError.
+	Interrupted bool
+	// Connections is the report about connections if they're tracked.
+	Connections *types.ConnectionReport
+	// ByEndpoint breaks down the stats by endpoint if connections are
+	// pinned to endpoints.
+	ByEndpoint map[string]types.ResponseStats
+}
+
+// AbortError is used as context's cancel cause to stop Schedule gracefully.
+type AbortError struct {
+	// Reason shows which abort condition fired.
+	Reason string
+}
+
+// Error implements error interface.
+func (e *AbortError) Error() string {
+	return "aborted: " + e.Reason
+}
+
+// InterruptError is used as context's cancel cause when the run is
+// interrupted by signal, for instance, Ctrl-C or pod eviction.
+type InterruptError struct {
+	// Signal is the received signal.
+	Signal os.Signal
+}
+
+// Error implements error interface.
+func (e *InterruptError) Error() string {
+	return "interrupted by signal " + e.Signal.String()
+}
+
+// ScheduleOpt is used to update default schedule setting.
+type ScheduleOpt func(*scheduleCfg)
+
+type scheduleCfg struct {
+	traceWriter *TraceWriter
+	connTracker *ConnTracker
+	endpoints   []string
+	rawSamples  bool
+	interval    time.Duration
+	liveMetrics *metrics.LiveMetrics
+	otlp        *OTLPExporter
+}
+
+// WithScheduleTraceWriterOpt records every dispatched request into tw.
+func WithScheduleTraceWriterOpt(tw *TraceWriter) ScheduleOpt {
+	return func(cfg *scheduleCfg) {
+		cfg.traceWriter = tw
+	}
+}
+
+// WithScheduleConnTrackerOpt reports connections tracked by ct, which should
+// be the one used by the clients. It's required by LoadProfileSpec.ConnChurn.
+func WithScheduleConnTrackerOpt(ct *ConnTracker) ScheduleOpt {
+	return func(cfg *scheduleCfg) {
+		cfg.connTracker = ct
+	}
+}
+
+// WithScheduleEndpointsOpt breaks down the result by endpoint. The endpoints
+// should be the ones passed to WithClientEndpointsOpt when creating clients.
+func WithScheduleEndpointsOpt(endpoints []string) ScheduleOpt {
+	return func(cfg *scheduleCfg) {
+		cfg.endpoints = endpoints
+	}
+}
+
+// WithScheduleRawSamplesOpt keeps every observed latency in result besides
+// histograms.
+func WithScheduleRawSamplesOpt(keep bool) ScheduleOpt {
+	return func(cfg *scheduleCfg) {
+		cfg.rawSamples = keep
+	}
+}
+
+// WithScheduleTimeSeriesOpt breaks down result by the given interval. Zero
+// disables it.
+func WithScheduleTimeSeriesOpt(interval time.Duration) ScheduleOpt {
+	return func(cfg *scheduleCfg) {
+		cfg.interval = interval
+	}
+}
+
+// WithScheduleLiveMetricsOpt exports observations to live while running.
+func WithScheduleLiveMetricsOpt(live *metrics.LiveMetrics) ScheduleOpt {
+	return func(cfg *scheduleCfg) {
+		cfg.liveMetrics = live
+	}
+}
+
+// WithScheduleOTLPExporterOpt exports a span per sampled request to exp.
+func WithScheduleOTLPExporterOpt(exp *OTLPExporter) ScheduleOpt {
+	return func(cfg *scheduleCfg) {
+		cfg.otlp = exp
+	}
 }
 
 // Schedule files requests to apiserver based on LoadProfileSpec.
-func Schedule(ctx context.Context, spec *types.LoadProfileSpec, restCli []rest.Interface) (*Result, error) {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+func Schedule(ctx context.Context, spec *types.LoadProfileSpec, restCli []rest.Interface, opts ...ScheduleOpt) (*Result, error) {
+	var cfg scheduleCfg
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if spec.ConnChurn != nil && cfg.connTracker == nil {
+		return nil, fmt.Errorf("connChurn requires clients with connection tracker")
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
 
 	rndReqs, err := NewWeightedRandomRequests(spec)
 	if err != nil {
@@ -54,10 +168,55 @@ func Schedule(ctx context.Context, spec *types.LoadProfileSpec, restCli []rest.I
 	reqBuilderCh := rndReqs.Chan()
 	var wg sync.WaitGroup
 
-	respMetric := metrics.NewResponseMetric()
+	metricOpts := []metrics.ResponseMetricOpt{
+		metrics.WithRawSamplesOpt(cfg.rawSamples),
+		metrics.WithTimeSeriesOpt(cfg.interval),
+		metrics.WithLiveMetricsOpt(cfg.liveMetrics),
+	}
+	respMetric := metrics.NewResponseMetric(metricOpts...)
+
+	// The responses of requests sent during warmup are observed by
+	// warmupMetric so that the main measurement only covers steady state.
+	var warmingUp atomic.Bool
+	warmupMetric := metrics.NewResponseMetric(metricOpts...)
+	warmingUp.Store(spec.Warmup != nil)
+
+	endpointMetrics := map[string]metrics.ResponseMetric{}
+	for _, ep := range cfg.endpoints {
+		// The time series of endpoint isn't reported.
+		endpointMetrics[ep] = metrics.NewResponseMetric(metrics.WithRawSamplesOpt(cfg.rawSamples))
+	}
+
+	// throughput is set once the measurement starts if connChurn is enabled.
+	var throughput atomic.Pointer[throughputRecorder]
+
+	var abortMonitor *metrics.AbortMonitor
+	if spec.AbortConditions != nil {
+		abortMonitor = metrics.NewAbortMonitor(*spec.AbortConditions)
+		go watchAbortConditions(ctx, abortMonitor, cancel)
+	}
+
+	executor := &requestExecutor{
+		cfg:          &cfg,
+		decode:       spec.Decode != nil,
+		abortMonitor: abortMonitor,
+		throughput:   &throughput,
+	}
 	for i := 0; i < clients; i++ {
 		// reuse connection if clients > conns
 		cli := restCli[i%len(restCli)]
+
+		// The i-th connection is pinned to endpoints[i % len(endpoints)].
+		var endpointMetric metrics.ResponseMetric
+		if len(cfg.endpoints) > 0 {
+			endpointMetric = endpointMetrics[cfg.endpoints[(i%len(restCli))%len(cfg.endpoints)]]
+		}
+
+		var throttle *clientThrottle
+		if spec.ControllerEmulation != nil {
+			throttle = newClientThrottle(spec.ControllerEmulation)
+		}
+
 		wg.Add(1)
 		go func(cli rest.Interface) {
 			defer wg.Done()
@@ -65,51 +224,34 @@ func Schedule(ctx context.Context, spec *types.LoadProfileSpec, restCli []rest.I
 			for builder := range reqBuilderCh {
 				req := builder.Build(cli)
 
+				waitStart := time.Now()
+				if throttle != nil {
+					if err := throttle.wait(ctx, req); err != nil {
+						klog.V(5).Infof("Client rate limiter wait failed: %v", err)
+						cancel(nil)
+						return
+					}
+				}
+
 				if err := limiter.Wait(ctx); err != nil {
 					klog.V(5).Infof("Rate limiter wait failed: %v", err)
-					cancel()
+					cancel(nil)
 					return
 				}
+				waited := time.Since(waitStart).Seconds()
+				if cfg.liveMetrics != nil {
+					cfg.liveMetrics.ObserveLimiterWait(waited)
+				}
 
 				klog.V(5).Infof("Request URL: %s", req.URL())
 
-				req.Timeout(defaultTimeout)
-				func() {
-					start := time.Now()
-
-					var bytes int64
-					bytes, err := req.Do(context.Background())
-					// Based on HTTP2 Spec Section 8.1 [1],
-					//
-					// A server can send a complete response prior to the client
-					// sending an entire request if the response does not depend
-					// on any portion of the request that has not been sent and
-					// received. When this is true, a server MAY request that the
-					// client abort transmission of a request without error by
-					// sending a RST_STREAM with an error code of NO_ERROR after
-					// sending a complete response (i.e., a frame with the END_STREAM
-					// flag). Clients MUST NOT discard responses as a result of receiving
-					// such a RST_STREAM, though clients can always discard responses
-					// at their discretion for other reasons.
-					//
-					// We should mark NO_ERROR as nil here.
-					//
-					// [1]: https://httpwg.org/specs/rfc7540.html#HttpSequence
-					if err != nil && isHTTP2StreamNoError(err) {
-						err = nil
-					}
-
-					end := time.Now()
-					latency := end.Sub(start).Seconds()
-
-					respMetric.ObserveReceivedBytes(bytes)
-					if err != nil {
-						respMetric.ObserveFailure(req.Method(), req.MaskedURL().String(), end, latency, err)
-						klog.V(5).Infof("Request stream failed: %v", err)
-						return
-					}
-					respMetric.ObserveLatency(req.Method(), req.MaskedURL().String(), latency)
-				}()
+				respMetric := respMetric
+				inWarmup := warmingUp.Load()
+				if inWarmup {
+					respMetric = warmupMetric
+				}
+				respMetric.ObserveLimiterWait(waited)
+				executor.execute(req, respMetric, endpointMetric, inWarmup)
 			}
 		}(cli)
 	}
@@ -122,10 +264,46 @@ func Schedule(ctx context.Context, spec *types.LoadProfileSpec, restCli []rest.I
 		"duration", spec.Duration,
 		"http2", !spec.DisableHTTP2,
 		"content-type", spec.ContentType,
+		"controller-emulation", spec.ControllerEmulation != nil,
 	)
 
+	var warmupDuration time.Duration
+	if spec.Warmup != nil {
+		warmupStart := time.Now()
+
+		warmupCtx := ctx
+		if spec.Warmup.Duration > 0 {
+			var warmupCancel context.CancelFunc
+			warmupCtx, warmupCancel = context.WithTimeout(ctx, time.Duration(spec.Warmup.Duration)*time.Second)
+			defer warmupCancel()
+		}
+		rndReqs.Run(warmupCtx, spec.Warmup.Total)
+
+		warmingUp.Store(false)
+		warmupDuration = time.Since(warmupStart)
+		klog.V(2).InfoS("Warmup finished", "duration", warmupDuration)
+	}
+
+	// Connections dialed during warmup are excluded.
+	if cfg.connTracker != nil {
+		cfg.connTracker.reset()
+	}
+
 	start := time.Now()
 
+	churnCtx, churnCancel := context.WithCancel(ctx)
+	defer churnCancel()
+
+	var churnEventsCh chan []types.ConnChurnEvent
+	if spec.ConnChurn != nil {
+		throughput.Store(newThroughputRecorder(start))
+
+		churnEventsCh = make(chan []types.ConnChurnEvent, 1)
+		go func() {
+			churnEventsCh <- runConnChurn(churnCtx, spec.ConnChurn, cfg.connTracker)
+		}()
+	}
+
 	if spec.Duration > 0 {
 		// If duration is set, we will run for duration.
 		var cancel context.CancelFunc
@@ -139,11 +317,80 @@ func Schedule(ctx context.Context, spec *types.LoadProfileSpec, restCli []rest.I
 
 	totalDuration := time.Since(start)
 	responseStats := respMetric.Gather()
-	return &Result{
+	result := &Result{
 		ResponseStats: responseStats,
 		Duration:      totalDuration,
-		Total:         spec.Total,
-	}, nil
+		Total:         dispatchedTotal(responseStats),
+	}
+
+	if len(endpointMetrics) > 0 {
+		result.ByEndpoint = make(map[string]types.ResponseStats, len(endpointMetrics))
+		for ep, m := range endpointMetrics {
+			result.ByEndpoint[ep] = m.Gather()
+		}
+	}
+
+	var churnEvents []types.ConnChurnEvent
+	if churnEventsCh != nil {
+		churnCancel()
+		churnEvents = <-churnEventsCh
+
+		recorder := throughput.Load()
+		for idx := range churnEvents {
+			churnEvents[idx].RecoverySeconds = recorder.recoverySeconds(churnEvents[idx].Timestamp, start.Add(totalDuration))
+		}
+	}
+	if cfg.connTracker != nil {
+		result.Connections = cfg.connTracker.report()
+		result.Connections.ChurnEvents = churnEvents
+	}
+
+	if spec.Warmup != nil && !spec.Warmup.Discard {
+		warmupStats := warmupMetric.Gather()
+		result.Warmup = &Result{
+			ResponseStats: warmupStats,
+			Duration:      warmupDuration,
+			Total:         dispatchedTotal(warmupStats),
+		}
+	}
+
+	var abortErr *AbortError
+	if errors.As(context.Cause(ctx), &abortErr) {
+		klog.V(2).InfoS("Run has been aborted", "reason", abortErr.Reason)
+		result.AbortReason = abortErr.Reason
+	}
+
+	var interruptErr *InterruptError
+	if errors.As(context.Cause(ctx), &interruptErr) {
+		klog.V(2).InfoS("Run has been interrupted", "signal", interruptErr.Signal)
+		result.Interrupted = true
+	}
+	return result, nil
+}
+
+// dispatchedTotal returns the number of requests which have been sent.
+func dispatchedTotal(stats types.ResponseStats) int {
+	dispatched, _, _ := metrics.SumRequestCounts(stats.CountsByURL)
+	return dispatched
+}
+
+// watchAbortConditions checks abort conditions every second and cancels the
+// run if any condition fires.
+func watchAbortConditions(ctx context.Context, m *metrics.AbortMonitor, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if reason, aborted := m.Check(now); aborted {
+				cancel(&AbortError{Reason: reason})
+				return
+			}
+		}
+	}
 }
 
 // isHTTP2StreamNoError returns true if it's NO_ERROR.
@@ -0,0 +1,187 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/metrics"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+// ThroughputTrialFunc runs one trial with the given offered QPS.
+type ThroughputTrialFunc func(ctx context.Context, rate float64) (*types.ThroughputSearchTrial, error)
+
+// errSearchInterrupted stops the search when the context is done.
+var errSearchInterrupted = errors.New("throughput search interrupted")
+
+// SearchMaxThroughput runs short trials with different rates and returns
+// the highest offered QPS which stays within the SLO defined by spec. If ctx
+// is done, the search stops and the report only covers the trials finished
+// before that.
+func SearchMaxThroughput(ctx context.Context, spec *types.ThroughputSearchSpec, trialFn ThroughputTrialFunc) (*types.ThroughputSearchReport, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid throughput search spec: %w", err)
+	}
+
+	report := &types.ThroughputSearchReport{
+		Spec:   *spec,
+		Trials: []types.ThroughputSearchTrial{},
+	}
+
+	err := searchMaxThroughput(ctx, spec, trialFn, report)
+	if errors.Is(err, errSearchInterrupted) {
+		klog.Warningf("Throughput search stopped: %v", context.Cause(ctx))
+		report.Interrupted = true
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func searchMaxThroughput(ctx context.Context, spec *types.ThroughputSearchSpec, trialFn ThroughputTrialFunc, report *types.ThroughputSearchReport) error {
+	runTrial := func(rate float64) (bool, error) {
+		if ctx.Err() != nil {
+			return false, errSearchInterrupted
+		}
+		klog.V(2).InfoS("Running throughput search trial", "rate", rate, "duration", spec.TrialDuration)
+
+		trial, err := trialFn(ctx, rate)
+		// The trial cut short doesn't tell whether the rate is
+		// sustainable.
+		if ctx.Err() != nil {
+			return false, errSearchInterrupted
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to run trial with rate %v: %w", rate, err)
+		}
+		trial.OfferedQPS = rate
+		trial.Passed = withinThroughputSLO(spec, trial)
+
+		klog.V(2).InfoS("Throughput search trial finished",
+			"rate", rate,
+			"achieved", trial.AchievedQPS,
+			"p99", trial.P99Latency,
+			"errorRate", trial.ErrorRate,
+			"passed", trial.Passed,
+		)
+
+		report.Trials = append(report.Trials, *trial)
+		if trial.Passed && rate >= report.MaxQPS {
+			report.MaxQPS = rate
+			report.MaxAchievedQPS = trial.AchievedQPS
+		}
+		return trial.Passed, nil
+	}
+
+	switch spec.Mode {
+	case types.ThroughputSearchModeStep:
+		for rate := spec.MinRate; rate <= spec.MaxRate; rate += spec.Step {
+			passed, err := runTrial(rate)
+			if err != nil {
+				return err
+			}
+			if !passed {
+				break
+			}
+		}
+	case types.ThroughputSearchModeBinary:
+		lo, hi := spec.MinRate, spec.MaxRate
+
+		passed, err := runTrial(lo)
+		if err != nil || !passed {
+			return err
+		}
+
+		if hi > lo {
+			passed, err = runTrial(hi)
+			if err != nil || passed {
+				return err
+			}
+		}
+
+		for hi-lo > spec.Precision {
+			mid := (lo + hi) / 2
+			passed, err := runTrial(mid)
+			if err != nil {
+				return err
+			}
+			if passed {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+	}
+	return nil
+}
+
+// withinThroughputSLO returns true if the trial meets the SLO.
+func withinThroughputSLO(spec *types.ThroughputSearchSpec, trial *types.ThroughputSearchTrial) bool {
+	if trial.Total == 0 {
+		return false
+	}
+	if spec.MaxP99Latency > 0 && trial.P99Latency > spec.MaxP99Latency {
+		return false
+	}
+	return trial.ErrorRate <= spec.MaxErrorRate
+}
+
+// NewThroughputSearchTrial builds a trial from request counters and latency
+// percentiles, which are in the format returned by metrics.BuildPercentileLatencies.
+func NewThroughputSearchTrial(succeeded, failed int, duration time.Duration, percentileLatencies [][2]float64) *types.ThroughputSearchTrial {
+	trial := &types.ThroughputSearchTrial{
+		Total:    succeeded + failed,
+		Duration: duration.String(),
+	}
+
+	if secs := duration.Seconds(); secs > 0 {
+		trial.AchievedQPS = float64(succeeded) / secs
+	}
+	if trial.Total > 0 {
+		trial.ErrorRate = float64(failed) * 100 / float64(trial.Total)
+	}
+
+	for _, pl := range percentileLatencies {
+		switch pl[0] {
+		case 0.5:
+			trial.P50Latency = pl[1]
+		case 0.99:
+			trial.P99Latency = pl[1]
+		}
+	}
+	return trial
+}
+
+// NewLocalThroughputTrialFunc returns ThroughputTrialFunc which runs spec
+// with given rest clients for trialDuration seconds.
+func NewLocalThroughputTrialFunc(spec *types.LoadProfileSpec, restCli []rest.Interface, trialDuration int) ThroughputTrialFunc {
+	return func(ctx context.Context, rate float64) (*types.ThroughputSearchTrial, error) {
+		trialSpec := *spec
+		trialSpec.Rate = rate
+		trialSpec.Total = 0
+		trialSpec.Duration = trialDuration
+
+		result, err := Schedule(ctx, &trialSpec, restCli)
+		if err != nil {
+			return nil, err
+		}
+
+		var hist *types.LatencyHistogram
+		for _, h := range result.LatencyHistogramsByURL {
+			hist = metrics.MergeLatencyHistograms(hist, h)
+		}
+		_, succeeded, failed := metrics.SumRequestCounts(result.CountsByURL)
+		return NewThroughputSearchTrial(succeeded, failed, result.Duration,
+			metrics.BuildPercentileLatenciesFromHistogram(hist)), nil
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKneeTrialFunc returns trials whose p99 latency breaks 1s above knee QPS.
+func fakeKneeTrialFunc(knee float64) ThroughputTrialFunc {
+	return func(_ context.Context, rate float64) (*types.ThroughputSearchTrial, error) {
+		p99 := 0.1
+		if rate > knee {
+			p99 = 2
+		}
+		return NewThroughputSearchTrial(int(rate)*10, 0, 10*time.Second,
+			[][2]float64{{0.5, 0.05}, {0.99, p99}}), nil
+	}
+}
+
+func TestSearchMaxThroughput(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		spec        types.ThroughputSearchSpec
+		expectedQPS float64
+		offered     []float64
+	}{
+		{
+			name: "step",
+			spec: types.ThroughputSearchSpec{
+				Mode:          types.ThroughputSearchModeStep,
+				MinRate:       100,
+				MaxRate:       1000,
+				Step:          100,
+				TrialDuration: 10,
+				MaxP99Latency: 1,
+			},
+			expectedQPS: 300,
+			offered:     []float64{100, 200, 300, 400},
+		},
+		{
+			name: "binary",
+			spec: types.ThroughputSearchSpec{
+				Mode:          types.ThroughputSearchModeBinary,
+				MinRate:       100,
+				MaxRate:       500,
+				Precision:     50,
+				TrialDuration: 10,
+				MaxP99Latency: 1,
+			},
+			expectedQPS: 300,
+			offered:     []float64{100, 500, 300, 400, 350},
+		},
+		{
+			name: "binary-max-rate-passed",
+			spec: types.ThroughputSearchSpec{
+				Mode:          types.ThroughputSearchModeBinary,
+				MinRate:       100,
+				MaxRate:       300,
+				Precision:     50,
+				TrialDuration: 10,
+				MaxP99Latency: 1,
+			},
+			expectedQPS: 300,
+			offered:     []float64{100, 300},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			report, err := SearchMaxThroughput(context.TODO(), &tc.spec, fakeKneeTrialFunc(340))
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedQPS, report.MaxQPS)
+			assert.Equal(t, tc.expectedQPS, report.MaxAchievedQPS)
+
+			offered := make([]float64, 0, len(report.Trials))
+			for _, trial := range report.Trials {
+				offered = append(offered, trial.OfferedQPS)
+			}
+			assert.Equal(t, tc.offered, offered)
+		})
+	}
+}
+
+func TestSearchMaxThroughputInterrupted(t *testing.T) {
+	spec := types.ThroughputSearchSpec{
+		Mode:          types.ThroughputSearchModeStep,
+		MinRate:       100,
+		MaxRate:       1000,
+		Step:          100,
+		TrialDuration: 10,
+		MaxP99Latency: 1,
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	trialFn := fakeKneeTrialFunc(340)
+	report, err := SearchMaxThroughput(ctx, &spec, func(ctx context.Context, rate float64) (*types.ThroughputSearchTrial, error) {
+		// The signal arrives during the second trial.
+		if rate == 200 {
+			cancel(&InterruptError{Signal: syscall.SIGTERM})
+		}
+		return trialFn(ctx, rate)
+	})
+	require.NoError(t, err)
+
+	assert.True(t, report.Interrupted)
+	require.Len(t, report.Trials, 1)
+	assert.Equal(t, float64(100), report.MaxQPS)
+}
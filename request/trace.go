@@ -0,0 +1,224 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+// roundTripInfoKey is the context key of roundTripInfo.
+type roundTripInfoKey struct{}
+
+// roundTripInfo records the request sent over the wire and the status of
+// the last response.
+type roundTripInfo struct {
+	method      string
+	url         string
+	contentType string
+	body        []byte
+	status      int
+}
+
+// withRoundTripInfo returns context which asks traceRoundTripper to fill info.
+func withRoundTripInfo(ctx context.Context, info *roundTripInfo) context.Context {
+	return context.WithValue(ctx, roundTripInfoKey{}, info)
+}
+
+// traceRoundTripper fills roundTripInfo if the request's context has one.
+type traceRoundTripper struct {
+	rt http.RoundTripper
+}
+
+// newTraceRoundTripper wraps rt with traceRoundTripper.
+func newTraceRoundTripper(rt http.RoundTripper) http.RoundTripper {
+	return &traceRoundTripper{rt: rt}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *traceRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	info, ok := req.Context().Value(roundTripInfoKey{}).(*roundTripInfo)
+	if !ok {
+		return t.rt.RoundTrip(req)
+	}
+
+	info.method = req.Method
+	info.url = req.URL.String()
+	info.contentType = req.Header.Get("Content-Type")
+	info.body = nil
+	info.status = 0
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			info.body, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+
+	resp, err := t.rt.RoundTrip(req)
+	if err == nil {
+		info.status = resp.StatusCode
+	}
+	return resp, err
+}
+
+// WrappedRoundTripper implements k8s.io/client-go/transport.WrappedRoundTripper.
+func (t *traceRoundTripper) WrappedRoundTripper() http.RoundTripper {
+	return t.rt
+}
+
+// TraceWriter writes dispatched requests into trace file.
+type TraceWriter struct {
+	mu sync.Mutex
+
+	gw *gzip.Writer
+	bw *bufio.Writer
+	// bodyHashes records the bodies which have been written.
+	bodyHashes map[string]struct{}
+	err        error
+}
+
+// NewTraceWriter returns new instance of TraceWriter.
+func NewTraceWriter(w io.Writer) *TraceWriter {
+	gw := gzip.NewWriter(w)
+	return &TraceWriter{
+		gw:         gw,
+		bw:         bufio.NewWriter(gw),
+		bodyHashes: map[string]struct{}{},
+	}
+}
+
+// Write writes one record. The body is only written by the first record
+// with the same hash.
+func (tw *TraceWriter) Write(rec *types.TraceRecord) error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.err != nil {
+		return tw.err
+	}
+
+	if rec.Body != nil {
+		sum := sha256.Sum256(rec.Body)
+		rec.BodyHash = hex.EncodeToString(sum[:])
+		rec.BodySize = len(rec.Body)
+		if _, ok := tw.bodyHashes[rec.BodyHash]; ok {
+			rec.Body = nil
+		} else {
+			tw.bodyHashes[rec.BodyHash] = struct{}{}
+		}
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trace record: %w", err)
+	}
+	data = append(data, '\n')
+
+	if _, err := tw.bw.Write(data); err != nil {
+		tw.err = fmt.Errorf("failed to write trace record: %w", err)
+		return tw.err
+	}
+	return nil
+}
+
+// Close flushes records. It doesn't close the underlying writer.
+func (tw *TraceWriter) Close() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.err != nil {
+		return tw.err
+	}
+
+	tw.err = errors.New("trace writer has been closed")
+	if err := tw.bw.Flush(); err != nil {
+		return fmt.Errorf("failed to flush trace records: %w", err)
+	}
+	if err := tw.gw.Close(); err != nil {
+		return fmt.Errorf("failed to flush trace records: %w", err)
+	}
+	return nil
+}
+
+// ReadTrace reads all records from trace file. The body of each record is
+// filled even if it was written by the previous record.
+func ReadTrace(r io.Reader) ([]*types.TraceRecord, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gzip reader: %w", err)
+	}
+	defer gr.Close()
+
+	bodies := map[string][]byte{}
+	res := make([]*types.TraceRecord, 0, 1024)
+
+	decoder := json.NewDecoder(gr)
+	for {
+		rec := &types.TraceRecord{}
+		if err := decoder.Decode(rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to decode trace record %d: %w", len(res)+1, err)
+		}
+
+		if rec.BodyHash != "" {
+			if rec.Body != nil {
+				bodies[rec.BodyHash] = rec.Body
+			} else if body, ok := bodies[rec.BodyHash]; ok {
+				rec.Body = body
+			} else {
+				return nil, fmt.Errorf("trace record %d refers to unknown body %s", len(res)+1, rec.BodyHash)
+			}
+		}
+		res = append(res, rec)
+	}
+	return res, nil
+}
+
+// newTraceRecord builds TraceRecord from request and roundTripInfo.
+func newTraceRecord(req Requester, info *roundTripInfo, start time.Time, latency float64, bytes int64, err error) *types.TraceRecord {
+	rec := &types.TraceRecord{
+		Timestamp:   start,
+		Method:      req.Method(),
+		HTTPMethod:  info.method,
+		URL:         info.url,
+		ContentType: info.contentType,
+		Body:        info.body,
+		Status:      info.status,
+		Latency:     latency,
+		Bytes:       bytes,
+	}
+	// The request didn't reach transport.
+	if rec.URL == "" {
+		rec.HTTPMethod = httpMethodOf(rec.Method)
+		rec.URL = req.URL().String()
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	return rec
+}
+
+// httpMethodOf returns http method for the method used in metrics.
+func httpMethodOf(method string) string {
+	switch method {
+	case "LIST", "WATCHLIST", "POD_LOG":
+		return http.MethodGet
+	default:
+		return method
+	}
+}
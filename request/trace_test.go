@@ -0,0 +1,134 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package request
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+	"github.com/Azure/kperf/request/unstructuredscheme"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+)
+
+func TestTraceWriteAndRead(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	body := []byte(`{"kind":"Pod"}`)
+
+	var buf bytes.Buffer
+	tw := NewTraceWriter(&buf)
+	for i := 0; i < 3; i++ {
+		rec := &types.TraceRecord{
+			Timestamp:  start.Add(time.Duration(i) * time.Second),
+			Method:     "POST",
+			HTTPMethod: "POST",
+			URL:        "https://127.0.0.1/api/v1/namespaces/default/pods",
+			Body:       append([]byte(nil), body...),
+			Status:     201,
+		}
+		require.NoError(t, tw.Write(rec))
+	}
+	require.NoError(t, tw.Write(&types.TraceRecord{
+		Timestamp:  start.Add(3 * time.Second),
+		Method:     "LIST",
+		HTTPMethod: "GET",
+		URL:        "https://127.0.0.1/api/v1/pods?resourceVersion=0",
+		Status:     200,
+	}))
+	require.NoError(t, tw.Close())
+	assert.Error(t, tw.Write(&types.TraceRecord{}))
+
+	// The body is only written once.
+	assert.Equal(t, 1, strings.Count(decompressTrace(t, buf.Bytes()), `"body":`))
+
+	records, err := ReadTrace(&buf)
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, body, records[i].Body)
+		assert.Equal(t, len(body), records[i].BodySize)
+		assert.Equal(t, records[0].BodyHash, records[i].BodyHash)
+	}
+	assert.Nil(t, records[3].Body)
+	assert.Empty(t, records[3].BodyHash)
+	assert.Equal(t, start.Add(3*time.Second), records[3].Timestamp.UTC())
+}
+
+func TestTraceRoundTripper(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	cli := &http.Client{Transport: newTraceRoundTripper(http.DefaultTransport)}
+
+	info := &roundTripInfo{}
+	req, err := http.NewRequestWithContext(withRoundTripInfo(context.Background(), info),
+		http.MethodPut, srv.URL+"/api/v1/namespaces/default/configmaps/x", strings.NewReader("data"))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := cli.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.MethodPut, info.method)
+	assert.Equal(t, srv.URL+"/api/v1/namespaces/default/configmaps/x", info.url)
+	assert.Equal(t, "application/json", info.contentType)
+	assert.Equal(t, []byte("data"), info.body)
+	assert.Equal(t, http.StatusTooManyRequests, info.status)
+}
+
+func decompressTrace(t *testing.T, data []byte) string {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	defer gr.Close()
+
+	raw, err := io.ReadAll(gr)
+	require.NoError(t, err)
+	return string(raw)
+}
+
+func TestReplayTrace(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	restCfg := &rest.Config{
+		Host: srv.URL,
+		// Make transport uncacheable.
+		Proxy: http.ProxyFromEnvironment,
+	}
+	restCfg.NegotiatedSerializer = unstructuredscheme.NewNegotiatedSerializer()
+	cli, err := rest.UnversionedRESTClientFor(restCfg)
+	require.NoError(t, err)
+
+	start := time.Now()
+	records := []*types.TraceRecord{
+		{Timestamp: start, Method: "GET", HTTPMethod: "GET", URL: "https://127.0.0.1/api/v1/namespaces/default/pods/a"},
+		{Timestamp: start.Add(10 * time.Millisecond), Method: "LIST", HTTPMethod: "GET", URL: "https://127.0.0.1/api/v1/pods"},
+	}
+
+	result, err := ReplayTrace(context.Background(), records, ReplayOptions{Client: 1}, []rest.Interface{cli},
+		WithScheduleTimeSeriesOpt(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Total)
+	// Replay shares the observation with Schedule.
+	require.NotNil(t, result.TimeSeries)
+	assert.NotEmpty(t, result.TimeSeries.Points)
+	assert.NotEmpty(t, result.PhaseLatencyHistograms)
+	assert.Len(t, result.LatencyHistogramsByURL, 2)
+}
@@ -34,6 +34,9 @@ var (
 	errRetryable = errors.New("retry")
 )
 
+// runnerMetricsLabel is the label of runner pods which serve live metrics.
+const runnerMetricsLabel = "kperf.io/runner-metrics"
+
 // Handler is to run a set of runners with same load profile.
 type Handler struct {
 	name      string
@@ -48,6 +51,9 @@ type Handler struct {
 	clientset kubernetes.Interface
 
 	runnerVerbosity int
+	// runnerMetricsPort is the port serving runner's live metrics. Zero
+	// disables it.
+	runnerMetricsPort int
 }
 
 // NewHandler returns new instance of Handler.
@@ -57,6 +63,7 @@ func NewHandler(
 	spec *types.RunnerGroupSpec,
 	imageRef string,
 	runnerVerbosity int,
+	runnerMetricsPort int,
 ) (*Handler, error) {
 	ownRef, err := buildOwnerReference(spec.OwnerReference)
 	if err != nil {
@@ -64,13 +71,14 @@ func NewHandler(
 	}
 
 	return &Handler{
-		name:            name,
-		namespace:       namespace,
-		spec:            spec,
-		ownerRef:        ownRef,
-		imageRef:        imageRef,
-		clientset:       clientset,
-		runnerVerbosity: runnerVerbosity,
+		name:              name,
+		namespace:         namespace,
+		spec:              spec,
+		ownerRef:          ownRef,
+		imageRef:          imageRef,
+		clientset:         clientset,
+		runnerVerbosity:   runnerVerbosity,
+		runnerMetricsPort: runnerMetricsPort,
 	}, nil
 }
 
@@ -111,11 +119,15 @@ func (h *Handler) Info(ctx context.Context) *types.RunnerGroup {
 }
 
 // Deploy deploys a group of runners.
-func (h *Handler) Deploy(ctx context.Context, uploadURL string) error {
+//
+// The runners upload their reports to uploadURL and use abortURL to report
+// and watch runner group's abort. If trace is enabled, the runners upload
+// their trace files to traceURL.
+func (h *Handler) Deploy(ctx context.Context, uploadURL, abortURL, traceURL string) error {
 	if err := h.uploadLoadProfileAsConfigMap(ctx); err != nil {
 		return fmt.Errorf("failed to ensure if load profile has been uploaded: %w", err)
 	}
-	return h.deployRunners(ctx, uploadURL)
+	return h.deployRunners(ctx, uploadURL, abortURL, traceURL)
 }
 
 // configMapDataKeyLoadProfile is load profile's name in configmap.
@@ -160,13 +172,13 @@ func (h *Handler) uploadLoadProfileAsConfigMap(ctx context.Context) error {
 }
 
 // deployRunners deploys a group of runners as batch job.
-func (h *Handler) deployRunners(ctx context.Context, uploadURL string) error {
+func (h *Handler) deployRunners(ctx context.Context, uploadURL, abortURL, traceURL string) error {
 	cli := h.clientset.BatchV1().Jobs(h.namespace)
 
 	_, err := cli.Get(ctx, h.name, metav1.GetOptions{})
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			_, err = cli.Create(ctx, h.buildBatchJobObject(uploadURL), metav1.CreateOptions{})
+			_, err = cli.Create(ctx, h.buildBatchJobObject(uploadURL, abortURL, traceURL), metav1.CreateOptions{})
 		}
 		return err
 	}
@@ -354,7 +366,7 @@ func (h *Handler) waitForJob(ctx context.Context, w watch.Interface, rv *string)
 }
 
 // buildBatchJobObject builds job object to run runners.
-func (h *Handler) buildBatchJobObject(uploadURL string) *batchv1.Job {
+func (h *Handler) buildBatchJobObject(uploadURL, abortURL, traceURL string) *batchv1.Job {
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      h.name,
@@ -414,6 +426,10 @@ func (h *Handler) buildBatchJobObject(uploadURL string) *batchv1.Job {
 						Name:  "TARGET_URL",
 						Value: uploadURL,
 					},
+					{
+						Name:  "ABORT_URL",
+						Value: abortURL,
+					},
 					{
 						Name:  "RUNNER_VERBOSITY",
 						Value: strconv.Itoa(h.runnerVerbosity),
@@ -435,6 +451,9 @@ func (h *Handler) buildBatchJobObject(uploadURL string) *batchv1.Job {
 			},
 		},
 		RestartPolicy: corev1.RestartPolicyNever,
+		// Leave enough time for runner to drain in-flight requests and
+		// upload partial result after receiving SIGTERM.
+		TerminationGracePeriodSeconds: toPtr(int64(120)),
 		TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
 			{
 				MaxSkew:           1,
@@ -495,6 +514,38 @@ func (h *Handler) buildBatchJobObject(uploadURL string) *batchv1.Job {
 		job.Spec.Template.Spec.ServiceAccountName = *sa
 	}
 
+	if h.runnerMetricsPort > 0 {
+		port := strconv.Itoa(h.runnerMetricsPort)
+
+		// NOTE: It should be aligned with ../../manifests/runnergroup/server/templates/service.yaml.
+		meta := &job.Spec.Template.ObjectMeta
+		meta.Labels[runnerMetricsLabel] = "true"
+		meta.Annotations = map[string]string{
+			"prometheus.io/scrape": "true",
+			"prometheus.io/port":   port,
+			"prometheus.io/path":   "/metrics",
+		}
+
+		container := &job.Spec.Template.Spec.Containers[0]
+		container.Ports = append(container.Ports, corev1.ContainerPort{
+			Name:          "metrics",
+			ContainerPort: int32(h.runnerMetricsPort),
+			Protocol:      corev1.ProtocolTCP,
+		})
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "METRICS_ADDRESS",
+			Value: ":" + port,
+		})
+	}
+
+	if h.spec.Trace {
+		container := &job.Spec.Template.Spec.Containers[0]
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "TRACE_URL",
+			Value: traceURL,
+		})
+	}
+
 	return job
 }
 
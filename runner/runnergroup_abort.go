@@ -0,0 +1,100 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Azure/kperf/api/types"
+
+	"k8s.io/klog/v2"
+)
+
+// ReportRunnerGroupAbort notifies runner group's server that this runner
+// has been aborted so that the server can propagate it to other runners.
+//
+// NOTE: Please align abortURL with ./server.go.
+func ReportRunnerGroupAbort(ctx context.Context, abortURL string, reason string) error {
+	data, err := json.Marshal(types.RunnerGroupAbort{Reason: reason})
+	if err != nil {
+		return fmt.Errorf("failed to marshal abort: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", abortURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to init POST request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to access %s: %w", abortURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusConflict {
+		errInRaw, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to report abort (http code = %v): %s", resp.Status, string(errInRaw))
+	}
+	return nil
+}
+
+// WatchRunnerGroupAbort polls runner group's server until any runner in the
+// group has been aborted. It returns nil if ctx is done before that.
+//
+// NOTE: Please align abortURL with ./server.go.
+func WatchRunnerGroupAbort(ctx context.Context, abortURL string, interval time.Duration) *types.RunnerGroupAbort {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		abort, err := getRunnerGroupAbort(ctx, abortURL)
+		if err != nil {
+			klog.V(5).ErrorS(err, "failed to get runner group abort", "url", abortURL)
+			continue
+		}
+		if abort != nil {
+			return abort
+		}
+	}
+}
+
+// getRunnerGroupAbort returns nil if the runner group hasn't been aborted.
+func getRunnerGroupAbort(ctx context.Context, abortURL string) (*types.RunnerGroupAbort, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", abortURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init GET request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access %s: %w", abortURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected http code %v", resp.Status)
+	}
+
+	abort := &types.RunnerGroupAbort{}
+	if err := json.NewDecoder(resp.Body).Decode(abort); err != nil {
+		return nil, fmt.Errorf("failed to decode abort: %w", err)
+	}
+	return abort, nil
+}
@@ -0,0 +1,59 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/Azure/kperf/api/types"
+)
+
+// GetRunnerGroupRunnerTrace downloads trace file uploaded by the runner and
+// writes it into w.
+func GetRunnerGroupRunnerTrace(ctx context.Context, kubecfgPath string, runnerName string, w io.Writer) error {
+	host, done, err := initPortForwardToServer(kubecfgPath)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	targetURL := fmt.Sprintf("http://%s/v1/runnergroups/%s/trace", host, url.PathEscape(runnerName))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", targetURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to init GET request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to access %s by portforward: %w", targetURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errInRaw, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read error message when http code = %v: %w",
+				resp.Status, err)
+		}
+
+		herr := types.HTTPError{}
+		err = json.Unmarshal(errInRaw, &herr)
+		if err != nil {
+			return fmt.Errorf("failed to get error when http code = %v: %w",
+				resp.Status, err)
+		}
+		return herr
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to download trace: %w", err)
+	}
+	return nil
+}
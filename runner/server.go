@@ -4,12 +4,15 @@
 package runner
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net"
 	"net/http"
+	"strconv"
 	"sync"
 
 	"github.com/Azure/kperf/api/types"
@@ -26,6 +29,10 @@ type Server struct {
 	groups    []*group.Handler
 	readyCh   chan struct{}
 	report    *types.RunnerMetricReport
+
+	abortMu sync.Mutex
+	// abort records the first runner which has been aborted.
+	abort *types.RunnerGroupAbort
 }
 
 // NewServer returns new instance of server.
@@ -62,6 +69,12 @@ func (s *Server) Run() error {
 	// NOTE: Please update ./runnergroup_result.go if endpoint has been changed.
 	r.HandleFunc("/v1/runnergroups/summary", s.getRunnerGroupsSummary).Methods("GET")
 	r.HandleFunc("/v1/runnergroups/{runner_name}/result", s.postRunnerGroupsRunnerResult).Methods("POST")
+	// NOTE: Please update ./runnergroup_abort.go if endpoint has been changed.
+	r.HandleFunc("/v1/runnergroups/{runner_name}/abort", s.getRunnerGroupsAbort).Methods("GET")
+	r.HandleFunc("/v1/runnergroups/{runner_name}/abort", s.postRunnerGroupsRunnerAbort).Methods("POST")
+	// NOTE: Please update ./runnergroup_trace.go if endpoint has been changed.
+	r.HandleFunc("/v1/runnergroups/{runner_name}/trace", s.getRunnerGroupsRunnerTrace).Methods("GET")
+	r.HandleFunc("/v1/runnergroups/{runner_name}/trace", s.postRunnerGroupsRunnerTrace).Methods("POST")
 
 	errCh := make(chan error, len(s.listeners))
 	var wg sync.WaitGroup
@@ -126,19 +139,46 @@ func (s *Server) getRunnerGroupsSummary(w http.ResponseWriter, r *http.Request)
 // postRunnerGroupsRunnerResult receives summary result from runner.
 func (s *Server) postRunnerGroupsRunnerResult(w http.ResponseWriter, r *http.Request) {
 	runnerName := mux.Vars(r)["runner_name"]
-	ctx := r.Context()
+	s.storeRunnerBlob(w, r, runnerName, runnerName)
+}
 
-	var found = false
-	var err error
-	for _, g := range s.groups {
-		found, err = g.IsControlled(ctx, runnerName)
-		if err != nil {
-			renderErrorResponse(w, http.StatusInternalServerError, err)
-			return
-		}
-		if found {
-			break
+// postRunnerGroupsRunnerTrace receives trace file from runner.
+func (s *Server) postRunnerGroupsRunnerTrace(w http.ResponseWriter, r *http.Request) {
+	runnerName := mux.Vars(r)["runner_name"]
+	s.storeRunnerBlob(w, r, runnerName, traceRef(runnerName))
+}
+
+// getRunnerGroupsRunnerTrace returns trace file uploaded by runner.
+func (s *Server) getRunnerGroupsRunnerTrace(w http.ResponseWriter, r *http.Request) {
+	runnerName := mux.Vars(r)["runner_name"]
+
+	reader, err := s.store.OpenReader(traceRef(runnerName))
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, fs.ErrNotExist) {
+			code = http.StatusNotFound
+			err = fmt.Errorf("no trace for runner %s", runnerName)
 		}
+		renderErrorResponse(w, code, err)
+		return
+	}
+	defer reader.Close()
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Length", strconv.FormatInt(reader.Size(), 10))
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, reader)
+}
+
+// storeRunnerBlob stores request's body as ref if runner is controlled by
+// any runner group.
+func (s *Server) storeRunnerBlob(w http.ResponseWriter, r *http.Request, runnerName, ref string) {
+	ctx := r.Context()
+
+	found, err := s.isControlledRunner(ctx, runnerName)
+	if err != nil {
+		renderErrorResponse(w, http.StatusInternalServerError, err)
+		return
 	}
 
 	if !found {
@@ -159,7 +199,7 @@ func (s *Server) postRunnerGroupsRunnerResult(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = writer.Commit(runnerName)
+	err = writer.Commit(ref)
 	if err != nil {
 		code := http.StatusInternalServerError
 		if errors.Is(err, localstore.ErrAlreadyExists) {
@@ -170,3 +210,73 @@ func (s *Server) postRunnerGroupsRunnerResult(w http.ResponseWriter, r *http.Req
 	}
 	w.WriteHeader(http.StatusCreated)
 }
+
+// traceRef returns the ref of runner's trace file in localstore.
+func traceRef(runnerName string) string {
+	return runnerName + ".trace"
+}
+
+// getRunnerGroupsAbort returns the abort if any runner has been aborted.
+func (s *Server) getRunnerGroupsAbort(w http.ResponseWriter, _ *http.Request) {
+	s.abortMu.Lock()
+	abort := s.abort
+	s.abortMu.Unlock()
+
+	if abort == nil {
+		renderErrorResponse(w, http.StatusNotFound, fmt.Errorf("runner group is not aborted"))
+		return
+	}
+
+	data, _ := json.Marshal(abort)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+// postRunnerGroupsRunnerAbort receives abort from runner. Only the first
+// abort is recorded.
+func (s *Server) postRunnerGroupsRunnerAbort(w http.ResponseWriter, r *http.Request) {
+	runnerName := mux.Vars(r)["runner_name"]
+	ctx := r.Context()
+
+	found, err := s.isControlledRunner(ctx, runnerName)
+	if err != nil {
+		renderErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !found {
+		renderErrorResponse(w, http.StatusNotFound, fmt.Errorf("no such runner %s", runnerName))
+		return
+	}
+
+	abort := &types.RunnerGroupAbort{}
+	if err := json.NewDecoder(r.Body).Decode(abort); err != nil {
+		renderErrorResponse(w, http.StatusBadRequest, fmt.Errorf("failed to decode abort: %w", err))
+		return
+	}
+	abort.Runner = runnerName
+
+	s.abortMu.Lock()
+	defer s.abortMu.Unlock()
+
+	if s.abort != nil {
+		renderErrorResponse(w, http.StatusConflict, fmt.Errorf("runner group has been aborted by %s", s.abort.Runner))
+		return
+	}
+	s.abort = abort
+	w.WriteHeader(http.StatusCreated)
+}
+
+// isControlledRunner returns true if the runner is controlled by any group.
+func (s *Server) isControlledRunner(ctx context.Context, runnerName string) (bool, error) {
+	for _, g := range s.groups {
+		found, err := g.IsControlled(ctx, runnerName)
+		if err != nil {
+			return false, err
+		}
+		if found {
+			return true, nil
+		}
+	}
+	return false, nil
+}
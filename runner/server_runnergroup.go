@@ -19,6 +19,8 @@ func (s *Server) deployRunnerGroups() error {
 	}
 
 	uploadURL := fmt.Sprintf("http://%s/v1/runnergroups/$(POD_NAME)/result", targetAddr)
+	abortURL := fmt.Sprintf("http://%s/v1/runnergroups/$(POD_NAME)/abort", targetAddr)
+	traceURL := fmt.Sprintf("http://%s/v1/runnergroups/$(POD_NAME)/trace", targetAddr)
 
 	var wg sync.WaitGroup
 	errCh := make(chan error, len(s.groups))
@@ -28,7 +30,7 @@ func (s *Server) deployRunnerGroups() error {
 		go func() {
 			defer wg.Done()
 
-			errCh <- g.Deploy(context.Background(), uploadURL)
+			errCh <- g.Deploy(context.Background(), uploadURL, abortURL, traceURL)
 		}()
 	}
 	wg.Wait()
@@ -4,7 +4,6 @@
 package runner
 
 import (
-	"container/list"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -62,10 +61,26 @@ func buildNetListeners(addrs []string) (_ []net.Listener, retErr error) {
 func buildRunnerGroupSummary(s *localstore.Store, groups []*group.Handler) *types.RunnerMetricReport {
 	totalBytes := int64(0)
 	totalResp := 0
-	latenciesByURL := map[string]*list.List{}
+	succeeded, failed := 0, 0
+	var countsByURL map[string]*types.RequestCounts
+	var limiterWait *types.LimiterWaitReport
+	latenciesByURL := map[string]*types.LatencyHistogram{}
+	phaseLatencies := map[types.RequestPhase]*types.LatencyHistogram{}
+	var latenciesByOutcome map[string]*types.LatencyHistogram
+	var latenciesByRetries map[int]*types.LatencyHistogram
 	errs := []types.ResponseError{}
+	errsTotal := 0
 	errStats := map[string]int32{}
+	var errClasses []types.ResponseErrorClass
 	maxDuration := 0 * time.Second
+	abortReason := ""
+	interrupted := false
+	var conns *types.ConnectionReport
+	endpoints := map[string]*types.EndpointReport{}
+	var decodeByURL map[string]*types.DecodeReport
+	var byFlowSchema, byPriorityLevel map[string]*types.FlowControlReport
+	var byVerb, byResource, byScope map[string]*types.AggregateReport
+	var timeSeries *types.TimeSeries
 
 	for idx := range groups {
 		g := groups[idx]
@@ -104,24 +119,67 @@ func buildRunnerGroupSummary(s *localstore.Store, groups []*group.Handler) *type
 			// update totalReceivedBytes
 			totalBytes += report.TotalReceivedBytes
 
-			// update latencies
-			for u, l := range report.LatenciesByURL {
-				latencies, ok := latenciesByURL[u]
-				if !ok {
-					latenciesByURL[u] = list.New()
-					latencies = latenciesByURL[u]
+			// update request counts
+			rDispatched, rSucceeded, rFailed := requestCountsFromReport(&report)
+			totalResp += rDispatched
+			succeeded += rSucceeded
+			failed += rFailed
+			countsByURL = metrics.MergeRequestCounts(countsByURL, report.CountsByURL)
+			limiterWait = metrics.MergeLimiterWaitReports(limiterWait, report.LimiterWait)
+
+			// update latencies. The report from runner which doesn't
+			// support histogram only has raw latencies.
+			if len(report.LatencyHistogramsByURL) == 0 {
+				for u, l := range report.LatenciesByURL {
+					latenciesByURL[u] = metrics.MergeLatencyHistograms(latenciesByURL[u], metrics.HistogramFromLatencies(l))
 				}
-				for _, v := range l {
-					totalResp++
-					latencies.PushBack(v)
+			}
+			latenciesByURL = metrics.MergeLatencyHistogramMaps(latenciesByURL, report.LatencyHistogramsByURL)
+
+			// update phase latencies
+			if len(report.PhaseLatencyHistograms) == 0 {
+				for phase, l := range report.PhaseLatencies {
+					phaseLatencies[phase] = metrics.MergeLatencyHistograms(phaseLatencies[phase], metrics.HistogramFromLatencies(l))
 				}
 			}
+			phaseLatencies = metrics.MergeLatencyHistogramMaps(phaseLatencies, report.PhaseLatencyHistograms)
+			latenciesByOutcome = metrics.MergeLatencyHistogramMaps(latenciesByOutcome, report.LatencyHistogramsByOutcome)
+			latenciesByRetries = metrics.MergeLatencyHistogramMaps(latenciesByRetries, report.LatencyHistogramsByRetries)
 
 			// update error stats
 			mergeErrorStat(errStats, report.ErrorStats)
-			errs = append(errs, report.Errors...)
+			// The report from runner which doesn't count errors by class
+			// only has raw errors.
+			classes := report.ErrorClasses
+			if classes == nil {
+				classes = metrics.ErrorClassesFromErrors(report.Errors)
+			}
+			errClasses = metrics.MergeErrorClasses(errClasses, classes)
+			// Every runner keeps its own sample, so re-sample them
+			// to keep the summary bounded with more runners.
+			errs, errsTotal = metrics.MergeErrorSamples(errs, errsTotal,
+				report.Errors, countErrorClasses(classes), metrics.DefaultErrorSampleSize)
 			report.Errors = nil
 
+			interrupted = interrupted || report.Interrupted
+			conns = metrics.MergeConnectionReports(conns, report.Connections)
+			mergeEndpointReports(endpoints, report.Endpoints)
+			decodeByURL = metrics.MergeDecodeReports(decodeByURL, report.DecodeByURL)
+			byFlowSchema = metrics.MergeFlowControlReports(byFlowSchema, report.ByFlowSchema)
+			byPriorityLevel = metrics.MergeFlowControlReports(byPriorityLevel, report.ByPriorityLevel)
+			byVerb = metrics.MergeAggregateReports(byVerb, report.ByVerb)
+			byResource = metrics.MergeAggregateReports(byResource, report.ByResource)
+			byScope = metrics.MergeAggregateReports(byScope, report.ByScope)
+			timeSeries, err = metrics.MergeTimeSeries(timeSeries, report.TimeSeries)
+			if err != nil {
+				klog.V(2).ErrorS(err, "failed to merge time series", "runner", pod.Name)
+			}
+
+			// keep the first abort reason
+			if report.Aborted && abortReason == "" {
+				abortReason = fmt.Sprintf("%s: %s", pod.Name, report.AbortReason)
+			}
+
 			// update max duration
 			rDur, err := time.ParseDuration(report.Duration)
 			if err != nil {
@@ -134,34 +192,139 @@ func buildRunnerGroupSummary(s *localstore.Store, groups []*group.Handler) *type
 		}
 	}
 
-	percentileLatenciesByURL := map[string][][2]float64{}
+	var percentilePhaseLatencies map[types.RequestPhase][][2]float64
+	for phase, h := range phaseLatencies {
+		if percentilePhaseLatencies == nil {
+			percentilePhaseLatencies = map[types.RequestPhase][][2]float64{}
+		}
+		percentilePhaseLatencies[phase] = metrics.BuildPercentileLatenciesFromHistogram(h)
+	}
+	if len(phaseLatencies) == 0 {
+		phaseLatencies = nil
+	}
+
+	// Raw dial latencies are only used to rebuild percentiles. The
+	// per-connection HTTP/2 reports aren't comparable across runners.
+	if conns != nil {
+		conns.DialLatencies = nil
+		if conns.HTTP2 != nil {
+			conns.HTTP2.PerConnection = nil
+		}
+	}
+
+	for _, r := range endpoints {
+		r.PercentileLatencies = metrics.BuildPercentileLatenciesFromHistogram(r.Histogram)
+		r.Latencies = nil
+	}
+	if len(endpoints) == 0 {
+		endpoints = nil
+	}
+
+	for _, r := range decodeByURL {
+		if r.Histogram == nil {
+			r.Histogram = metrics.HistogramFromLatencies(r.Latencies)
+		}
+		r.PercentileLatencies = metrics.BuildPercentileLatenciesFromHistogram(r.Histogram)
+		r.Latencies = nil
+	}
+
+	for _, reports := range []map[string]*types.FlowControlReport{byFlowSchema, byPriorityLevel} {
+		for _, r := range reports {
+			if r.Histogram == nil {
+				r.Histogram = metrics.HistogramFromLatencies(r.Latencies)
+			}
+			r.PercentileLatencies = metrics.BuildPercentileLatenciesFromHistogram(r.Histogram)
+			r.Latencies = nil
+		}
+	}
 
-	latencies := make([]float64, 0, totalResp)
-	for u, l := range latenciesByURL {
-		lInSlice := listToSliceFloat64(l)
+	for _, reports := range []map[string]*types.AggregateReport{byVerb, byResource, byScope} {
+		for _, r := range reports {
+			r.PercentileLatencies = metrics.BuildPercentileLatenciesFromHistogram(r.Histogram)
+		}
+	}
 
-		latencies = append(latencies, lInSlice...)
-		percentileLatenciesByURL[u] = metrics.BuildPercentileLatencies(lInSlice)
+	percentileLatenciesByURL := map[string][][2]float64{}
+
+	var latencies *types.LatencyHistogram
+	for u, h := range latenciesByURL {
+		latencies = metrics.MergeLatencyHistograms(latencies, h)
+		percentileLatenciesByURL[u] = metrics.BuildPercentileLatenciesFromHistogram(h)
+	}
+	if len(latenciesByURL) == 0 {
+		latenciesByURL = nil
 	}
 
+	// Runners start at the same time so that the achieved QPS of group
+	// is based on the longest run.
+	metrics.SetAchievedQPS(countsByURL, maxDuration.Seconds())
+
 	return &types.RunnerMetricReport{
-		Total:                    totalResp,
-		Errors:                   errs,
-		ErrorStats:               errStats,
-		Duration:                 maxDuration.String(),
-		TotalReceivedBytes:       totalBytes,
-		PercentileLatencies:      metrics.BuildPercentileLatencies(latencies),
-		PercentileLatenciesByURL: percentileLatenciesByURL,
+		Total:                        totalResp,
+		Dispatched:                   totalResp,
+		Succeeded:                    succeeded,
+		Failed:                       failed,
+		AchievedQPS:                  metrics.AchievedQPS(succeeded, maxDuration.Seconds()),
+		CountsByURL:                  countsByURL,
+		LimiterWait:                  limiterWait,
+		Errors:                       errs,
+		ErrorStats:                   errStats,
+		ErrorClasses:                 errClasses,
+		Duration:                     maxDuration.String(),
+		TotalReceivedBytes:           totalBytes,
+		PercentileLatencies:          metrics.BuildPercentileLatenciesFromHistogram(latencies),
+		LatencyHistogramsByURL:       latenciesByURL,
+		PercentileLatenciesByURL:     percentileLatenciesByURL,
+		LatencyHistogramsByOutcome:   latenciesByOutcome,
+		PercentileLatenciesByOutcome: metrics.BuildPercentileLatenciesFromHistogramMap(latenciesByOutcome),
+		LatencyHistogramsByRetries:   latenciesByRetries,
+		PercentileLatenciesByRetries: metrics.BuildPercentileLatenciesFromHistogramMap(latenciesByRetries),
+		PhaseLatencyHistograms:       phaseLatencies,
+		PercentilePhaseLatencies:     percentilePhaseLatencies,
+		Aborted:                      abortReason != "",
+		AbortReason:                  abortReason,
+		Interrupted:                  interrupted,
+		Connections:                  conns,
+		Endpoints:                    endpoints,
+		DecodeByURL:                  decodeByURL,
+		ByFlowSchema:                 byFlowSchema,
+		ByPriorityLevel:              byPriorityLevel,
+		ByVerb:                       byVerb,
+		ByResource:                   byResource,
+		ByScope:                      byScope,
+		TimeSeries:                   timeSeries,
+	}
+}
+
+// requestCountsFromReport returns the number of dispatched, succeeded and
+// failed requests in report. The report from runner which doesn't count
+// requests only has latencies of succeeded requests and error stats.
+func requestCountsFromReport(report *types.RunnerMetricReport) (dispatched, succeeded, failed int) {
+	if report.CountsByURL != nil {
+		return report.Dispatched, report.Succeeded, report.Failed
+	}
+
+	for _, h := range report.LatencyHistogramsByURL {
+		succeeded += int(h.Count)
+	}
+	if len(report.LatencyHistogramsByURL) == 0 {
+		for _, l := range report.LatenciesByURL {
+			succeeded += len(l)
+		}
+	}
+	for _, n := range report.ErrorStats {
+		failed += int(n)
 	}
+	return succeeded + failed, succeeded, failed
 }
 
-// listToSliceFloat64 converts list.List into []float64.
-func listToSliceFloat64(l *list.List) []float64 {
-	res := make([]float64, 0, l.Len())
-	for e := l.Front(); e != nil; e = e.Next() {
-		res = append(res, e.Value.(float64))
+// countErrorClasses returns the total number of errors in classes.
+func countErrorClasses(classes []types.ResponseErrorClass) int {
+	total := 0
+	for _, c := range classes {
+		total += c.Count
 	}
-	return res
+	return total
 }
 
 // mergeErrorStat merges two error stats.
@@ -171,6 +334,25 @@ func mergeErrorStat(s, d map[string]int32) {
 	}
 }
 
+// mergeEndpointReports merges endpoint reports from d into s. The latency
+// percentiles should be rebuilt from histogram after merge.
+func mergeEndpointReports(s, d map[string]*types.EndpointReport) {
+	for ep, r := range d {
+		merged, ok := s[ep]
+		if !ok {
+			merged = &types.EndpointReport{ErrorStats: map[string]int32{}}
+			s[ep] = merged
+		}
+		merged.Total += r.Total
+		hist := r.Histogram
+		if hist == nil {
+			hist = metrics.HistogramFromLatencies(r.Latencies)
+		}
+		merged.Histogram = metrics.MergeLatencyHistograms(merged.Histogram, hist)
+		mergeErrorStat(merged.ErrorStats, r.ErrorStats)
+	}
+}
+
 // readBlob reads blob data from localstore.
 func readBlob(s *localstore.Store, ref string) ([]byte, error) {
 	r, err := s.OpenReader(ref)
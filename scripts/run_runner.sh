@@ -6,33 +6,73 @@
 set -euo pipefail
 
 result_file=/data/${POD_NAMESPACE}-${POD_NAME}-${POD_UID}.json
+trace_file=/data/${POD_NAMESPACE}-${POD_NAME}-${POD_UID}.trace.jsonl.gz
+
+trace_args=""
+if [ -n "${TRACE_URL:-}" ]; then
+  trace_args="--trace=${trace_file}"
+fi
+
+metrics_args=""
+if [ -n "${METRICS_ADDRESS:-}" ]; then
+  metrics_args="--metrics-address=${METRICS_ADDRESS}"
+fi
 
 /kperf -v=${RUNNER_VERBOSITY} runner run --config=/config/load_profile.yaml \
     --user-agent=${POD_NAME} \
     --result=${result_file} \
-    --raw-data
-
-while true; do
-  set +e
-  http_code=$(curl -s -o /dev/null -w "%{http_code}" -XPOST -d "@${result_file}" ${TARGET_URL} || "50X")
-  set -e
-
-  case $http_code in
-    201)
-      echo "Uploaded it"
-      exit 0
-      ;;
-    409)
-      echo "Has been uploaded, skip"
-      exit 0;
-      ;;
-    404)
-      echo "Leaking pod? skip"
-      exit 1;
-      ;;
-    *)
-      echo "Need to retry after received http code ${http_code} (or failed to connect)"
-      sleep 5s
-      ;;
-  esac
-done
+    --abort-url=${ABORT_URL} \
+    ${trace_args} \
+    ${metrics_args} &
+kperf_pid=$!
+
+# Forward SIGTERM (for instance, pod eviction) to kperf so that it writes
+# partial result, which is uploaded as usual.
+trap 'kill -TERM ${kperf_pid} 2>/dev/null || true' TERM INT
+
+kperf_exit_code=0
+wait ${kperf_pid} || kperf_exit_code=$?
+if kill -0 ${kperf_pid} 2>/dev/null; then
+  # wait was interrupted by trap. Wait for kperf to drain.
+  kperf_exit_code=0
+  wait ${kperf_pid} || kperf_exit_code=$?
+fi
+if [ ${kperf_exit_code} -ne 0 ]; then
+  exit ${kperf_exit_code}
+fi
+
+# upload posts file to url until it's accepted.
+upload() {
+  local file=$1
+  local url=$2
+
+  while true; do
+    set +e
+    http_code=$(curl -s -o /dev/null -w "%{http_code}" -XPOST --data-binary "@${file}" ${url} || "50X")
+    set -e
+
+    case $http_code in
+      201)
+        echo "Uploaded ${file}"
+        return 0
+        ;;
+      409)
+        echo "${file} has been uploaded, skip"
+        return 0
+        ;;
+      404)
+        echo "Leaking pod? skip"
+        exit 1;
+        ;;
+      *)
+        echo "Need to retry after received http code ${http_code} (or failed to connect)"
+        sleep 5s
+        ;;
+    esac
+  done
+}
+
+if [ -n "${TRACE_URL:-}" ]; then
+  upload ${trace_file} ${TRACE_URL}
+fi
+upload ${result_file} ${TARGET_URL}